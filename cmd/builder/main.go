@@ -8,9 +8,10 @@ import (
 	"strings"
 
 	"github.com/AdConDev/pos-printer"
-	"github.com/AdConDev/pos-printer/connector"
+	posconnector "github.com/AdConDev/pos-printer/connector"
 	"github.com/AdConDev/pos-printer/profile"
 	"github.com/AdConDev/pos-printer/protocol/escpos"
+	"pos-daemon.adcon.dev/internal/connector"
 	"pos-daemon.adcon.dev/internal/models"
 	"pos-daemon.adcon.dev/internal/service"
 )
@@ -41,12 +42,13 @@ func main() {
 	}
 
 	// 1. Crear conector
-	log.Printf("Conectando a impresora: %s", dataConfig.Printer)
-	conn, err := connector.NewWindowsPrintConnector(dataConfig.Printer)
+	connOpts := connector.OptionsFromConfig(dataConfig)
+	log.Printf("Conectando a impresora: %s (%s)", dataConfig.Printer, connOpts)
+	conn, err := connector.Open(connOpts)
 	if err != nil {
 		log.Fatalf("Error al crear conector: %v", err)
 	}
-	defer func(conn *connector.WindowsPrintConnector) {
+	defer func(conn posconnector.Connector) {
 		err := conn.Close()
 		if err != nil {
 			log.Printf("Error al cerrar conector: %v", err)
@@ -79,7 +81,7 @@ func main() {
 
 	writer := os.Stdout
 	// 5. Crear constructor de tickets con la impresora genérica
-	constructor := service.NewTicketConstructor(writer, printer)
+	constructor := service.NewTicketConstructor(writer, service.ESCPOSPrinter{GenericPrinter: printer})
 
 	// Cargar template y datos de ticket
 	templateData, err := os.ReadFile(filepath.Join("./internal/api/rest/", "new_ticket_template.json"))
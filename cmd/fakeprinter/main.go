@@ -0,0 +1,134 @@
+// Command fakeprinter emula una impresora ESC/POS de red para probar el daemon
+// sin impresoras físicas. Guarda cada trabajo recibido en un archivo .bin que
+// puede verse con posd replay.
+//
+// Uso:
+//
+//	fakeprinter [-addr :9100] [-dir ./data/fakeprinter] [-paper-lines N] [-cover-open]
+//	            [-drop-after N] [-ack-delay 2s] [-script fallas.json]
+//
+// El script es una lista de pasos que cambian las fallas con el tiempo:
+//
+//	[
+//	  {"at_ms": 0, "paper_out_after_lines": 40},
+//	  {"at_ms": 30000, "refill": true},
+//	  {"at_ms": 45000, "cover_open": true},
+//	  {"at_ms": 60000, "cover_open": false}
+//	]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"pos-daemon.adcon.dev/internal/fakeprinter"
+)
+
+// step es un paso del script: los campos presentes reemplazan la falla
+// correspondiente cuando pasan AtMs milisegundos desde el arranque
+type step struct {
+	AtMs               int   `json:"at_ms"`
+	PaperOutAfterLines *int  `json:"paper_out_after_lines,omitempty"`
+	CoverOpen          *bool `json:"cover_open,omitempty"`
+	DropAfterBytes     *int  `json:"drop_after_bytes,omitempty"`
+	AckDelayMs         *int  `json:"ack_delay_ms,omitempty"`
+	Refill             bool  `json:"refill,omitempty"` // Cargar papel nuevo
+}
+
+func main() {
+	addr := flag.String("addr", ":9100", "dirección de escucha")
+	dir := flag.String("dir", "./data/fakeprinter", "directorio donde se guardan los trabajos")
+	paperLines := flag.Int("paper-lines", 0, "terminar el papel tras imprimir N líneas (0 = nunca)")
+	coverOpen := flag.Bool("cover-open", false, "arrancar con la tapa abierta")
+	dropAfter := flag.Int("drop-after", 0, "cortar cada conexión al recibir N bytes (0 = nunca)")
+	ackDelay := flag.Duration("ack-delay", 0, "retraso antes de responder consultas y leer datos")
+	scriptPath := flag.String("script", "", "archivo JSON con pasos que cambian las fallas")
+	flag.Parse()
+
+	if err := run(*addr, *dir, *scriptPath, fakeprinter.Faults{
+		PaperOutAfterLines: *paperLines,
+		CoverOpen:          *coverOpen,
+		DropAfterBytes:     *dropAfter,
+		AckDelay:           *ackDelay,
+	}); err != nil {
+		log.Fatalf("fakeprinter: %v", err)
+	}
+}
+
+// run atiende conexiones hasta recibir una señal
+func run(addr, dir, scriptPath string, faults fakeprinter.Faults) error {
+	var script []step
+	if scriptPath != "" {
+		data, err := os.ReadFile(filepath.Clean(scriptPath))
+		if err != nil {
+			return fmt.Errorf("error al leer script: %w", err)
+		}
+		if err := json.Unmarshal(data, &script); err != nil {
+			return fmt.Errorf("script inválido: %w", err)
+		}
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("error al crear %s: %w", dir, err)
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	printer := fakeprinter.New(fakeprinter.Options{Dir: dir, Faults: faults})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go runScript(ctx, printer, script)
+	go func() {
+		<-ctx.Done()
+		if err := printer.Close(); err != nil {
+			log.Printf("Error al cerrar: %v", err)
+		}
+	}()
+
+	log.Printf("fakeprinter escuchando en %s, trabajos en %s", ln.Addr(), dir)
+	return printer.Serve(ln)
+}
+
+// runScript aplica los pasos del script en su momento
+func runScript(ctx context.Context, printer *fakeprinter.Printer, script []step) {
+	start := time.Now()
+	for _, s := range script {
+		timer := time.NewTimer(time.Until(start.Add(time.Duration(s.AtMs) * time.Millisecond)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		f := printer.Faults()
+		if s.PaperOutAfterLines != nil {
+			f.PaperOutAfterLines = *s.PaperOutAfterLines
+		}
+		if s.CoverOpen != nil {
+			f.CoverOpen = *s.CoverOpen
+		}
+		if s.DropAfterBytes != nil {
+			f.DropAfterBytes = *s.DropAfterBytes
+		}
+		if s.AckDelayMs != nil {
+			f.AckDelay = time.Duration(*s.AckDelayMs) * time.Millisecond
+		}
+		printer.SetFaults(f)
+		if s.Refill {
+			printer.Refill()
+		}
+		log.Printf("fakeprinter: paso a los %d ms: %+v", s.AtMs, f)
+	}
+}
@@ -0,0 +1,362 @@
+//go:build integration
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"pos-daemon.adcon.dev/internal/connector"
+	"pos-daemon.adcon.dev/internal/events"
+	"pos-daemon.adcon.dev/internal/fakeprinter"
+	"pos-daemon.adcon.dev/internal/printers"
+	"pos-daemon.adcon.dev/internal/queue"
+	"pos-daemon.adcon.dev/internal/rawport"
+	"pos-daemon.adcon.dev/internal/receipt"
+	"pos-daemon.adcon.dev/internal/status"
+)
+
+// Estas pruebas levantan impresoras emuladas en el mismo proceso y las
+// alimentan con el worker real de la cola:
+//
+//	go test -race -tags integration ./cmd/posd
+
+// poll es el intervalo de consulta de estado usado en las pruebas
+const poll = 20 * time.Millisecond
+
+// fakeTCP levanta una impresora emulada y devuelve su configuración
+func fakeTCP(t *testing.T, name string, faults fakeprinter.Faults) (*fakeprinter.Printer, printers.Printer) {
+	t.Helper()
+	fake, err := fakeprinter.Start("127.0.0.1:0", fakeprinter.Options{Dir: t.TempDir(), Faults: faults})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { fake.Close() })
+	addr := fake.Addr().(*net.TCPAddr)
+	return fake, printers.Printer{
+		Name: name,
+		Poll: poll,
+		Connector: connector.Options{
+			Type: connector.TypeTCP, Address: addr.IP.String(), Port: addr.Port, Timeout: time.Second,
+		},
+	}
+}
+
+// daemon es el worker de impresión de posd con su cola y su monitor
+type daemon struct {
+	registry *printers.Registry
+	jobs     *queue.Queue
+	monitor  *status.Monitor
+	ticket   []byte
+	tmpl     []byte
+}
+
+// startDaemon arranca la cola y el monitor sobre las impresoras indicadas
+func startDaemon(t *testing.T, list []printers.Printer, groups []printers.Group) *daemon {
+	t.Helper()
+	registry, err := printers.New(list, groups, nil)
+	if err != nil {
+		t.Fatalf("printers.New: %v", err)
+	}
+	jobs, err := queue.Open(queue.Options{
+		Dir:            t.TempDir(),
+		BackoffInitial: 10 * time.Millisecond,
+		BackoffMax:     50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("queue.Open: %v", err)
+	}
+	d := &daemon{
+		registry: registry,
+		jobs:     jobs,
+		monitor:  status.NewMonitor(registry, events.NewHub()),
+		ticket:   readFixture(t, "new_ticket.json"),
+		tmpl:     readFixture(t, "new_ticket_template.json"),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		jobs.Run(ctx, printJob(registry, d.monitor, nil))
+	}()
+	go func() {
+		defer wg.Done()
+		d.monitor.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		wg.Wait()
+		if err := jobs.Close(); err != nil {
+			t.Errorf("Close: %v", err)
+		}
+	})
+	return d
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("../../internal/api/rest/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// enqueue encola el ticket de ejemplo para printer
+func (d *daemon) enqueue(t *testing.T, printer string) string {
+	t.Helper()
+	job, err := d.jobs.Enqueue(queue.Request{Ticket: d.ticket, Template: d.tmpl, Printer: printer})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	return job.ID
+}
+
+// waitFor espera hasta que cond se cumpla
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("tiempo agotado esperando %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// waitJob espera a que el trabajo llegue a state
+func (d *daemon) waitJob(t *testing.T, id string, state queue.State) *queue.Job {
+	t.Helper()
+	var job *queue.Job
+	waitFor(t, "el trabajo "+string(state), func() bool {
+		var err error
+		job, err = d.jobs.Get(id)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		return job.State == state
+	})
+	return job
+}
+
+// waitState espera a que el monitor informe state para printer
+func (d *daemon) waitState(t *testing.T, printer string, state status.State) status.PrinterStatus {
+	t.Helper()
+	var st status.PrinterStatus
+	waitFor(t, printer+" "+string(state), func() bool {
+		st, _ = d.monitor.Status(printer)
+		return st.State == state
+	})
+	return st
+}
+
+// waitPrinted espera a que la impresora emulada guarde n trabajos
+func waitPrinted(t *testing.T, fake *fakeprinter.Printer, n int) []fakeprinter.Job {
+	t.Helper()
+	waitFor(t, "los trabajos impresos", func() bool { return len(fake.Jobs()) >= n })
+	return fake.Jobs()
+}
+
+func TestIntegrationPrint(t *testing.T) {
+	fake, mostrador := fakeTCP(t, "mostrador", fakeprinter.Faults{AckDelay: 30 * time.Millisecond})
+	d := startDaemon(t, []printers.Printer{mostrador}, nil)
+
+	id := d.enqueue(t, "mostrador")
+	job := d.waitJob(t, id, queue.StateDone)
+	if job.PrintedBy != "mostrador" || job.Attempts != 1 {
+		t.Errorf("trabajo = %+v", job)
+	}
+
+	printed := waitPrinted(t, fake, 1)
+	if !bytes.Contains(printed[0].Data, []byte("LA RAZON")) {
+		t.Error("el trabajo impreso no contiene el ticket")
+	}
+	if _, err := os.Stat(printed[0].Path); err != nil {
+		t.Errorf("no se guardó el trabajo: %v", err)
+	}
+}
+
+func TestIntegrationCoverOpen(t *testing.T) {
+	fake, mostrador := fakeTCP(t, "mostrador", fakeprinter.Faults{CoverOpen: true})
+	d := startDaemon(t, []printers.Printer{mostrador}, nil)
+
+	if st := d.waitState(t, "mostrador", status.StateError); !st.CoverOpen {
+		t.Errorf("estado = %+v; want tapa abierta", st)
+	}
+
+	// Con la tapa abierta el trabajo se pospone sin gastar intentos
+	id := d.enqueue(t, "mostrador")
+	waitFor(t, "el trabajo pospuesto", func() bool {
+		job, _ := d.jobs.Get(id)
+		return job.LastError != ""
+	})
+	if job, _ := d.jobs.Get(id); job.State != queue.StateQueued || job.Attempts != 0 {
+		t.Errorf("trabajo = %s con %d intentos; want queued sin intentos", job.State, job.Attempts)
+	}
+
+	fake.SetFaults(fakeprinter.Faults{})
+	d.waitJob(t, id, queue.StateDone)
+	waitPrinted(t, fake, 1)
+}
+
+func TestIntegrationFailover(t *testing.T) {
+	// El primario corta cada conexión al primer byte
+	primary, mostrador := fakeTCP(t, "mostrador", fakeprinter.Faults{DropAfterBytes: 1})
+	mostrador.Poll = 0
+	backup, respaldo := fakeTCP(t, "respaldo", fakeprinter.Faults{})
+	d := startDaemon(t, []printers.Printer{mostrador, respaldo}, []printers.Group{
+		{Name: "caja", Printers: []string{"mostrador", "respaldo"}},
+	})
+
+	id := d.enqueue(t, "caja")
+	job := d.waitJob(t, id, queue.StateDone)
+	if job.Printer != "caja" || job.PrintedBy != "respaldo" {
+		t.Errorf("trabajo impreso por %q en %q; want respaldo en caja", job.PrintedBy, job.Printer)
+	}
+	waitPrinted(t, backup, 1)
+	if st, _ := d.monitor.Status("mostrador"); st.State != status.StateUnreachable {
+		t.Errorf("mostrador = %s; want unreachable", st.State)
+	}
+	for _, j := range primary.Jobs() {
+		if !j.Dropped {
+			t.Errorf("el primario terminó un trabajo: %+v", j)
+		}
+	}
+}
+
+func TestIntegrationFailoverNarrowerPaper(t *testing.T) {
+	// El ticket trae la plantilla de 80mm del primario; el respaldo de 58mm
+	// debe recibirlo acomodado a sus 32 columnas
+	_, mostrador := fakeTCP(t, "mostrador", fakeprinter.Faults{DropAfterBytes: 1})
+	mostrador.Poll = 0
+	backup, respaldo := fakeTCP(t, "respaldo", fakeprinter.Faults{})
+	respaldo.Profile = printers.Profile58mm
+	d := startDaemon(t, []printers.Printer{mostrador, respaldo}, []printers.Group{
+		{Name: "caja", Printers: []string{"mostrador", "respaldo"}},
+	})
+
+	id := d.enqueue(t, "caja")
+	if job := d.waitJob(t, id, queue.StateDone); job.PrintedBy != "respaldo" {
+		t.Fatalf("trabajo impreso por %q; want respaldo", job.PrintedBy)
+	}
+	printed := waitPrinted(t, backup, 1)
+	if got := headerColumns(t, printed[0].Data); got != 32 {
+		t.Errorf("encabezado de conceptos de %d columnas; want 32", got)
+	}
+}
+
+// headerColumns mide el encabezado de la tabla de conceptos, que ocupa el
+// ancho completo del papel
+func headerColumns(t *testing.T, data []byte) int {
+	t.Helper()
+	for _, b := range receipt.Parse(data).Blocks {
+		line, ok := b.(*receipt.Line)
+		if !ok || !strings.Contains(line.Text(), "PRODUCTO") {
+			continue
+		}
+		columns := 0
+		for _, span := range line.Spans {
+			columns += utf8.RuneCountInString(span.Text) * span.Width
+		}
+		return columns
+	}
+	t.Fatal("el ticket no tiene encabezado de conceptos")
+	return 0
+}
+
+func TestIntegrationPaperOut(t *testing.T) {
+	// Cada ticket de ejemplo ocupa 43 líneas: el papel se termina a mitad del
+	// segundo, que queda en el búfer de la impresora hasta cargar papel
+	fake, mostrador := fakeTCP(t, "mostrador", fakeprinter.Faults{PaperOutAfterLines: 60})
+	d := startDaemon(t, []printers.Printer{mostrador}, nil)
+	d.waitState(t, "mostrador", status.StateOnline)
+
+	d.waitJob(t, d.enqueue(t, "mostrador"), queue.StateDone)
+	d.waitJob(t, d.enqueue(t, "mostrador"), queue.StateDone)
+	if st := d.waitState(t, "mostrador", status.StateError); !st.PaperEnd {
+		t.Errorf("estado = %+v; want sin papel", st)
+	}
+
+	// Sin papel el siguiente trabajo se pospone sin gastar intentos
+	third := d.enqueue(t, "mostrador")
+	waitFor(t, "el trabajo pospuesto", func() bool {
+		job, _ := d.jobs.Get(third)
+		return job.LastError != ""
+	})
+	if job, _ := d.jobs.Get(third); job.State != queue.StateQueued || job.Attempts != 0 {
+		t.Errorf("trabajo = %s con %d intentos; want queued sin intentos", job.State, job.Attempts)
+	}
+
+	// Rollo nuevo que no se termina
+	fake.SetFaults(fakeprinter.Faults{})
+	fake.Refill()
+	d.waitJob(t, third, queue.StateDone)
+	waitPrinted(t, fake, 3)
+}
+
+func TestIntegrationRawPort(t *testing.T) {
+	_, mostrador := fakeTCP(t, "mostrador", fakeprinter.Faults{DropAfterBytes: 1})
+	mostrador.Poll = 0
+	backup, respaldo := fakeTCP(t, "respaldo", fakeprinter.Faults{})
+	d := startDaemon(t, []printers.Printer{mostrador, respaldo}, []printers.Group{
+		{Name: "caja", Printers: []string{"mostrador", "respaldo"}},
+	})
+
+	dest, err := d.registry.Resolve("caja")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := rawport.Listen(rawport.Options{Addr: "127.0.0.1:0", Destination: dest, Queue: d.jobs, Monitor: d.monitor})
+	if err != nil {
+		t.Fatalf("rawport.Listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		if err := l.Serve(ctx); err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-served
+	})
+
+	// Un programa antiguo imprime directo al puerto como a una impresora de red
+	data := append([]byte{0x1B, 0x40}, "VENTA 123\n"...)
+	data = append(data, 0x1D, 0x56, 0x00)
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	var id string
+	waitFor(t, "el trabajo raw", func() bool {
+		list := d.jobs.List("")
+		if len(list) > 0 {
+			id = list[0].ID
+		}
+		return id != ""
+	})
+	job := d.waitJob(t, id, queue.StateDone)
+	if !job.IsRaw() || job.PrintedBy != "respaldo" {
+		t.Errorf("trabajo = %s impreso por %q; want raw impreso por respaldo", job.Kind, job.PrintedBy)
+	}
+	if printed := waitPrinted(t, backup, 1); !bytes.Equal(printed[0].Data, data) {
+		t.Errorf("impreso = %q; want %q", printed[0].Data, data)
+	}
+}
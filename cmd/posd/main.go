@@ -0,0 +1,82 @@
+// Command posd es el daemon de impresión: expone una API HTTP que recibe
+// tickets y los envía a la impresora configurada.
+//
+// Uso:
+//
+//	posd [serve] [-config archivo] [-addr :8080]
+//	posd reprint [-addr http://localhost:8080] (-identificador ID | -serie S -folio F)
+//	posd preview -ticket ticket.json -template plantilla.json [-printer 58mm] [-o ticket.png]
+//	posd replay -i captura.bin [-format text|html|png] [-printer 58mm] [-o salida]
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"pos-daemon.adcon.dev/internal/models"
+)
+
+const (
+	defaultConfigPath   = "./internal/api/rest/config.json"
+	defaultListenAddr   = ":8080"
+	defaultTemplatesDir = "./internal/api/rest"
+	defaultTemplate     = "new_ticket_template"
+	defaultQueueDir     = "./data/queue"
+	defaultJournalDir   = "./data/journal"
+)
+
+func main() {
+	args := os.Args[1:]
+	cmd := "serve"
+	if len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' {
+		cmd, args = args[0], args[1:]
+	}
+
+	var err error
+	switch cmd {
+	case "serve":
+		err = runServe(args)
+	case "reprint":
+		err = runReprint(args)
+	case "preview":
+		err = runPreview(args)
+	case "replay":
+		err = runReplay(args)
+	default:
+		err = fmt.Errorf("comando desconocido: %s", cmd)
+	}
+	if err != nil {
+		log.Fatalf("posd %s: %v", cmd, err)
+	}
+}
+
+// loadConfig lee la configuración y aplica los valores por defecto
+func loadConfig(path string) (*models.ConfigData, error) {
+	jsonBytes, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("error al leer archivo de configuración: %w", err)
+	}
+	cfg, err := models.BytesToConfig(jsonBytes)
+	if err != nil {
+		return nil, fmt.Errorf("error al deserializar configuración: %w", err)
+	}
+
+	if cfg.ListenAddr == "" {
+		cfg.ListenAddr = defaultListenAddr
+	}
+	if cfg.TemplatesDir == "" {
+		cfg.TemplatesDir = defaultTemplatesDir
+	}
+	if cfg.DefaultTemplate == "" {
+		cfg.DefaultTemplate = defaultTemplate
+	}
+	if cfg.Queue.Dir == "" {
+		cfg.Queue.Dir = defaultQueueDir
+	}
+	if cfg.JournalDir == "" {
+		cfg.JournalDir = defaultJournalDir
+	}
+	return cfg, nil
+}
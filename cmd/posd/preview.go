@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+
+	"pos-daemon.adcon.dev/internal/preview"
+	"pos-daemon.adcon.dev/internal/service"
+)
+
+// runPreview dibuja un ticket como PNG sin necesidad de una impresora
+func runPreview(args []string) error {
+	fs := flag.NewFlagSet("preview", flag.ExitOnError)
+	ticketPath := fs.String("ticket", "", "archivo JSON del ticket")
+	templatePath := fs.String("template", "", "archivo JSON de la plantilla")
+	receiptPath := fs.String("receipt", "", "recibo libre (.tmpl) en lugar de -template")
+	printer := fs.String("printer", "80mm", "nombre de la impresora a simular (contiene 58mm para papel de 58 mm)")
+	out := fs.String("o", "ticket.png", "archivo PNG de salida")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *ticketPath == "" || (*templatePath == "") == (*receiptPath == "") {
+		return fmt.Errorf("indique -ticket y -template o -receipt")
+	}
+
+	ticketData, err := os.ReadFile(filepath.Clean(*ticketPath))
+	if err != nil {
+		return fmt.Errorf("error al leer ticket: %w", err)
+	}
+
+	var img *image.Gray
+	if *receiptPath != "" {
+		img, err = previewReceipt(*receiptPath, ticketData, *printer)
+	} else {
+		var templateData []byte
+		templateData, err = os.ReadFile(filepath.Clean(*templatePath))
+		if err != nil {
+			return fmt.Errorf("error al leer plantilla: %w", err)
+		}
+		img, err = preview.RenderTicket(templateData, ticketData, profileFor(*printer), service.TicketOptions{})
+	}
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(filepath.Clean(*out))
+	if err != nil {
+		return fmt.Errorf("error al crear %s: %w", *out, err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("error al escribir %s: %w", *out, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("error al cerrar %s: %w", *out, err)
+	}
+	log.Printf("Vista previa escrita en %s", *out)
+	return nil
+}
+
+// previewReceipt dibuja un recibo libre; las imágenes relativas parten del
+// directorio del archivo, como en el daemon
+func previewReceipt(path string, ticketData []byte, printer string) (*image.Gray, error) {
+	receipt, err := service.ParseReceiptFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return preview.RenderReceipt(receipt, ticketData, profileFor(printer))
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/AdConDev/pos-printer/profile"
+	"pos-daemon.adcon.dev/internal/connector"
+	"pos-daemon.adcon.dev/internal/models"
+	"pos-daemon.adcon.dev/internal/printers"
+	"pos-daemon.adcon.dev/internal/queue"
+	"pos-daemon.adcon.dev/internal/service"
+	"pos-daemon.adcon.dev/internal/status"
+)
+
+// profileFor detecta el perfil de una impresora por su nombre
+func profileFor(name string) *profile.Profile {
+	p := printers.Printer{Name: name, Profile: printers.DetectProfile(name)}
+	return p.NewProfile()
+}
+
+// jobDestination devuelve la impresora o grupo asignado al trabajo. Los
+// trabajos encolados antes de configurar el ruteo no tienen destino y se rutean
+// por los datos del ticket.
+func jobDestination(registry *printers.Registry, job *queue.Job) (*printers.Destination, error) {
+	if job.Printer != "" {
+		return registry.Resolve(job.Printer)
+	}
+	if job.Kind == queue.KindRaw || job.Kind == queue.KindText {
+		return nil, fmt.Errorf("el trabajo %s de tipo %s no tiene impresora destino", job.ID, job.Kind)
+	}
+	var ticket models.NewTicket
+	if err := json.Unmarshal(job.Ticket, &ticket); err != nil {
+		return nil, fmt.Errorf("error al leer el ticket: %w", err)
+	}
+	return registry.Route(printers.TargetFromTicket(&ticket))
+}
+
+// printJob devuelve el Processor que imprime cada trabajo en su destino. Si la
+// impresora falla, no tiene papel o el monitor informa un problema, se intenta
+// con la siguiente del grupo. El ticket, el texto plano y los recibos libres se
+// vuelven a construir con el perfil de cada impresora, de modo que un respaldo
+// con otro ancho de papel los recibe acomodados a su ancho; los trabajos raw se
+// envían tal cual. Si ninguna impresora del destino está lista el trabajo se
+// pospone sin contar el intento.
+func printJob(registry *printers.Registry, monitor *status.Monitor, receipts *service.Receipts) queue.Processor {
+	return func(_ context.Context, job *queue.Job) error {
+		dest, err := jobDestination(registry, job)
+		if err != nil {
+			// La configuración no cambia mientras el daemon está corriendo
+			return queue.Permanent(err)
+		}
+
+		opts := service.TicketOptions{Warnings: job.Warnings}
+		if job.Reprint != nil {
+			opts.Reprint = &service.Reprint{Number: job.Reprint.Number, At: job.Reprint.At}
+		}
+
+		var failures []error
+		notReady := 0
+		retry := printers.DefaultPoll
+		for i, printer := range dest.Printers {
+			if err := monitor.Ready(printer.Name); err != nil {
+				failures = append(failures, err)
+				notReady++
+				retry = min(retry, printer.Poll)
+				continue
+			}
+
+			data, err := render(job, printer, opts, receipts)
+			if err != nil {
+				// Los datos no van a cambiar entre reintentos
+				return queue.Permanent(err)
+			}
+
+			err = monitor.Use(printer.Name, func() error { return send(printer, data) })
+			if err == nil {
+				job.PrintedBy = printer.Name
+				log.Printf("Trabajo %s enviado a %s (%d bytes)", job.ID, printer.Name, len(data))
+				return nil
+			}
+			failures = append(failures, fmt.Errorf("%s: %w", printer.Name, err))
+			if i+1 < len(dest.Printers) {
+				log.Printf("Trabajo %s: %s falló (%v), se reenvía a %s", job.ID, printer.Name, err, dest.Printers[i+1].Name)
+			}
+		}
+		if notReady == len(dest.Printers) {
+			return queue.Defer(errors.Join(failures...), retry)
+		}
+		return errors.Join(failures...)
+	}
+}
+
+// render construye los bytes del trabajo para printer; opts solo se usa en
+// los tickets
+func render(job *queue.Job, printer *printers.Printer, opts service.TicketOptions, receipts *service.Receipts) ([]byte, error) {
+	switch job.Kind {
+	case queue.KindRaw:
+		return job.Raw, nil
+	case queue.KindText:
+		return service.RenderText(job.Text, printer.NewProfile())
+	case queue.KindReceipt:
+		receipt, err := receipts.Get(job.Receipt)
+		if err != nil {
+			return nil, err
+		}
+		return service.RenderReceipt(receipt, job.Ticket, printer.NewProfile())
+	}
+	return service.RenderTicket(job.Template, job.Ticket, printer.NewProfile(), opts)
+}
+
+// send envía los bytes del ticket a la impresora. En las conexiones que
+// permiten leer respuestas primero se comprueba que haya papel.
+func send(printer *printers.Printer, data []byte) error {
+	conn, err := connector.Open(printer.Connector)
+	if err != nil {
+		return fmt.Errorf("error al crear conector: %w", err)
+	}
+	if rw, ok := conn.(io.ReadWriter); ok && printer.Connector.Bidirectional() {
+		if err := status.CheckPaper(rw); err != nil {
+			if cerr := conn.Close(); cerr != nil {
+				log.Printf("Error al cerrar conector: %v", cerr)
+			}
+			return err
+		}
+	}
+	if _, err := conn.Write(data); err != nil {
+		if cerr := conn.Close(); cerr != nil {
+			log.Printf("Error al cerrar conector: %v", cerr)
+		}
+		return fmt.Errorf("error al escribir en la impresora: %w", err)
+	}
+	if err := conn.Close(); err != nil {
+		return fmt.Errorf("error al cerrar conector: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"pos-daemon.adcon.dev/internal/receipt"
+)
+
+// runReplay interpreta una captura ESC/POS y la muestra como texto, HTML o PNG
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	in := fs.String("i", "", "captura .bin con los bytes enviados a la impresora")
+	format := fs.String("format", "text", "formato de salida: text, html o png")
+	out := fs.String("o", "-", "archivo de salida (- para la salida estándar)")
+	printer := fs.String("printer", "80mm", "nombre de la impresora a simular (contiene 58mm para papel de 58 mm)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("indique la captura con -i")
+	}
+
+	data, err := os.ReadFile(filepath.Clean(*in))
+	if err != nil {
+		return fmt.Errorf("error al leer captura: %w", err)
+	}
+	doc := receipt.Parse(data)
+	for _, w := range doc.Warnings {
+		log.Printf("Advertencia: %s", w)
+	}
+
+	prof := profileFor(*printer)
+	columns := prof.DotsPerLine / 12 // Columnas de Font A
+
+	var buf bytes.Buffer
+	switch *format {
+	case "text":
+		buf.WriteString(doc.Text(columns))
+	case "html":
+		page, err := doc.HTML(columns)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(page)
+	case "png":
+		if err := doc.PNG(&buf, prof); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("formato desconocido: %s", *format)
+	}
+
+	if *out == "-" {
+		_, err := io.Copy(os.Stdout, &buf)
+		return err
+	}
+	if err := os.WriteFile(filepath.Clean(*out), buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("error al escribir %s: %w", *out, err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"pos-daemon.adcon.dev/internal/api/rest"
+)
+
+// runReprint solicita al daemon en ejecución la reimpresión de un ticket del journal
+func runReprint(args []string) error {
+	fs := flag.NewFlagSet("reprint", flag.ExitOnError)
+	addr := fs.String("addr", "http://localhost"+defaultListenAddr, "URL base del daemon")
+	identificador := fs.String("identificador", "", "identificador del ticket")
+	serie := fs.String("serie", "", "serie del ticket")
+	folio := fs.String("folio", "", "folio del ticket")
+	jobID := fs.String("job", "", "trabajo que imprimió el original (trabajos raw y de texto)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *identificador == "" && (*serie == "" || *folio == "") && *jobID == "" {
+		return fmt.Errorf("indique -identificador, -serie y -folio o -job")
+	}
+
+	body, err := json.Marshal(rest.ReprintRequest{
+		Identificador: *identificador,
+		Serie:         *serie,
+		Folio:         *folio,
+		JobID:         *jobID,
+	})
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	url := strings.TrimRight(*addr, "/") + "/v1/reprints"
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("no se pudo contactar al daemon: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("Error al cerrar respuesta: %v", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusAccepted {
+		var apiErr struct {
+			Error rest.APIError `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil {
+			return fmt.Errorf("respuesta inesperada del daemon: %s", resp.Status)
+		}
+		return fmt.Errorf("%s: %s", apiErr.Error.Code, apiErr.Error.Message)
+	}
+
+	var out rest.ReprintResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return fmt.Errorf("respuesta inválida del daemon: %w", err)
+	}
+	fmt.Printf("Reimpresión #%d encolada (trabajo %s)\n", out.Reprint, out.JobID)
+	return nil
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"pos-daemon.adcon.dev/internal/api/rest"
+	"pos-daemon.adcon.dev/internal/events"
+	"pos-daemon.adcon.dev/internal/hotfolder"
+	"pos-daemon.adcon.dev/internal/journal"
+	"pos-daemon.adcon.dev/internal/lpd"
+	"pos-daemon.adcon.dev/internal/models"
+	"pos-daemon.adcon.dev/internal/printers"
+	"pos-daemon.adcon.dev/internal/queue"
+	"pos-daemon.adcon.dev/internal/rawport"
+	"pos-daemon.adcon.dev/internal/service"
+	"pos-daemon.adcon.dev/internal/status"
+)
+
+// runServe levanta la API HTTP y el worker de la cola hasta recibir una señal
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, "ruta al archivo de configuración")
+	addr := fs.String("addr", "", "dirección de escucha (sobrescribe listen_addr)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if *addr != "" {
+		cfg.ListenAddr = *addr
+	}
+
+	log.SetOutput(os.Stdout)
+	if cfg.DebugLog {
+		log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
+		log.Println("Modo de depuración activado.")
+	} else {
+		log.SetFlags(log.Ldate | log.Ltime)
+	}
+
+	registry, err := printers.FromConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("error en la configuración de impresoras: %w", err)
+	}
+
+	validation, err := service.ParseValidationMode(cfg.Validation.Mode)
+	if err != nil {
+		return fmt.Errorf("error en la configuración de validación: %w", err)
+	}
+
+	receipts, err := service.LoadReceipts(cfg.ReceiptsDir)
+	if err != nil {
+		return fmt.Errorf("error al cargar los recibos libres: %w", err)
+	}
+
+	printed, err := journal.Open(journal.Options{
+		Dir:       cfg.JournalDir,
+		Retention: time.Duration(cfg.JournalRetentionDays) * 24 * time.Hour,
+	})
+	if err != nil {
+		return fmt.Errorf("error al abrir el journal: %w", err)
+	}
+	defer func() {
+		if err := printed.Close(); err != nil {
+			log.Printf("Error al cerrar el journal: %v", err)
+		}
+	}()
+
+	hub := events.NewHub()
+	jobs, err := queue.Open(queue.Options{
+		Dir:            cfg.Queue.Dir,
+		MaxAttempts:    cfg.Queue.MaxAttempts,
+		BackoffInitial: time.Duration(cfg.Queue.BackoffInitialMs) * time.Millisecond,
+		BackoffMax:     time.Duration(cfg.Queue.BackoffMaxMs) * time.Millisecond,
+		DedupWindow:    time.Duration(cfg.Queue.DedupWindowMs) * time.Millisecond,
+		Retention:      time.Duration(cfg.Queue.RetentionHours) * time.Hour,
+		Notify: func(job *queue.Job) {
+			hub.Publish(events.FromJob(job))
+			recordPrinted(printed, job)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error al abrir la cola de trabajos: %w", err)
+	}
+
+	// Si el arranque falla se cierran la cola y los puertos ya abiertos; una
+	// vez que los workers corren, los cierra el apagado normal
+	var (
+		rawPorts  []*rawport.Listener
+		lpdServer *lpd.Server
+		running   bool
+	)
+	defer func() {
+		if running {
+			return
+		}
+		for _, l := range rawPorts {
+			if err := l.Close(); err != nil {
+				log.Printf("Error al cerrar puerto raw: %v", err)
+			}
+		}
+		if lpdServer != nil {
+			if err := lpdServer.Close(); err != nil {
+				log.Printf("Error al cerrar servidor LPD: %v", err)
+			}
+		}
+		if err := jobs.Close(); err != nil {
+			log.Printf("Error al cerrar la cola de trabajos: %v", err)
+		}
+	}()
+
+	monitor := status.NewMonitor(registry, hub)
+	rawPorts, err = listenRaw(cfg, registry, jobs, monitor)
+	if err != nil {
+		return err
+	}
+	lpdServer, err = listenLPD(cfg, registry, jobs)
+	if err != nil {
+		return err
+	}
+	spooler, err := newHotFolder(cfg, registry, jobs, validation)
+	if err != nil {
+		return err
+	}
+	server := rest.NewServer(rest.Options{
+		TemplatesDir:    cfg.TemplatesDir,
+		DefaultTemplate: cfg.DefaultTemplate,
+		Queue:           jobs,
+		Events:          hub,
+		Journal:         printed,
+		Printers:        registry,
+		Status:          monitor,
+		Receipts:        receipts,
+		Validation:      validation,
+	})
+
+	httpServer := &http.Server{
+		Addr:              cfg.ListenAddr,
+		Handler:           server,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	running = true
+	var workers sync.WaitGroup
+	workers.Add(2)
+	go func() {
+		defer workers.Done()
+		jobs.Run(ctx, printJob(registry, monitor, receipts))
+	}()
+	go func() {
+		defer workers.Done()
+		monitor.Run(ctx)
+	}()
+	for _, l := range rawPorts {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			if err := l.Serve(ctx); err != nil {
+				log.Printf("Error en puerto raw: %v", err)
+			}
+		}()
+	}
+	if spooler != nil {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			spooler.Run(ctx)
+		}()
+	}
+	if lpdServer != nil {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			if err := lpdServer.Serve(ctx); err != nil {
+				log.Printf("Error en servidor LPD: %v", err)
+			}
+		}()
+	}
+
+	go func() {
+		log.Printf("posd escuchando en %s", cfg.ListenAddr)
+		for _, p := range registry.List() {
+			poll := "sin consulta de estado"
+			if p.Poll > 0 {
+				poll = "estado cada " + p.Poll.String()
+			}
+			log.Printf("Impresora %s (%s, %s, %s)", p.Name, p.Profile, p.Connector, poll)
+		}
+		for _, l := range rawPorts {
+			log.Printf("Puerto raw %s para %s", l.Addr(), l.Destination().Name)
+		}
+		if lpdServer != nil {
+			log.Printf("Servidor LPD en %s", lpdServer.Addr())
+		}
+		if spooler != nil {
+			log.Printf("Vigilando tickets en %s", spooler.Dir())
+		}
+		if names := receipts.Names(); len(names) > 0 {
+			log.Printf("Recibos libres: %s", strings.Join(names, ", "))
+		}
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Error en servidor HTTP: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Deteniendo posd...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error al detener servidor HTTP: %v", err)
+	}
+
+	// La cola se cierra solo cuando el worker terminó de usarla
+	workers.Wait()
+	if err := jobs.Close(); err != nil {
+		log.Printf("Error al cerrar la cola de trabajos: %v", err)
+	}
+	return nil
+}
+
+// listenLPD abre el servidor LPD si está configurado; sin dirección de escucha
+// devuelve nil
+func listenLPD(cfg *models.ConfigData, registry *printers.Registry, jobs *queue.Queue) (*lpd.Server, error) {
+	if cfg.LPD.Listen == "" {
+		return nil, nil
+	}
+	s, err := lpd.Listen(lpd.Options{
+		Addr:     cfg.LPD.Listen,
+		Printers: registry,
+		Queues:   cfg.LPD.Queues,
+		Queue:    jobs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("servidor LPD %s: %w", cfg.LPD.Listen, err)
+	}
+	return s, nil
+}
+
+// newHotFolder prepara el directorio vigilado si está configurado; sin
+// directorio devuelve nil
+func newHotFolder(cfg *models.ConfigData, registry *printers.Registry, jobs *queue.Queue, validation service.ValidationMode) (*hotfolder.Spooler, error) {
+	hf := cfg.HotFolder
+	if hf.Dir == "" {
+		return nil, nil
+	}
+	s, err := hotfolder.New(hotfolder.Options{
+		Dir:             hf.Dir,
+		Queue:           jobs,
+		Printers:        registry,
+		Printer:         hf.Printer,
+		TemplatesDir:    cfg.TemplatesDir,
+		Template:        hf.Template,
+		DefaultTemplate: cfg.DefaultTemplate,
+		Poll:            time.Duration(hf.PollMs) * time.Millisecond,
+		StableFor:       time.Duration(hf.StableMs) * time.Millisecond,
+		Validation:      validation,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("directorio vigilado %s: %w", hf.Dir, err)
+	}
+	return s, nil
+}
+
+// recordPrinted guarda en el journal los tickets originales y los trabajos raw
+// o de texto que terminaron de imprimirse
+func recordPrinted(printed *journal.Journal, job *queue.Job) {
+	// Los recibos libres comparten los datos del ticket pero no son el ticket;
+	// no deben reemplazar su entrada
+	if job.State != queue.StateDone || job.Reprint != nil || job.Kind == queue.KindReceipt {
+		return
+	}
+	err := printed.Record(journal.Entry{
+		Identificador: job.Identificador,
+		Serie:         job.Serie,
+		Folio:         job.Folio,
+		JobID:         job.ID,
+		Printer:       job.Printer,
+		Ticket:        job.Ticket,
+		Template:      job.Template,
+		Raw:           job.Raw,
+		Text:          job.Text,
+		PrintedAt:     job.UpdatedAt,
+	})
+	if err != nil {
+		log.Printf("Error al registrar el trabajo %s en el journal: %v", job.ID, err)
+	}
+}
+
+// listenRaw abre los puertos raw configurados. Cada uno encola los trabajos
+// que recibe para su impresora o grupo.
+func listenRaw(cfg *models.ConfigData, registry *printers.Registry, jobs *queue.Queue, monitor *status.Monitor) ([]*rawport.Listener, error) {
+	var listeners []*rawport.Listener
+	closeAll := func() {
+		for _, l := range listeners {
+			if err := l.Close(); err != nil {
+				log.Printf("Error al cerrar puerto raw: %v", err)
+			}
+		}
+	}
+	for _, rc := range cfg.Raw {
+		dest, err := registry.Route(printers.Target{Printer: rc.Printer})
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("puerto raw %s: %w", rc.Listen, err)
+		}
+		l, err := rawport.Listen(rawport.Options{
+			Addr:        rc.Listen,
+			Destination: dest,
+			Queue:       jobs,
+			Monitor:     monitor,
+			IdleTimeout: time.Duration(rc.IdleTimeoutMs) * time.Millisecond,
+		})
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("puerto raw %s: %w", rc.Listen, err)
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
@@ -2,10 +2,10 @@ module pos-daemon.adcon.dev
 
 go 1.24.6
 
-require golang.org/x/text v0.28.0 // indirect
+require golang.org/x/text v0.28.0
 
 require github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
 
-require golang.org/x/image v0.30.0 // indirect
+require golang.org/x/image v0.30.0
 
 require github.com/AdConDev/pos-printer v0.2.0
@@ -0,0 +1,2 @@
+// Package rest expone la API HTTP del daemon para recibir e imprimir tickets.
+package rest
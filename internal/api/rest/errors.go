@@ -0,0 +1,52 @@
+package rest
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"pos-daemon.adcon.dev/internal/service"
+)
+
+// Códigos de error devueltos por la API
+const (
+	CodeInvalidBody     = "invalid_body"
+	CodeInvalidTicket   = "invalid_ticket"
+	CodeInvalidTemplate = "invalid_template"
+	CodeInvalidReceipt  = "invalid_receipt"
+	CodeInvalidQuery    = "invalid_query"
+	CodeNotFound        = "not_found"
+	CodeQueueError      = "queue_error"
+	CodeJournalError    = "journal_error"
+	CodeRenderError     = "render_error"
+	CodeNoRoute         = "no_route"
+	CodeValidation      = "validation_failed"
+)
+
+// APIError describe un error estructurado de la API
+type APIError struct {
+	Code    string `json:"code"`    // Código estable para el cliente
+	Message string `json:"message"` // Descripción legible del error
+
+	// Findings son las observaciones de un ticket rechazado por la validación
+	Findings service.Findings `json:"findings,omitempty"`
+}
+
+// errorResponse es el cuerpo JSON de cualquier respuesta con error
+type errorResponse struct {
+	Error APIError `json:"error"`
+}
+
+// writeJSON serializa v como JSON con el código de estado indicado
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("rest: error al escribir respuesta: %v", err)
+	}
+}
+
+// writeError responde con un error estructurado
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, errorResponse{Error: APIError{Code: code, Message: message}})
+}
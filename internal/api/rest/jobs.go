@@ -0,0 +1,90 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"pos-daemon.adcon.dev/internal/queue"
+)
+
+// JobView es la representación pública de un trabajo, sin el JSON del ticket
+type JobView struct {
+	ID            string      `json:"id"`
+	State         queue.State `json:"state"`
+	Kind          queue.Kind  `json:"kind,omitempty"`
+	Receipt       string      `json:"receipt,omitempty"`
+	Printer       string      `json:"printer,omitempty"`
+	PrintedBy     string      `json:"printed_by,omitempty"`
+	Identificador string      `json:"identificador,omitempty"`
+	Serie         string      `json:"serie,omitempty"`
+	Folio         string      `json:"folio,omitempty"`
+	Attempts      int         `json:"attempts"`
+	LastError     string      `json:"last_error,omitempty"`
+	Warnings      []string    `json:"warnings,omitempty"` // Aviso de validación impreso en el ticket
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+	NextAttemptAt *time.Time  `json:"next_attempt_at,omitempty"`
+}
+
+// JobListResponse es la respuesta de GET /v1/jobs
+type JobListResponse struct {
+	Jobs []JobView `json:"jobs"`
+}
+
+// newJobView convierte un trabajo de la cola a su vista pública
+func newJobView(job *queue.Job) JobView {
+	v := JobView{
+		ID:            job.ID,
+		State:         job.State,
+		Kind:          job.Kind,
+		Receipt:       job.Receipt,
+		Printer:       job.Printer,
+		PrintedBy:     job.PrintedBy,
+		Identificador: job.Identificador,
+		Serie:         job.Serie,
+		Folio:         job.Folio,
+		Attempts:      job.Attempts,
+		LastError:     job.LastError,
+		Warnings:      job.Warnings,
+		CreatedAt:     job.CreatedAt,
+		UpdatedAt:     job.UpdatedAt,
+	}
+	if !job.NextAttemptAt.IsZero() {
+		next := job.NextAttemptAt
+		v.NextAttemptAt = &next
+	}
+	return v
+}
+
+// handleGetJob devuelve el estado de un trabajo
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	job, err := s.opts.Queue.Get(r.PathValue("id"))
+	if errors.Is(err, queue.ErrNotFound) {
+		writeError(w, http.StatusNotFound, CodeNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeQueueError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, newJobView(job))
+}
+
+// handleListJobs lista los trabajos, opcionalmente filtrados con ?state=
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	state := queue.State(r.URL.Query().Get("state"))
+	switch state {
+	case "", queue.StateQueued, queue.StatePrinting, queue.StateDone, queue.StateFailed, queue.StateDead:
+	default:
+		writeError(w, http.StatusBadRequest, CodeInvalidQuery, fmt.Sprintf("estado desconocido: %q", state))
+		return
+	}
+
+	resp := JobListResponse{Jobs: []JobView{}}
+	for _, job := range s.opts.Queue.List(state) {
+		resp.Jobs = append(resp.Jobs, newJobView(job))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,83 @@
+package rest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"log"
+	"net/http"
+
+	"github.com/AdConDev/pos-printer/profile"
+	"pos-daemon.adcon.dev/internal/preview"
+	"pos-daemon.adcon.dev/internal/queue"
+	"pos-daemon.adcon.dev/internal/receipt"
+	"pos-daemon.adcon.dev/internal/service"
+)
+
+// handlePreview dibuja como PNG el ticket de un trabajo de la cola, tal como
+// saldría en la impresora que le corresponde. Los trabajos raw se dibujan a
+// partir de sus bytes ESC/POS, los de texto acomodados al ancho del perfil y
+// los recibos libres con su plantilla.
+func (s *Server) handlePreview(w http.ResponseWriter, r *http.Request) {
+	job, err := s.opts.Queue.Get(r.PathValue("id"))
+	if errors.Is(err, queue.ErrNotFound) {
+		writeError(w, http.StatusNotFound, CodeNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeQueueError, err.Error())
+		return
+	}
+
+	// La vista previa usa la impresora que imprimió el ticket o, si aún no se
+	// imprime, la principal de su destino
+	prof := profile.CreateProfile80mm()
+	if s.opts.Printers != nil {
+		if printer, err := s.opts.Printers.Get(job.PrintedBy); err == nil {
+			prof = printer.NewProfile()
+		} else if dest, err := s.opts.Printers.Resolve(job.Printer); err == nil {
+			prof = dest.Primary().NewProfile()
+		}
+	}
+	var buf bytes.Buffer
+	if job.IsRaw() {
+		// Los trabajos raw se dibujan interpretando los bytes recibidos
+		if err := receipt.Parse(job.Raw).PNG(&buf, prof); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, CodeRenderError, fmt.Sprintf("no se pudo dibujar el trabajo: %v", err))
+			return
+		}
+	} else {
+		var img *image.Gray
+		switch job.Kind {
+		case queue.KindText:
+			img, err = preview.RenderText(job.Text, prof)
+		case queue.KindReceipt:
+			var receipt *service.Receipt
+			if receipt, err = s.opts.Receipts.Get(job.Receipt); err == nil {
+				img, err = preview.RenderReceipt(receipt, job.Ticket, prof)
+			}
+		default:
+			opts := service.TicketOptions{Warnings: job.Warnings}
+			if job.Reprint != nil {
+				opts.Reprint = &service.Reprint{Number: job.Reprint.Number, At: job.Reprint.At}
+			}
+			img, err = preview.RenderTicket(job.Template, job.Ticket, prof, opts)
+		}
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, CodeRenderError, fmt.Sprintf("no se pudo dibujar el ticket: %v", err))
+			return
+		}
+		if err := png.Encode(&buf, img); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeRenderError, err.Error())
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		log.Printf("rest: error al escribir vista previa: %v", err)
+	}
+}
@@ -0,0 +1,19 @@
+package rest
+
+import (
+	"net/http"
+)
+
+// handlePrinterStatus devuelve el último estado conocido de una impresora
+func (s *Server) handlePrinterStatus(w http.ResponseWriter, r *http.Request) {
+	if s.opts.Status == nil {
+		writeError(w, http.StatusNotFound, CodeNotFound, "el estado de las impresoras no está disponible")
+		return
+	}
+	st, err := s.opts.Status.Status(r.PathValue("name"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, CodeNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
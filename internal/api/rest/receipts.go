@@ -0,0 +1,89 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"pos-daemon.adcon.dev/internal/models"
+	"pos-daemon.adcon.dev/internal/printers"
+	"pos-daemon.adcon.dev/internal/queue"
+	"pos-daemon.adcon.dev/internal/service"
+)
+
+// ReceiptRequest es el cuerpo de POST /v1/receipts
+type ReceiptRequest struct {
+	Receipt string          `json:"receipt"`           // Nombre del recibo libre (archivo sin .tmpl)
+	Printer string          `json:"printer,omitempty"` // Impresora explícita; omite las reglas de ruteo
+	Ticket  json.RawMessage `json:"ticket"`            // Ticket con el formato de POST /v1/tickets
+}
+
+// handleCreateReceipt encola un ticket para imprimirse con un recibo libre.
+// El recibo se ejecuta con el ticket antes de encolar, de modo que los errores
+// de la plantilla se reportan en la respuesta. Los reenvíos del mismo recibo
+// para el mismo ticket se deduplican, sin chocar con el ticket original.
+func (s *Server) handleCreateReceipt(w http.ResponseWriter, r *http.Request) {
+	var req ReceiptRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidBody, "cuerpo inválido: "+err.Error())
+		return
+	}
+	if req.Receipt == "" || len(req.Ticket) == 0 {
+		writeError(w, http.StatusBadRequest, CodeInvalidBody, "se requieren receipt y ticket")
+		return
+	}
+
+	receipt, err := s.opts.Receipts.Get(req.Receipt)
+	if errors.Is(err, service.ErrReceiptNotFound) {
+		writeError(w, http.StatusNotFound, CodeNotFound, err.Error())
+		return
+	}
+	var ticket models.NewTicket
+	if err := json.Unmarshal(req.Ticket, &ticket); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidTicket, "error al leer el ticket JSON: "+err.Error())
+		return
+	}
+	if err := receipt.Check(req.Ticket); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, CodeInvalidReceipt, err.Error())
+		return
+	}
+
+	enqueue := queue.Request{
+		Ticket:  req.Ticket,
+		Receipt: receipt.Name(),
+		TicketRef: queue.TicketRef{
+			Identificador: ticket.Data.Identificador,
+			Serie:         ticket.Data.Serie,
+			Folio:         ticket.Data.Folio,
+		},
+	}
+	if s.opts.Printers != nil {
+		target := printers.TargetFromTicket(&ticket)
+		if req.Printer != "" {
+			target.Printer = req.Printer
+		}
+		dest, err := s.opts.Printers.Route(target)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, CodeNoRoute, err.Error())
+			return
+		}
+		enqueue.Printer = dest.Name
+	}
+
+	key := queue.IdempotencyKey(r.Header.Get(IdempotencyKeyHeader), enqueue.TicketRef)
+	if key != "" {
+		key = "receipt:" + receipt.Name() + ":" + key
+	}
+	job, duplicate, err := s.opts.Queue.EnqueueOnce(key, enqueue)
+	if err != nil {
+		log.Printf("rest: no se pudo encolar el recibo: %v", err)
+		writeError(w, http.StatusInternalServerError, CodeQueueError, err.Error())
+		return
+	}
+	if duplicate {
+		writeJSON(w, http.StatusOK, TicketResponse{JobID: job.ID, Status: job.State, Printer: job.Printer, Duplicate: true})
+		return
+	}
+	writeJSON(w, http.StatusAccepted, TicketResponse{JobID: job.ID, Status: job.State, Printer: job.Printer})
+}
@@ -0,0 +1,111 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"pos-daemon.adcon.dev/internal/journal"
+	"pos-daemon.adcon.dev/internal/models"
+	"pos-daemon.adcon.dev/internal/printers"
+	"pos-daemon.adcon.dev/internal/queue"
+)
+
+// ReprintRequest es el cuerpo de POST /v1/reprints. Se busca por Identificador,
+// por Serie y Folio o por el JobID del original, en ese orden; los trabajos
+// raw y de texto solo se encuentran por JobID.
+type ReprintRequest struct {
+	Identificador string `json:"identificador,omitempty"`
+	Serie         string `json:"serie,omitempty"`
+	Folio         string `json:"folio,omitempty"`
+	JobID         string `json:"job_id,omitempty"`
+	Printer       string `json:"printer,omitempty"` // Impresora explícita; omite las reglas de ruteo
+}
+
+// ReprintResponse es la respuesta de POST /v1/reprints
+type ReprintResponse struct {
+	JobID   string      `json:"job_id"`
+	Status  queue.State `json:"status"`
+	Printer string      `json:"printer,omitempty"` // Impresora elegida por el ruteo
+	Reprint int         `json:"reprint"`           // Número de reimpresión asignado
+}
+
+// handleCreateReprint reimprime un ticket del journal marcado como copia.
+// No pasa por la deduplicación de POST /v1/tickets. Los trabajos raw y de
+// texto se reenvían tal cual, sin la marca de copia.
+func (s *Server) handleCreateReprint(w http.ResponseWriter, r *http.Request) {
+	var req ReprintRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidBody, "cuerpo inválido: "+err.Error())
+		return
+	}
+	if req.Identificador == "" && req.Serie == "" && req.Folio == "" && req.JobID == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidBody, "se requiere identificador, serie/folio o job_id")
+		return
+	}
+
+	// El número de la copia se registra solo cuando ya está en la cola; una
+	// reimpresión que no se pudo rutear o encolar no lo consume
+	s.reprintMu.Lock()
+	defer s.reprintMu.Unlock()
+	entry, err := s.opts.Journal.Find(journal.Query{
+		Identificador: req.Identificador,
+		Serie:         req.Serie,
+		Folio:         req.Folio,
+		JobID:         req.JobID,
+	})
+	if errors.Is(err, journal.ErrNotFound) {
+		writeError(w, http.StatusNotFound, CodeNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeJournalError, err.Error())
+		return
+	}
+
+	enqueue := queue.Request{
+		Ticket:    entry.Ticket,
+		Template:  entry.Template,
+		TicketRef: queue.TicketRef{Identificador: entry.Identificador, Serie: entry.Serie, Folio: entry.Folio},
+		Raw:       entry.Raw,
+		Text:      entry.Text,
+		Printer:   entry.Printer,
+	}
+	if s.opts.Printers != nil {
+		// La copia sigue las mismas reglas que el original, salvo que se pida
+		// una impresora explícita. Los trabajos raw y de texto no traen
+		// ticket y vuelven a la impresora del original.
+		target := printers.Target{Printer: entry.Printer}
+		if len(entry.Raw) == 0 && entry.Text == "" {
+			var ticket models.NewTicket
+			if err := json.Unmarshal(entry.Ticket, &ticket); err != nil {
+				writeError(w, http.StatusInternalServerError, CodeJournalError, "ticket del journal inválido: "+err.Error())
+				return
+			}
+			target = printers.TargetFromTicket(&ticket)
+		}
+		if req.Printer != "" {
+			target.Printer = req.Printer
+		}
+		dest, err := s.opts.Printers.Route(target)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, CodeNoRoute, err.Error())
+			return
+		}
+		enqueue.Printer = dest.Name
+	}
+	reprint := queue.Reprint{Number: entry.Reprints + 1, At: time.Now()}
+	job, err := s.opts.Queue.EnqueueReprint(enqueue, reprint)
+	if err != nil {
+		log.Printf("rest: no se pudo encolar la reimpresión: %v", err)
+		writeError(w, http.StatusInternalServerError, CodeQueueError, err.Error())
+		return
+	}
+	if err := s.opts.Journal.RecordReprint(entry, reprint.Number, reprint.At); err != nil {
+		// La copia ya está en la cola y va a imprimirse
+		log.Printf("rest: no se pudo registrar la reimpresión #%d del trabajo %s: %v", reprint.Number, job.ID, err)
+	}
+	writeJSON(w, http.StatusAccepted, ReprintResponse{JobID: job.ID, Status: job.State, Printer: job.Printer, Reprint: reprint.Number})
+}
@@ -0,0 +1,218 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"pos-daemon.adcon.dev/internal/events"
+	"pos-daemon.adcon.dev/internal/journal"
+	"pos-daemon.adcon.dev/internal/models"
+	"pos-daemon.adcon.dev/internal/printers"
+	"pos-daemon.adcon.dev/internal/queue"
+	"pos-daemon.adcon.dev/internal/service"
+	"pos-daemon.adcon.dev/internal/status"
+)
+
+// MaxBodyBytes limita el tamaño del cuerpo aceptado en POST /v1/tickets
+const MaxBodyBytes = 1 << 20
+
+// Options contiene la configuración del servidor HTTP
+type Options struct {
+	TemplatesDir    string           // Directorio donde viven las plantillas JSON
+	DefaultTemplate string           // Plantilla usada cuando la petición no indica una
+	Queue           *queue.Queue     // Cola persistente donde se encolan los trabajos
+	Events          *events.Hub      // Origen de los eventos para /v1/events
+	Journal         *journal.Journal // Tickets impresos disponibles para reimpresión
+
+	// Printers elige la impresora de cada ticket. Si es nil los trabajos se
+	// encolan sin impresora y las vistas previas asumen papel de 80mm.
+	Printers *printers.Registry
+
+	// Status informa el estado de las impresoras para /v1/printers/{name}/status
+	Status *status.Monitor
+
+	// Receipts son los recibos libres disponibles para POST /v1/receipts
+	Receipts *service.Receipts
+
+	// Validation decide qué pasa con los tickets que no pasan la validación;
+	// vacío es service.ValidationWarn
+	Validation service.ValidationMode
+}
+
+// Server atiende las peticiones de impresión de tickets
+type Server struct {
+	opts Options
+	mux  *http.ServeMux
+
+	// reprintMu serializa las reimpresiones entre la búsqueda en el journal y
+	// el registro del número asignado
+	reprintMu sync.Mutex
+}
+
+// TicketResponse es la respuesta de POST /v1/tickets
+type TicketResponse struct {
+	JobID     string      `json:"job_id"`
+	Status    queue.State `json:"status"`
+	Printer   string      `json:"printer,omitempty"`   // Impresora o grupo elegido por el ruteo
+	Duplicate bool        `json:"duplicate,omitempty"` // El ticket ya se había recibido
+
+	// Findings son las observaciones de la validación del ticket
+	Findings service.Findings `json:"findings,omitempty"`
+}
+
+// IdempotencyKeyHeader permite al cliente fijar su propia llave de deduplicación
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// NewServer crea un servidor con las rutas de la API registradas
+func NewServer(opts Options) *Server {
+	if opts.Validation == "" {
+		opts.Validation = service.ValidationWarn
+	}
+	s := &Server{
+		opts: opts,
+		mux:  http.NewServeMux(),
+	}
+	s.mux.HandleFunc("POST /v1/tickets", s.handleCreateTicket)
+	s.mux.HandleFunc("GET /v1/jobs", s.handleListJobs)
+	s.mux.HandleFunc("GET /v1/jobs/{id}", s.handleGetJob)
+	s.mux.HandleFunc("GET /v1/events", s.handleEvents)
+	s.mux.HandleFunc("GET /v1/tickets/{id}/preview.png", s.handlePreview)
+	s.mux.HandleFunc("POST /v1/reprints", s.handleCreateReprint)
+	s.mux.HandleFunc("POST /v1/receipts", s.handleCreateReceipt)
+	s.mux.HandleFunc("GET /v1/printers/{name}/status", s.handlePrinterStatus)
+	return s
+}
+
+// ServeHTTP implementa http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handleCreateTicket recibe un models.NewTicket y lo encola para impresión.
+// La impresora se elige con ?printer=<nombre>, el campo "printer" del cuerpo o
+// las reglas de ruteo; la plantilla con ?template=<nombre> o la de la impresora.
+// Los reenvíos del mismo ticket dentro de la ventana de deduplicación devuelven
+// el trabajo original. Las observaciones de la validación se devuelven en la
+// respuesta y, según Options.Validation, rechazan el ticket o se imprimen en
+// un aviso.
+func (s *Server) handleCreateTicket(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidBody, fmt.Sprintf("no se pudo leer el cuerpo: %v", err))
+		return
+	}
+	if len(body) == 0 {
+		writeError(w, http.StatusBadRequest, CodeInvalidBody, "el cuerpo de la petición está vacío")
+		return
+	}
+
+	var ticket models.NewTicket
+	if err := json.Unmarshal(body, &ticket); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidTicket, fmt.Sprintf("error al leer el ticket JSON: %v", err))
+		return
+	}
+
+	findings := service.ValidateTicket(&ticket.Data)
+	if len(findings) > 0 {
+		log.Printf("rest: observaciones del ticket %s: %s", ticket.Data.Identificador, strings.Join(findings.Messages(), "; "))
+	}
+	if s.opts.Validation.Rejects(findings) {
+		writeJSON(w, http.StatusUnprocessableEntity, errorResponse{Error: APIError{
+			Code:     CodeValidation,
+			Message:  "el ticket no pasó la validación",
+			Findings: findings,
+		}})
+		return
+	}
+
+	dest, err := s.route(r, &ticket)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, CodeNoRoute, err.Error())
+		return
+	}
+
+	templateName := r.URL.Query().Get("template")
+	if templateName == "" && dest != nil {
+		templateName = dest.Primary().DefaultTemplate
+	}
+	templateData, err := s.loadTemplate(templateName)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidTemplate, err.Error())
+		return
+	}
+	// Validar la plantilla antes de encolar
+	check := service.NewTicketConstructor(io.Discard, nil)
+	if err := check.LoadTemplateFromJSON(templateData); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidTemplate, err.Error())
+		return
+	}
+
+	req := queue.Request{
+		Ticket:   body,
+		Template: templateData,
+		TicketRef: queue.TicketRef{
+			Identificador: ticket.Data.Identificador,
+			Serie:         ticket.Data.Serie,
+			Folio:         ticket.Data.Folio,
+		},
+		Warnings: s.opts.Validation.Banner(findings),
+	}
+	if dest != nil {
+		req.Printer = dest.Name
+	}
+	key := queue.IdempotencyKey(r.Header.Get(IdempotencyKeyHeader), req.TicketRef)
+	job, duplicate, err := s.opts.Queue.EnqueueOnce(key, req)
+	if err != nil {
+		log.Printf("rest: no se pudo encolar el ticket: %v", err)
+		writeError(w, http.StatusInternalServerError, CodeQueueError, err.Error())
+		return
+	}
+
+	if duplicate {
+		log.Printf("rest: ticket duplicado (%s), se devuelve el trabajo %s", key, job.ID)
+		writeJSON(w, http.StatusOK, TicketResponse{JobID: job.ID, Status: job.State, Printer: job.Printer, Duplicate: true, Findings: findings})
+		return
+	}
+	writeJSON(w, http.StatusAccepted, TicketResponse{JobID: job.ID, Status: job.State, Printer: job.Printer, Findings: findings})
+}
+
+// route elige la impresora o grupo del ticket. Devuelve nil sin error si el
+// servidor no tiene impresoras configuradas.
+func (s *Server) route(r *http.Request, ticket *models.NewTicket) (*printers.Destination, error) {
+	if s.opts.Printers == nil {
+		return nil, nil
+	}
+	target := printers.TargetFromTicket(ticket)
+	if explicit := r.URL.Query().Get("printer"); explicit != "" {
+		target.Printer = explicit
+	}
+	return s.opts.Printers.Route(target)
+}
+
+// loadTemplate lee la plantilla indicada o la plantilla por defecto
+func (s *Server) loadTemplate(name string) ([]byte, error) {
+	if name == "" {
+		name = s.opts.DefaultTemplate
+	}
+	if name == "" {
+		return nil, fmt.Errorf("no se indicó plantilla y no hay plantilla por defecto")
+	}
+	// Solo se aceptan nombres simples, sin rutas
+	if strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return nil, fmt.Errorf("nombre de plantilla inválido: %q", name)
+	}
+	if !strings.HasSuffix(strings.ToLower(name), ".json") {
+		name += ".json"
+	}
+	data, err := models.ReadJSONFile(s.opts.TemplatesDir, filepath.Join(s.opts.TemplatesDir, name))
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo cargar la plantilla %q: %w", name, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,539 @@
+package rest
+
+import (
+	"encoding/json"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pos-daemon.adcon.dev/internal/events"
+	"pos-daemon.adcon.dev/internal/journal"
+	"pos-daemon.adcon.dev/internal/printers"
+	"pos-daemon.adcon.dev/internal/queue"
+	"pos-daemon.adcon.dev/internal/service"
+	"pos-daemon.adcon.dev/internal/status"
+)
+
+func TestCreateTicketErrors(t *testing.T) {
+	srv := NewServer(Options{
+		TemplatesDir:    ".",
+		DefaultTemplate: "new_ticket_template",
+	})
+
+	tests := []struct {
+		name     string
+		url      string
+		body     string
+		wantCode string
+	}{
+		{"Cuerpo vacío", "/v1/tickets", "", CodeInvalidBody},
+		{"Plantilla con ruta", "/v1/tickets?template=../config", `{"data":{}}`, CodeInvalidTemplate},
+		{"Plantilla inexistente", "/v1/tickets?template=no_existe", `{"data":{}}`, CodeInvalidTemplate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, tt.url, strings.NewReader(tt.body))
+			srv.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d; want %d", rec.Code, http.StatusBadRequest)
+			}
+			var resp errorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("respuesta no es JSON: %v", err)
+			}
+			if resp.Error.Code != tt.wantCode {
+				t.Errorf("code = %q; want %q", resp.Error.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestJobsEndpoints(t *testing.T) {
+	q, err := queue.Open(queue.Options{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("queue.Open: %v", err)
+	}
+	defer q.Close()
+	job, err := q.Enqueue(queue.Request{
+		Ticket:    []byte(`{}`),
+		Template:  []byte(`{}`),
+		TicketRef: queue.TicketRef{Identificador: "NTQ3", Serie: "ABC1", Folio: "326"},
+	})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	srv := NewServer(Options{Queue: q, Events: events.NewHub()})
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/jobs/"+job.ID, nil))
+	var view JobView
+	if err := json.NewDecoder(rec.Body).Decode(&view); err != nil {
+		t.Fatalf("respuesta no es JSON: %v", err)
+	}
+	if view.State != queue.StateQueued || view.Folio != "326" || view.Serie != "ABC1" {
+		t.Errorf("GET /v1/jobs/{id} = %+v", view)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/jobs/no-existe", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d; want %d", rec.Code, http.StatusNotFound)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/jobs?state=failed", nil))
+	var list JobListResponse
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("respuesta no es JSON: %v", err)
+	}
+	if len(list.Jobs) != 0 {
+		t.Errorf("GET /v1/jobs?state=failed = %d trabajos; want 0", len(list.Jobs))
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/jobs?state=roto", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d; want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestPreviewEndpoint(t *testing.T) {
+	ticket, err := os.ReadFile("new_ticket.json")
+	if err != nil {
+		t.Fatalf("error al leer ticket: %v", err)
+	}
+	template, err := os.ReadFile("new_ticket_template.json")
+	if err != nil {
+		t.Fatalf("error al leer plantilla: %v", err)
+	}
+	q, err := queue.Open(queue.Options{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("queue.Open: %v", err)
+	}
+	defer q.Close()
+	job, err := q.Enqueue(queue.Request{Ticket: ticket, Template: template})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	srv := NewServer(Options{Queue: q})
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/tickets/"+job.ID+"/preview.png", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "image/png" {
+		t.Errorf("Content-Type = %q; want image/png", ct)
+	}
+	if _, err := png.Decode(rec.Body); err != nil {
+		t.Errorf("la respuesta no es un PNG: %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/tickets/no-existe/preview.png", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d; want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestPreviewRawJob(t *testing.T) {
+	q, err := queue.Open(queue.Options{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("queue.Open: %v", err)
+	}
+	defer q.Close()
+	job, err := q.Enqueue(queue.Request{Raw: []byte("\x1b@VENTA 123\n\x1dV\x00")})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	srv := NewServer(Options{Queue: q})
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/jobs/"+job.ID, nil))
+	if !strings.Contains(rec.Body.String(), `"kind":"raw"`) {
+		t.Errorf("GET /v1/jobs/{id} = %s; want kind raw", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/tickets/"+job.ID+"/preview.png", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if _, err := png.Decode(rec.Body); err != nil {
+		t.Errorf("la respuesta no es un PNG: %v", err)
+	}
+}
+
+func TestPreviewTextJob(t *testing.T) {
+	q, err := queue.Open(queue.Options{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("queue.Open: %v", err)
+	}
+	defer q.Close()
+	job, err := q.Enqueue(queue.Request{Text: "Cierre de caja\n\tTotal\t$1,234.00\n"})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	srv := NewServer(Options{Queue: q})
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/tickets/"+job.ID+"/preview.png", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if _, err := png.Decode(rec.Body); err != nil {
+		t.Errorf("la respuesta no es un PNG: %v", err)
+	}
+}
+
+func TestCreateTicketRouting(t *testing.T) {
+	ticket, err := os.ReadFile("new_ticket.json")
+	if err != nil {
+		t.Fatalf("error al leer ticket: %v", err)
+	}
+	q, err := queue.Open(queue.Options{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("queue.Open: %v", err)
+	}
+	defer q.Close()
+	registry, err := printers.New(
+		[]printers.Printer{{Name: "mostrador"}, {Name: "cocina"}},
+		nil,
+		[]printers.Rule{{Sucursal: "S0001", Printer: "mostrador"}},
+	)
+	if err != nil {
+		t.Fatalf("printers.New: %v", err)
+	}
+	srv := NewServer(Options{
+		TemplatesDir:    ".",
+		DefaultTemplate: "new_ticket_template",
+		Queue:           q,
+		Printers:        registry,
+	})
+
+	tests := []struct {
+		name        string
+		url         string
+		body        string
+		wantStatus  int
+		wantPrinter string
+	}{
+		{"Regla por sucursal", "/v1/tickets", string(ticket), http.StatusAccepted, "mostrador"},
+		{"Impresora explícita", "/v1/tickets?printer=cocina", strings.Replace(string(ticket), `"NTQ3"`, `"NTQ4"`, 1), http.StatusAccepted, "cocina"},
+		{"Sin ruta", "/v1/tickets", strings.Replace(string(ticket), `"S0001"`, `"S0009"`, 1), http.StatusUnprocessableEntity, ""},
+		{"Impresora desconocida", "/v1/tickets?printer=bodega", string(ticket), http.StatusUnprocessableEntity, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.url, strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d; want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusAccepted {
+				var resp errorResponse
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("respuesta no es JSON: %v", err)
+				}
+				if resp.Error.Code != CodeNoRoute {
+					t.Errorf("code = %q; want %q", resp.Error.Code, CodeNoRoute)
+				}
+				return
+			}
+			var resp TicketResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("respuesta no es JSON: %v", err)
+			}
+			job, err := q.Get(resp.JobID)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if resp.Printer != tt.wantPrinter || job.Printer != tt.wantPrinter {
+				t.Errorf("impresora = %q (trabajo %q); want %q", resp.Printer, job.Printer, tt.wantPrinter)
+			}
+		})
+	}
+}
+
+func TestCreateTicketValidation(t *testing.T) {
+	// El total de new_ticket.json no coincide con la suma de sus conceptos
+	ticket, err := os.ReadFile("new_ticket.json")
+	if err != nil {
+		t.Fatalf("error al leer ticket: %v", err)
+	}
+
+	tests := []struct {
+		mode         service.ValidationMode
+		wantStatus   int
+		wantWarnings int
+	}{
+		{service.ValidationReject, http.StatusUnprocessableEntity, 0},
+		{service.ValidationWarn, http.StatusAccepted, 1},
+		{service.ValidationSilent, http.StatusAccepted, 0},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			q, err := queue.Open(queue.Options{Dir: t.TempDir()})
+			if err != nil {
+				t.Fatalf("queue.Open: %v", err)
+			}
+			defer q.Close()
+			srv := NewServer(Options{
+				TemplatesDir:    ".",
+				DefaultTemplate: "new_ticket_template",
+				Queue:           q,
+				Validation:      tt.mode,
+			})
+
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/tickets", strings.NewReader(string(ticket))))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d; want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+
+			if tt.wantStatus != http.StatusAccepted {
+				var resp errorResponse
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("respuesta no es JSON: %v", err)
+				}
+				if resp.Error.Code != CodeValidation || len(resp.Error.Findings) != 1 || resp.Error.Findings[0].Code != service.FindingTotalMismatch {
+					t.Errorf("error = %+v", resp.Error)
+				}
+				if n := len(q.List("")); n != 0 {
+					t.Errorf("se encolaron %d trabajos", n)
+				}
+				return
+			}
+			var resp TicketResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("respuesta no es JSON: %v", err)
+			}
+			if len(resp.Findings) != 1 || resp.Findings[0].Severity != service.SeverityError {
+				t.Errorf("findings = %+v", resp.Findings)
+			}
+			job, err := q.Get(resp.JobID)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if len(job.Warnings) != tt.wantWarnings {
+				t.Errorf("avisos del trabajo = %q; want %d", job.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestPrinterStatusEndpoint(t *testing.T) {
+	registry, err := printers.New([]printers.Printer{{Name: "mostrador"}}, nil, nil)
+	if err != nil {
+		t.Fatalf("printers.New: %v", err)
+	}
+	srv := NewServer(Options{Printers: registry, Status: status.NewMonitor(registry, events.NewHub())})
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/printers/mostrador/status", nil))
+	var st status.PrinterStatus
+	if err := json.NewDecoder(rec.Body).Decode(&st); err != nil {
+		t.Fatalf("respuesta no es JSON: %v", err)
+	}
+	if rec.Code != http.StatusOK || st.Printer != "mostrador" || st.State != status.StateUnknown {
+		t.Errorf("GET /v1/printers/mostrador/status = %d %+v", rec.Code, st)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/printers/bodega/status", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d; want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestCreateReceipt(t *testing.T) {
+	ticket, err := os.ReadFile("new_ticket.json")
+	if err != nil {
+		t.Fatalf("error al leer ticket: %v", err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "rifa.tmpl"), []byte("<center>\n<double>RIFA</double>\nFolio {{.Folio}}\n</center>\n<barcode>{{.Folio}}</barcode>\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "decimo.tmpl"), []byte("{{(index .Conceptos 9).Descripcion}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	receipts, err := service.LoadReceipts(dir)
+	if err != nil {
+		t.Fatalf("LoadReceipts: %v", err)
+	}
+	q, err := queue.Open(queue.Options{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("queue.Open: %v", err)
+	}
+	defer q.Close()
+	srv := NewServer(Options{Queue: q, Receipts: receipts})
+
+	post := func(receipt string) *httptest.ResponseRecorder {
+		body := `{"receipt": "` + receipt + `", "ticket": ` + string(ticket) + `}`
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/receipts", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := post("rifa")
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d; want %d: %s", rec.Code, http.StatusAccepted, rec.Body)
+	}
+	var resp TicketResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("respuesta no es JSON: %v", err)
+	}
+	job, err := q.Get(resp.JobID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if job.Kind != queue.KindReceipt || job.Receipt != "rifa" || job.Identificador != "NTQ3" {
+		t.Errorf("trabajo = %+v", job)
+	}
+
+	// El reenvío se deduplica
+	if rec := post("rifa"); rec.Code != http.StatusOK {
+		t.Errorf("reenvío: status = %d; want %d", rec.Code, http.StatusOK)
+	}
+
+	prev := httptest.NewRecorder()
+	srv.ServeHTTP(prev, httptest.NewRequest(http.MethodGet, "/v1/tickets/"+job.ID+"/preview.png", nil))
+	if prev.Code != http.StatusOK {
+		t.Fatalf("preview: status = %d: %s", prev.Code, prev.Body)
+	}
+	if _, err := png.Decode(prev.Body); err != nil {
+		t.Errorf("la vista previa no es un PNG: %v", err)
+	}
+
+	if rec := post("promo"); rec.Code != http.StatusNotFound {
+		t.Errorf("recibo desconocido: status = %d; want %d", rec.Code, http.StatusNotFound)
+	}
+	// El ticket de ejemplo no tiene un décimo concepto
+	if rec := post("decimo"); rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), CodeInvalidReceipt) {
+		t.Errorf("recibo que falla: status = %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestCreateReprintRaw(t *testing.T) {
+	q, err := queue.Open(queue.Options{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("queue.Open: %v", err)
+	}
+	defer q.Close()
+	printed, err := journal.Open(journal.Options{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("journal.Open: %v", err)
+	}
+	defer printed.Close()
+	registry, err := printers.New([]printers.Printer{{Name: "mostrador"}, {Name: "cocina"}}, nil, nil)
+	if err != nil {
+		t.Fatalf("printers.New: %v", err)
+	}
+	// Un trabajo raw no tiene ticket: se busca por trabajo y vuelve a su impresora
+	raw := []byte("\x1b@COMANDA 7\n")
+	if err := printed.Record(journal.Entry{JobID: "job-7", Printer: "cocina", Raw: raw}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	srv := NewServer(Options{Queue: q, Journal: printed, Printers: registry})
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/reprints", strings.NewReader(`{"job_id": "job-7"}`)))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d; want %d: %s", rec.Code, http.StatusAccepted, rec.Body)
+	}
+	var resp ReprintResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("respuesta no es JSON: %v", err)
+	}
+	job, err := q.Get(resp.JobID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if job.Kind != queue.KindRaw || string(job.Raw) != string(raw) || job.Printer != "cocina" || resp.Reprint != 1 {
+		t.Errorf("reimpresión = %+v (respuesta %+v)", job, resp)
+	}
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/reprints", strings.NewReader(`{"job_id": "job-8"}`)))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d; want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestCreateReprintCounter(t *testing.T) {
+	q, err := queue.Open(queue.Options{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("queue.Open: %v", err)
+	}
+	defer q.Close()
+	printed, err := journal.Open(journal.Options{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("journal.Open: %v", err)
+	}
+	defer printed.Close()
+	registry, err := printers.New(
+		[]printers.Printer{{Name: "mostrador"}},
+		nil,
+		[]printers.Rule{{Sucursal: "S0001", Printer: "mostrador"}},
+	)
+	if err != nil {
+		t.Fatalf("printers.New: %v", err)
+	}
+	err = printed.Record(journal.Entry{
+		Identificador: "NTQ3",
+		JobID:         "job-1",
+		Ticket:        []byte(`{"data": {"identificador": "NTQ3", "sucursal": "S0001"}}`),
+	})
+	if err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	srv := NewServer(Options{Queue: q, Journal: printed, Printers: registry})
+
+	// Una reimpresión que no se pudo rutear no consume número
+	tests := []struct {
+		body        string
+		wantStatus  int
+		wantReprint int
+	}{
+		{`{"identificador": "NTQ3", "printer": "bodega"}`, http.StatusUnprocessableEntity, 0},
+		{`{"identificador": "NTQ3"}`, http.StatusAccepted, 1},
+		{`{"identificador": "NTQ3", "printer": "bodega"}`, http.StatusUnprocessableEntity, 1},
+		{`{"identificador": "NTQ3"}`, http.StatusAccepted, 2},
+	}
+	for i, tt := range tests {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/reprints", strings.NewReader(tt.body)))
+		if rec.Code != tt.wantStatus {
+			t.Fatalf("%d: status = %d; want %d: %s", i, rec.Code, tt.wantStatus, rec.Body)
+		}
+		entry, err := printed.Find(journal.Query{Identificador: "NTQ3"})
+		if err != nil {
+			t.Fatalf("Find: %v", err)
+		}
+		if entry.Reprints != tt.wantReprint {
+			t.Errorf("%d: reimpresiones = %d; want %d", i, entry.Reprints, tt.wantReprint)
+		}
+		if rec.Code != http.StatusAccepted {
+			continue
+		}
+		var resp ReprintResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("respuesta no es JSON: %v", err)
+		}
+		job, err := q.Get(resp.JobID)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if resp.Reprint != tt.wantReprint || job.Reprint == nil || job.Reprint.Number != tt.wantReprint {
+			t.Errorf("%d: reimpresión = %d (trabajo %+v); want %d", i, resp.Reprint, job.Reprint, tt.wantReprint)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package connector
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	posconnector "github.com/AdConDev/pos-printer/connector"
+	"pos-daemon.adcon.dev/internal/models"
+)
+
+// Type identifica el tipo de conexión con la impresora
+type Type string
+
+const (
+	TypeWindows   Type = "windows"    // Cola de impresión de Windows (spooler)
+	TypeTCP       Type = "tcp"        // Socket TCP, normalmente el puerto raw 9100
+	TypeSerial    Type = "serial"     // Puerto serial (/dev/ttyS0, /dev/ttyUSB0)
+	TypeUSBDevice Type = "usb-device" // Dispositivo de impresora USB (/dev/usb/lp0)
+	TypeFile      Type = "file"       // Archivo donde se agregan los bytes, útil para capturas
+)
+
+// Valores por defecto de Options
+const (
+	DefaultTCPPort  = 9100
+	DefaultTimeout  = 5 * time.Second
+	DefaultBaudRate = 9600
+	DefaultDataBits = 8
+	DefaultStopBits = 1
+	DefaultParity   = "none"
+)
+
+// SerialOptions configura un puerto serial
+type SerialOptions struct {
+	BaudRate int
+	DataBits int
+	StopBits int
+	Parity   string // none, odd o even
+}
+
+// Options describe cómo conectarse con una impresora
+type Options struct {
+	Type    Type
+	Printer string        // Nombre de la impresora en la cola de Windows
+	Address string        // Host o IP (tcp)
+	Port    int           // Puerto TCP
+	Device  string        // Ruta del dispositivo o archivo (serial, usb-device, file)
+	Timeout time.Duration // Tiempo máximo para conectar y para cada escritura (tcp)
+	Serial  SerialOptions
+}
+
+// OptionsFromConfig arma las opciones de conexión de la impresora configurada en
+// Printer/Connector. Sin sección connector se usa la cola de Windows con el
+// nombre de la impresora.
+func OptionsFromConfig(cfg *models.ConfigData) Options {
+	return OptionsFor(cfg, cfg.Printer, cfg.Connector)
+}
+
+// OptionsFor arma las opciones de conexión de la impresora name. Los parámetros
+// del puerto serial son comunes a todas las impresoras.
+func OptionsFor(cfg *models.ConfigData, name string, c models.ConnectorConfig) Options {
+	if c.Printer != "" {
+		name = c.Printer
+	}
+	return Options{
+		Type:    Type(strings.ToLower(c.Type)),
+		Printer: name,
+		Address: c.Address,
+		Port:    c.Port,
+		Device:  c.Device,
+		Timeout: time.Duration(c.TimeoutMs) * time.Millisecond,
+		Serial: SerialOptions{
+			BaudRate: cfg.SerialBaudRate,
+			DataBits: cfg.SerialDataBits,
+			StopBits: cfg.SerialStopBits,
+			Parity:   cfg.SerialParity,
+		},
+	}
+}
+
+// withDefaults completa los valores no indicados
+func (o Options) withDefaults() Options {
+	if o.Type == "" {
+		o.Type = TypeWindows
+	}
+	if o.Port <= 0 {
+		o.Port = DefaultTCPPort
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultTimeout
+	}
+	if o.Serial.BaudRate <= 0 {
+		o.Serial.BaudRate = DefaultBaudRate
+	}
+	if o.Serial.DataBits <= 0 {
+		o.Serial.DataBits = DefaultDataBits
+	}
+	if o.Serial.StopBits <= 0 {
+		o.Serial.StopBits = DefaultStopBits
+	}
+	if o.Serial.Parity == "" {
+		o.Serial.Parity = DefaultParity
+	}
+	return o
+}
+
+// String describe la conexión para los logs
+func (o Options) String() string {
+	o = o.withDefaults()
+	switch o.Type {
+	case TypeWindows:
+		return fmt.Sprintf("%s:%s", o.Type, o.Printer)
+	case TypeTCP:
+		return fmt.Sprintf("%s:%s", o.Type, net.JoinHostPort(o.Address, strconv.Itoa(o.Port)))
+	}
+	return fmt.Sprintf("%s:%s", o.Type, o.Device)
+}
+
+// Bidirectional indica si la conexión permite leer respuestas de la impresora
+// con un tiempo máximo de espera
+func (o Options) Bidirectional() bool {
+	t := o.withDefaults().Type
+	return t == TypeTCP || t == TypeSerial
+}
+
+// Open abre la conexión indicada por opts
+func Open(opts Options) (posconnector.Connector, error) {
+	opts = opts.withDefaults()
+	switch opts.Type {
+	case TypeWindows:
+		if opts.Printer == "" {
+			return nil, fmt.Errorf("connector: falta el nombre de la impresora")
+		}
+		return posconnector.NewWindowsPrintConnector(opts.Printer)
+	case TypeTCP:
+		if opts.Address == "" {
+			return nil, fmt.Errorf("connector: falta la dirección de la impresora")
+		}
+		return dialTCP(net.JoinHostPort(opts.Address, strconv.Itoa(opts.Port)), opts.Timeout)
+	case TypeSerial:
+		if opts.Device == "" {
+			return nil, fmt.Errorf("connector: falta el dispositivo serial")
+		}
+		return openSerial(opts.Device, opts.Serial)
+	case TypeUSBDevice:
+		if opts.Device == "" {
+			return nil, fmt.Errorf("connector: falta el dispositivo USB")
+		}
+		f, err := os.OpenFile(filepath.Clean(opts.Device), os.O_RDWR, 0)
+		if err != nil {
+			return nil, fmt.Errorf("connector: no se pudo abrir %s: %w", opts.Device, err)
+		}
+		return f, nil
+	case TypeFile:
+		if opts.Device == "" {
+			return nil, fmt.Errorf("connector: falta la ruta del archivo")
+		}
+		f, err := os.OpenFile(filepath.Clean(opts.Device), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("connector: no se pudo abrir %s: %w", opts.Device, err)
+		}
+		return f, nil
+	}
+	return nil, fmt.Errorf("connector: tipo de conexión desconocido: %q", opts.Type)
+}
+
+// tcpConn aplica un plazo a cada escritura para no bloquear el worker si la
+// impresora deja de leer
+type tcpConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+// dialTCP conecta con una impresora de red
+func dialTCP(addr string, timeout time.Duration) (*tcpConn, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("connector: no se pudo conectar con %s: %w", addr, err)
+	}
+	return &tcpConn{Conn: conn, timeout: timeout}, nil
+}
+
+// Write implementa io.Writer
+func (c *tcpConn) Write(p []byte) (int, error) {
+	if err := c.SetWriteDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Write(p)
+}
+
+// Read implementa io.Reader con el mismo plazo que las escrituras
+func (c *tcpConn) Read(p []byte) (int, error) {
+	if err := c.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(p)
+}
@@ -0,0 +1,127 @@
+package connector
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"pos-daemon.adcon.dev/internal/models"
+)
+
+func TestOpenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "captura.bin")
+	for i := 0; i < 2; i++ {
+		conn, err := Open(Options{Type: TypeFile, Device: path})
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		if _, err := conn.Write([]byte("\x1b@hola\n")); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if err := conn.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "\x1b@hola\n\x1b@hola\n" {
+		t.Errorf("el archivo contiene %q; se esperaban ambas escrituras agregadas", got)
+	}
+}
+
+func TestOpenTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		data, _ := io.ReadAll(c)
+		received <- data
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	conn, err := Open(Options{Type: TypeTCP, Address: "127.0.0.1", Port: addr.Port})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, err := conn.Write([]byte("ticket")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if got := <-received; string(got) != "ticket" {
+		t.Errorf("la impresora recibió %q", got)
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"Tipo desconocido", Options{Type: "paloma"}},
+		{"TCP sin dirección", Options{Type: TypeTCP}},
+		{"TCP sin impresora", Options{Type: TypeTCP, Address: "127.0.0.1", Port: closedPort(t)}},
+		{"USB sin dispositivo", Options{Type: TypeUSBDevice}},
+		{"USB inexistente", Options{Type: TypeUSBDevice, Device: filepath.Join(t.TempDir(), "lp0")}},
+		{"Serial sin dispositivo", Options{Type: TypeSerial}},
+		{"Archivo sin ruta", Options{Type: TypeFile}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := Open(tt.opts)
+			if err == nil {
+				_ = conn.Close()
+				t.Fatal("se esperaba un error")
+			}
+		})
+	}
+}
+
+func TestOptionsFromConfig(t *testing.T) {
+	cfg := &models.ConfigData{
+		Printer:        "EPSON TM-T20",
+		Connector:      models.ConnectorConfig{Type: "TCP", Address: "10.0.0.5"},
+		SerialBaudRate: 19200,
+	}
+	opts := OptionsFromConfig(cfg).withDefaults()
+	if opts.Type != TypeTCP || opts.Port != DefaultTCPPort || opts.Serial.BaudRate != 19200 || opts.Serial.Parity != DefaultParity {
+		t.Errorf("opciones = %+v", opts)
+	}
+	if got := opts.String(); got != "tcp:10.0.0.5:9100" {
+		t.Errorf("String() = %q", got)
+	}
+
+	// Sin sección connector se mantiene la cola de Windows
+	legacy := OptionsFromConfig(&models.ConfigData{Printer: "58mm GP-58N"}).withDefaults()
+	if legacy.Type != TypeWindows || legacy.Printer != "58mm GP-58N" {
+		t.Errorf("opciones sin connector = %+v", legacy)
+	}
+}
+
+// closedPort devuelve un puerto local en el que nadie escucha
+func closedPort(t *testing.T) int {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	_ = ln.Close()
+	n, _ := strconv.Atoi(port)
+	return n
+}
@@ -0,0 +1,7 @@
+// Package connector construye la conexión con la impresora a partir de la
+// configuración: cola de impresión de Windows, socket TCP (puerto raw 9100),
+// puerto serial, dispositivo USB de impresora (/dev/usb/lp0) o archivo.
+//
+// Todas las conexiones cumplen connector.Connector de pos-printer, por lo que
+// pueden usarse directamente con GenericPrinter.
+package connector
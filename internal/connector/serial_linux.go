@@ -0,0 +1,151 @@
+//go:build linux && (386 || amd64 || arm || arm64 || riscv64)
+
+package connector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// El paquete syscall no exporta las constantes de termios en todas las
+// arquitecturas; estos valores son los de <asm-generic/termbits.h>.
+const (
+	tcgets = 0x5401
+	tcsets = 0x5402
+
+	// c_iflag
+	ignbrk = 0x1
+	brkint = 0x2
+	parmrk = 0x8
+	inpck  = 0x10
+	istrip = 0x20
+	inlcr  = 0x40
+	igncr  = 0x80
+	icrnl  = 0x100
+	ixon   = 0x400
+	ixoff  = 0x1000
+
+	// c_oflag
+	opost = 0x1
+
+	// c_cflag
+	cbaud  = 0x100f
+	csize  = 0x30
+	cs5    = 0x0
+	cs6    = 0x10
+	cs7    = 0x20
+	cs8    = 0x30
+	cstopb = 0x40
+	cread  = 0x80
+	parenb = 0x100
+	parodd = 0x200
+	clocal = 0x800
+
+	// c_lflag
+	isig   = 0x1
+	icanon = 0x2
+	echo   = 0x8
+	echonl = 0x40
+	iexten = 0x8000
+
+	// c_cc
+	vtime = 5
+	vmin  = 6
+)
+
+// baudRates relaciona la velocidad en baudios con su constante Bnnn
+var baudRates = map[int]uint32{
+	1200:   0x9,
+	2400:   0xb,
+	4800:   0xc,
+	9600:   0xd,
+	19200:  0xe,
+	38400:  0xf,
+	57600:  0x1001,
+	115200: 0x1002,
+	230400: 0x1003,
+}
+
+// dataBits relaciona los bits de datos con su constante CSn
+var dataBits = map[int]uint32{5: cs5, 6: cs6, 7: cs7, 8: cs8}
+
+// openSerial abre el puerto en modo raw con la velocidad, bits y paridad indicados
+func openSerial(device string, opts SerialOptions) (*os.File, error) {
+	f, err := os.OpenFile(filepath.Clean(device), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("connector: no se pudo abrir %s: %w", device, err)
+	}
+
+	var t syscall.Termios
+	if err := ioctl(f, tcgets, &t); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("connector: %s no es un puerto serial: %w", device, err)
+	}
+	if err := configureTermios(&t, opts); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if err := ioctl(f, tcsets, &t); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("connector: no se pudo configurar %s: %w", device, err)
+	}
+	return f, nil
+}
+
+// configureTermios pone el puerto en modo raw y aplica opts
+func configureTermios(t *syscall.Termios, opts SerialOptions) error {
+	speed, ok := baudRates[opts.BaudRate]
+	if !ok {
+		return fmt.Errorf("connector: velocidad serial no soportada: %d", opts.BaudRate)
+	}
+	size, ok := dataBits[opts.DataBits]
+	if !ok {
+		return fmt.Errorf("connector: bits de datos no soportados: %d", opts.DataBits)
+	}
+
+	t.Iflag &^= ignbrk | brkint | parmrk | istrip | inlcr | igncr | icrnl | ixon | ixoff | inpck
+	t.Oflag &^= opost
+	t.Lflag &^= echo | echonl | icanon | isig | iexten
+	t.Cflag &^= cbaud | csize | cstopb | parenb | parodd
+	t.Cflag |= cread | clocal | speed | size
+
+	switch opts.StopBits {
+	case 1:
+	case 2:
+		t.Cflag |= cstopb
+	default:
+		return fmt.Errorf("connector: bits de parada no soportados: %d", opts.StopBits)
+	}
+
+	switch opts.Parity {
+	case "none":
+	case "even":
+		t.Cflag |= parenb
+		t.Iflag |= inpck
+	case "odd":
+		t.Cflag |= parenb | parodd
+		t.Iflag |= inpck
+	default:
+		return fmt.Errorf("connector: paridad no soportada: %q", opts.Parity)
+	}
+
+	t.Ispeed = speed
+	t.Ospeed = speed
+
+	// Las lecturas de estado esperan hasta 1 s por la respuesta
+	t.Cc[vmin] = 0
+	t.Cc[vtime] = 10
+	return nil
+}
+
+// ioctl lee o escribe la configuración termios del puerto
+func ioctl(f *os.File, req uintptr, t *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), req, uintptr(unsafe.Pointer(t))) // #nosec G103
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
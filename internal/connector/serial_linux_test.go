@@ -0,0 +1,42 @@
+//go:build linux && (386 || amd64 || arm || arm64 || riscv64)
+
+package connector
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestConfigureTermios(t *testing.T) {
+	var term syscall.Termios
+	term.Lflag = icanon | echo
+	term.Cflag = cbaud // Velocidad previa que debe reemplazarse
+
+	err := configureTermios(&term, SerialOptions{BaudRate: 115200, DataBits: 7, StopBits: 2, Parity: "odd"})
+	if err != nil {
+		t.Fatalf("configureTermios: %v", err)
+	}
+	if term.Cflag&cbaud != baudRates[115200] || term.Ospeed != baudRates[115200] {
+		t.Errorf("velocidad = %#x", term.Cflag&cbaud)
+	}
+	if term.Cflag&csize != cs7 {
+		t.Errorf("bits de datos = %#x; want CS7", term.Cflag&csize)
+	}
+	if term.Cflag&cstopb == 0 || term.Cflag&(parenb|parodd) != parenb|parodd {
+		t.Errorf("paridad o bits de parada incorrectos: cflag = %#x", term.Cflag)
+	}
+	if term.Lflag&(icanon|echo) != 0 {
+		t.Errorf("el puerto no quedó en modo raw: lflag = %#x", term.Lflag)
+	}
+
+	for _, bad := range []SerialOptions{
+		{BaudRate: 12345, DataBits: 8, StopBits: 1, Parity: "none"},
+		{BaudRate: 9600, DataBits: 9, StopBits: 1, Parity: "none"},
+		{BaudRate: 9600, DataBits: 8, StopBits: 3, Parity: "none"},
+		{BaudRate: 9600, DataBits: 8, StopBits: 1, Parity: "mark"},
+	} {
+		if err := configureTermios(&term, bad); err == nil {
+			t.Errorf("configureTermios(%+v) no devolvió error", bad)
+		}
+	}
+}
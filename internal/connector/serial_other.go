@@ -0,0 +1,15 @@
+//go:build !(linux && (386 || amd64 || arm || arm64 || riscv64))
+
+package connector
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// openSerial no está disponible fuera de Linux; en Windows use el tipo windows
+// con una impresora instalada sobre el puerto COM
+func openSerial(device string, _ SerialOptions) (*os.File, error) {
+	return nil, fmt.Errorf("connector: el puerto serial %s no está soportado en %s/%s", device, runtime.GOOS, runtime.GOARCH)
+}
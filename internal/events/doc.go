@@ -0,0 +1,3 @@
+// Package events distribuye a los suscriptores los cambios de estado de los
+// trabajos de impresión y de las impresoras.
+package events
@@ -0,0 +1,99 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"pos-daemon.adcon.dev/internal/queue"
+)
+
+// Tipos de evento
+const (
+	TypeJob     = "job"     // Transición de estado de un trabajo
+	TypePrinter = "printer" // Cambio de estado de una impresora
+)
+
+// Event es un cambio publicado a los suscriptores
+type Event struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+
+	// Campos de eventos de trabajo
+	JobID         string      `json:"job_id,omitempty"`
+	State         queue.State `json:"state,omitempty"`
+	Attempts      int         `json:"attempts,omitempty"`
+	Identificador string      `json:"identificador,omitempty"`
+	Serie         string      `json:"serie,omitempty"`
+	Folio         string      `json:"folio,omitempty"`
+
+	// Campos de eventos de impresora. Printer también indica la impresora
+	// asignada en los eventos de trabajo.
+	Printer string `json:"printer,omitempty"`
+	Status  string `json:"status,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// FromJob construye el evento de transición de un trabajo
+func FromJob(job *queue.Job) Event {
+	return Event{
+		Type:          TypeJob,
+		Time:          job.UpdatedAt,
+		JobID:         job.ID,
+		State:         job.State,
+		Printer:       job.Printer,
+		Attempts:      job.Attempts,
+		Identificador: job.Identificador,
+		Serie:         job.Serie,
+		Folio:         job.Folio,
+		Error:         job.LastError,
+	}
+}
+
+// subscriberBuffer es la cantidad de eventos que un suscriptor lento puede acumular
+const subscriberBuffer = 64
+
+// Hub reparte eventos a todos los suscriptores activos.
+// Un suscriptor que no consume a tiempo pierde eventos en lugar de bloquear al resto.
+type Hub struct {
+	mu   sync.Mutex
+	subs map[chan Event]struct{}
+}
+
+// NewHub crea un Hub vacío
+func NewHub() *Hub {
+	return &Hub{subs: make(map[chan Event]struct{})}
+}
+
+// Subscribe registra un suscriptor nuevo. La función devuelta lo da de baja.
+func (h *Hub) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs, ch)
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish envía el evento a todos los suscriptores
+func (h *Hub) Publish(ev Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
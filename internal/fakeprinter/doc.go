@@ -0,0 +1,6 @@
+// Package fakeprinter emula una impresora ESC/POS de red para pruebas locales.
+// Acepta conexiones TCP como el puerto raw 9100, responde las consultas de
+// estado DLE EOT y GS a, guarda en disco cada trabajo recibido y puede simular
+// fallas: fin de papel tras N líneas, tapa abierta, conexión cortada a mitad de
+// un trabajo y respuestas lentas.
+package fakeprinter
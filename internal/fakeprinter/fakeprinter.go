@@ -0,0 +1,386 @@
+package fakeprinter
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"pos-daemon.adcon.dev/internal/receipt"
+	"pos-daemon.adcon.dev/internal/status"
+)
+
+// nearEndLines es la cantidad de líneas restantes a partir de la cual el
+// sensor informa que el papel está por terminarse
+const nearEndLines = 10
+
+// Faults son las fallas que simula la impresora
+type Faults struct {
+	PaperOutAfterLines int           // Se termina el papel tras imprimir N líneas (0 = nunca)
+	CoverOpen          bool          // La tapa está abierta y no se imprime
+	DropAfterBytes     int           // Corta cada conexión al recibir N bytes (0 = nunca)
+	AckDelay           time.Duration // Retraso antes de responder cada consulta y de leer cada bloque
+}
+
+// Job es un trabajo recibido
+type Job struct {
+	Path    string // Archivo donde se guardó ("" si Dir está vacío)
+	Data    []byte // Bytes ESC/POS recibidos, sin las consultas de estado
+	Dropped bool   // La conexión se cortó a propósito antes de terminar
+}
+
+// Options configura la impresora emulada
+type Options struct {
+	Dir    string // Directorio donde se guarda cada trabajo (vacío = solo en memoria)
+	Faults Faults // Fallas iniciales
+}
+
+// Printer es una impresora ESC/POS de red emulada
+type Printer struct {
+	opts Options
+
+	mu       sync.Mutex
+	ready    *sync.Cond // Se señala cuando cambia la tapa o el papel
+	faults   Faults
+	printed  int // Líneas impresas desde la última carga de papel
+	paperOut bool
+	jobs     []Job
+	closed   bool
+
+	ln    net.Listener
+	conns map[net.Conn]struct{}
+	wg    sync.WaitGroup
+}
+
+// New crea una impresora emulada; Serve o Start la ponen a escuchar
+func New(opts Options) *Printer {
+	p := &Printer{opts: opts, faults: opts.Faults, conns: make(map[net.Conn]struct{})}
+	p.ready = sync.NewCond(&p.mu)
+	return p
+}
+
+// Start escucha en addr (por ejemplo "127.0.0.1:0") y atiende conexiones en
+// segundo plano hasta Close
+func Start(addr string, opts Options) (*Printer, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("fakeprinter: %w", err)
+	}
+	p := New(opts)
+	p.ln = ln // Addr responde desde el regreso de Start
+	go func() {
+		if err := p.Serve(ln); err != nil {
+			log.Printf("fakeprinter: %v", err)
+		}
+	}()
+	return p, nil
+}
+
+// Serve atiende conexiones de ln hasta Close
+func (p *Printer) Serve(ln net.Listener) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ln.Close()
+	}
+	p.ln = ln
+	p.mu.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			p.mu.Lock()
+			closed := p.closed
+			p.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			_ = conn.Close()
+			return nil
+		}
+		p.conns[conn] = struct{}{}
+		p.wg.Add(1)
+		p.mu.Unlock()
+		go p.handle(conn)
+	}
+}
+
+// Addr devuelve la dirección en la que escucha, o nil si aún no escucha
+func (p *Printer) Addr() net.Addr {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ln == nil {
+		return nil
+	}
+	return p.ln.Addr()
+}
+
+// Close deja de escuchar, corta las conexiones abiertas y espera a que se
+// guarden sus trabajos
+func (p *Printer) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	var err error
+	if p.ln != nil {
+		err = p.ln.Close()
+	}
+	for conn := range p.conns {
+		_ = conn.Close()
+	}
+	p.ready.Broadcast()
+	p.mu.Unlock()
+
+	p.wg.Wait()
+	return err
+}
+
+// SetFaults reemplaza las fallas simuladas. Cerrar la tapa reanuda la impresión.
+func (p *Printer) SetFaults(f Faults) {
+	p.mu.Lock()
+	p.faults = f
+	p.ready.Broadcast()
+	p.mu.Unlock()
+}
+
+// Faults devuelve las fallas simuladas actuales
+func (p *Printer) Faults() Faults {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.faults
+}
+
+// Refill carga papel nuevo y reanuda la impresión
+func (p *Printer) Refill() {
+	p.mu.Lock()
+	p.printed = 0
+	p.paperOut = false
+	p.ready.Broadcast()
+	p.mu.Unlock()
+}
+
+// Jobs devuelve los trabajos recibidos hasta ahora
+func (p *Printer) Jobs() []Job {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]Job, len(p.jobs))
+	copy(out, p.jobs)
+	return out
+}
+
+// handle atiende una conexión. Cada conexión con datos es un trabajo.
+func (p *Printer) handle(conn net.Conn) {
+	defer p.wg.Done()
+	defer func() {
+		p.mu.Lock()
+		delete(p.conns, conn)
+		p.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	var (
+		data     bytes.Buffer // Datos del trabajo
+		pending  []byte       // Comando de estado partido entre lecturas
+		stream   = receipt.NewStream()
+		lines    int // Líneas del trabajo interpretadas hasta ahora
+		received int
+		dropped  bool
+		buf      = make([]byte, 4096)
+	)
+	for {
+		if data.Len() > 0 {
+			p.waitReady()
+		}
+		n, err := conn.Read(buf)
+		if n > 0 {
+			p.delay()
+			chunk := append(pending, buf[:n]...)
+			pending = nil
+			if data.Len() == 0 {
+				// Las consultas de estado solo se atienden antes del trabajo y
+				// se responden aunque la impresora no esté lista
+				var replies []byte
+				chunk, replies, pending = p.split(chunk)
+				if len(replies) > 0 {
+					if _, err := conn.Write(replies); err != nil {
+						break
+					}
+				}
+				if len(chunk) > 0 {
+					p.waitReady()
+				}
+			}
+			data.Write(chunk)
+			lines += countLines(stream.Write(chunk))
+			p.feed(lines)
+
+			received += n
+			if drop := p.Faults().DropAfterBytes; drop > 0 && received >= drop {
+				dropped = true
+				if tcp, ok := conn.(*net.TCPConn); ok {
+					// Cortar con RST, como una impresora que se reinicia
+					_ = tcp.SetLinger(0)
+				}
+				break
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	data.Write(pending)
+	if data.Len() > 0 {
+		stream.Write(pending)
+		p.store(data.Bytes(), countLines(stream.Close().Blocks), dropped)
+	}
+}
+
+// waitReady bloquea los datos del trabajo mientras la tapa está abierta o no
+// hay papel, igual que una impresora que deja de aceptar datos
+func (p *Printer) waitReady() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for !p.closed && (p.faults.CoverOpen || p.paperOut) {
+		p.ready.Wait()
+	}
+}
+
+// delay aplica el retraso configurado
+func (p *Printer) delay() {
+	if d := p.Faults().AckDelay; d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// split responde las consultas de estado al inicio de una conexión. Devuelve
+// las respuestas, los datos del trabajo desde el primer byte que no es una
+// consulta, y el final de chunk si es una consulta incompleta. Solo se
+// interpretan antes del trabajo para no confundirlas con bytes de una imagen.
+func (p *Printer) split(chunk []byte) (job, replies, rest []byte) {
+	for i := 0; i < len(chunk); i += 3 {
+		if len(chunk)-i < 3 {
+			if isPrefix(chunk[i:]) {
+				return nil, replies, chunk[i:]
+			}
+			return chunk[i:], replies, nil
+		}
+		switch {
+		case chunk[i] == 0x10 && chunk[i+1] == 0x04:
+			replies = append(replies, p.eot(chunk[i+2]))
+		case chunk[i] == 0x1D && chunk[i+1] == 0x61:
+			// GS a n habilita ASB y la impresora responde el estado actual;
+			// GS a 0 lo deshabilita sin responder
+			if chunk[i+2] != 0 {
+				replies = append(replies, p.asb()...)
+			}
+		default:
+			return chunk[i:], replies, nil
+		}
+	}
+	return nil, replies, nil
+}
+
+// isPrefix indica si b puede ser el inicio de DLE EOT n o GS a n
+func isPrefix(b []byte) bool {
+	switch len(b) {
+	case 1:
+		return b[0] == 0x10 || b[0] == 0x1D
+	case 2:
+		return (b[0] == 0x10 && b[1] == 0x04) || (b[0] == 0x1D && b[1] == 0x61)
+	}
+	return false
+}
+
+// feed termina el papel si las lines líneas del trabajo en curso alcanzan el
+// límite
+func (p *Printer) feed(lines int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	limit := p.faults.PaperOutAfterLines
+	if limit <= 0 || p.paperOut {
+		return
+	}
+	if p.printed+lines >= limit {
+		p.paperOut = true
+	}
+}
+
+// countLines cuenta las líneas de texto y de avance de los bloques
+func countLines(blocks []receipt.Block) int {
+	lines := 0
+	for _, b := range blocks {
+		switch b := b.(type) {
+		case *receipt.Line:
+			lines++
+		case *receipt.Feed:
+			lines += b.Lines
+		}
+	}
+	return lines
+}
+
+// store guarda un trabajo terminado de lines líneas
+func (p *Printer) store(data []byte, lines int, dropped bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.paperOut {
+		p.printed += lines
+	}
+	job := Job{Data: append([]byte(nil), data...), Dropped: dropped}
+	if p.opts.Dir != "" {
+		job.Path = filepath.Join(p.opts.Dir, fmt.Sprintf("job-%04d.bin", len(p.jobs)+1))
+		if err := os.WriteFile(job.Path, job.Data, 0o600); err != nil {
+			log.Printf("fakeprinter: no se pudo guardar el trabajo: %v", err)
+			job.Path = ""
+		}
+	}
+	p.jobs = append(p.jobs, job)
+	if dropped {
+		log.Printf("fakeprinter: trabajo %d cortado tras %d bytes", len(p.jobs), len(data))
+	} else {
+		log.Printf("fakeprinter: trabajo %d recibido (%d bytes)", len(p.jobs), len(data))
+	}
+}
+
+// eot responde a DLE EOT n según el estado actual
+func (p *Printer) eot(n byte) byte {
+	p.delay()
+	return status.EncodeEOT(p.sensors(), n)
+}
+
+// asb arma los cuatro bytes de Automatic Status Back
+func (p *Printer) asb() []byte {
+	p.delay()
+	b := status.EncodeASB(p.sensors())
+	return b[:]
+}
+
+// sensors devuelve el estado que informan los sensores
+func (p *Printer) sensors() status.PrinterStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return status.PrinterStatus{
+		Online:       !p.faults.CoverOpen && !p.paperOut,
+		CoverOpen:    p.faults.CoverOpen,
+		PaperNearEnd: p.nearEnd(),
+		PaperEnd:     p.paperOut,
+	}
+}
+
+// nearEnd indica si quedan pocas líneas de papel. Requiere p.mu.
+func (p *Printer) nearEnd() bool {
+	limit := p.faults.PaperOutAfterLines
+	return limit > 0 && !p.paperOut && limit-p.printed <= nearEndLines
+}
@@ -0,0 +1,198 @@
+package fakeprinter
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+// start levanta una impresora emulada que se cierra al terminar la prueba
+func start(t *testing.T, opts Options) *Printer {
+	t.Helper()
+	p, err := Start("127.0.0.1:0", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func dial(t *testing.T, p *Printer) net.Conn {
+	t.Helper()
+	conn, err := net.Dial("tcp", p.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+// query envía DLE EOT n y devuelve la respuesta
+func query(t *testing.T, conn net.Conn, n byte) byte {
+	t.Helper()
+	if _, err := conn.Write([]byte{0x10, 0x04, n}); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 1)
+	if _, err := io.ReadFull(conn, b); err != nil {
+		t.Fatalf("DLE EOT %d: %v", n, err)
+	}
+	return b[0]
+}
+
+// waitJobs espera hasta que la impresora haya guardado n trabajos
+func waitJobs(t *testing.T, p *Printer, n int) []Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		jobs := p.Jobs()
+		if len(jobs) >= n {
+			return jobs
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("se recibieron %d trabajos; want %d", len(jobs), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// lines arma un trabajo con n líneas de texto y un corte
+func lines(n int) []byte {
+	job := []byte{0x1B, 0x40}
+	for range n {
+		job = append(job, "LINEA\n"...)
+	}
+	return append(job, 0x1D, 0x56, 0x00)
+}
+
+func TestStoresJob(t *testing.T) {
+	dir := t.TempDir()
+	p := start(t, Options{Dir: dir})
+
+	conn := dial(t, p)
+	if got := query(t, conn, 1); got != 0x12 {
+		t.Errorf("DLE EOT 1 = %#x; want 0x12", got)
+	}
+	job := lines(3)
+	if _, err := conn.Write(job); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	jobs := waitJobs(t, p, 1)
+	if !bytes.Equal(jobs[0].Data, job) || jobs[0].Dropped {
+		t.Errorf("trabajo = %+v", jobs[0])
+	}
+	data, err := os.ReadFile(jobs[0].Path)
+	if err != nil {
+		t.Fatalf("no se guardó el trabajo: %v", err)
+	}
+	if !bytes.Equal(data, job) {
+		t.Errorf("archivo = %q; want %q", data, job)
+	}
+}
+
+func TestCoverOpen(t *testing.T) {
+	p := start(t, Options{Faults: Faults{CoverOpen: true}})
+
+	conn := dial(t, p)
+	if got := query(t, conn, 1); got&0x08 == 0 {
+		t.Errorf("DLE EOT 1 = %#x; want fuera de línea", got)
+	}
+	if got := query(t, conn, 2); got&0x04 == 0 {
+		t.Errorf("DLE EOT 2 = %#x; want tapa abierta", got)
+	}
+	if _, err := conn.Write(lines(1)); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	time.Sleep(50 * time.Millisecond)
+	if n := len(p.Jobs()); n != 0 {
+		t.Fatalf("se imprimieron %d trabajos con la tapa abierta", n)
+	}
+	p.SetFaults(Faults{})
+	waitJobs(t, p, 1)
+}
+
+func TestPaperOut(t *testing.T) {
+	p := start(t, Options{Faults: Faults{PaperOutAfterLines: 8}})
+
+	conn := dial(t, p)
+	if got := query(t, conn, 4); got&0x0C == 0 {
+		t.Errorf("DLE EOT 4 = %#x; want papel por terminarse", got)
+	}
+	if _, err := conn.Write(lines(20)); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	// El papel se termina a mitad del trabajo y la impresora deja de aceptarlo
+	conn = dial(t, p)
+	deadline := time.Now().Add(5 * time.Second)
+	for query(t, conn, 4)&0x60 == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("DLE EOT 4 no informó sin papel")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := query(t, conn, 2); got&0x20 == 0 {
+		t.Errorf("DLE EOT 2 = %#x; want sin papel", got)
+	}
+	if n := len(p.Jobs()); n != 0 {
+		t.Fatalf("se terminaron %d trabajos sin papel", n)
+	}
+
+	p.Refill()
+	waitJobs(t, p, 1)
+	if got := query(t, conn, 1); got != 0x12 {
+		t.Errorf("DLE EOT 1 = %#x tras cargar papel; want 0x12", got)
+	}
+}
+
+func TestASB(t *testing.T) {
+	p := start(t, Options{Faults: Faults{CoverOpen: true}})
+
+	conn := dial(t, p)
+	if _, err := conn.Write([]byte{0x1D, 0x61, 0x0F}); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 4)
+	if _, err := io.ReadFull(conn, b); err != nil {
+		t.Fatal(err)
+	}
+	if b[0] != 0x38 {
+		t.Errorf("ASB = % x; want tapa abierta y fuera de línea", b)
+	}
+}
+
+func TestDropAfterBytes(t *testing.T) {
+	p := start(t, Options{Faults: Faults{DropAfterBytes: 4}})
+
+	conn := dial(t, p)
+	if _, err := conn.Write(lines(2)); err != nil {
+		t.Fatal(err)
+	}
+	jobs := waitJobs(t, p, 1)
+	if !jobs[0].Dropped {
+		t.Errorf("trabajo = %+v; want cortado", jobs[0])
+	}
+	if _, err := io.ReadAll(conn); err == nil {
+		t.Error("la conexión no se cortó con error")
+	}
+}
+
+func TestAckDelay(t *testing.T) {
+	p := start(t, Options{Faults: Faults{AckDelay: 100 * time.Millisecond}})
+
+	conn := dial(t, p)
+	begin := time.Now()
+	query(t, conn, 1)
+	if d := time.Since(begin); d < 100*time.Millisecond {
+		t.Errorf("respondió en %v; want al menos 100ms", d)
+	}
+}
@@ -0,0 +1,5 @@
+// Package hotfolder imprime los tickets que otro sistema deja como archivos
+// JSON en un directorio vigilado. Cada archivo se encola como un ticket y se
+// mueve a processed/ o, si no se pudo encolar, a failed/ junto con un archivo
+// .error que explica el motivo.
+package hotfolder
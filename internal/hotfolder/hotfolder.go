@@ -0,0 +1,363 @@
+package hotfolder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"pos-daemon.adcon.dev/internal/models"
+	"pos-daemon.adcon.dev/internal/printers"
+	"pos-daemon.adcon.dev/internal/queue"
+	"pos-daemon.adcon.dev/internal/service"
+)
+
+// Valores por defecto de Options
+const (
+	DefaultPoll         = time.Second
+	DefaultStableFor    = time.Second
+	DefaultMaxFileBytes = 1 << 20
+)
+
+// Subdirectorios a los que se mueven los archivos procesados
+const (
+	ProcessedDir = "processed"
+	FailedDir    = "failed"
+)
+
+// Options configura un directorio vigilado
+type Options struct {
+	Dir   string       // Directorio donde se dejan los tickets
+	Queue *queue.Queue // Cola donde se encolan los tickets
+
+	// Printers elige la impresora de cada ticket; Printer la fija para todos.
+	// Si Printers es nil los tickets se encolan sin impresora.
+	Printers *printers.Registry
+	Printer  string
+
+	// Plantilla de los tickets: Template, la de la impresora elegida o
+	// DefaultTemplate, en ese orden, leída de TemplatesDir
+	TemplatesDir    string
+	Template        string
+	DefaultTemplate string
+
+	// Validation decide qué pasa con los tickets que no pasan la validación;
+	// vacío es service.ValidationWarn. Los rechazados se mueven a failed/.
+	Validation service.ValidationMode
+
+	Poll time.Duration // Cada cuánto se revisa el directorio
+	// StableFor es el tiempo que un archivo debe mantener su tamaño y fecha de
+	// modificación antes de leerse, para no tomar archivos a medio escribir
+	StableFor    time.Duration
+	MaxFileBytes int64 // Tamaño máximo de un ticket
+}
+
+// Spooler vigila un directorio y encola los tickets que aparecen en él
+type Spooler struct {
+	opts Options
+	seen map[string]fileState
+	now  func() time.Time
+}
+
+// fileState es el tamaño y la fecha de un archivo la primera vez que se vieron
+// sin cambios
+type fileState struct {
+	size  int64
+	mod   time.Time
+	since time.Time
+}
+
+// New prepara el directorio vigilado y sus subdirectorios processed/ y failed/
+func New(opts Options) (*Spooler, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("hotfolder: no se indicó el directorio")
+	}
+	if opts.Queue == nil {
+		return nil, fmt.Errorf("hotfolder: no se indicó la cola de trabajos")
+	}
+	if opts.Printers != nil && opts.Printer != "" {
+		if _, err := opts.Printers.Resolve(opts.Printer); err != nil {
+			return nil, fmt.Errorf("hotfolder: %w", err)
+		}
+	}
+	if opts.Poll <= 0 {
+		opts.Poll = DefaultPoll
+	}
+	if opts.StableFor <= 0 {
+		opts.StableFor = DefaultStableFor
+	}
+	if opts.MaxFileBytes <= 0 {
+		opts.MaxFileBytes = DefaultMaxFileBytes
+	}
+	if opts.Validation == "" {
+		opts.Validation = service.ValidationWarn
+	}
+	for _, dir := range []string{opts.Dir, filepath.Join(opts.Dir, ProcessedDir), filepath.Join(opts.Dir, FailedDir)} {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return nil, fmt.Errorf("hotfolder: %w", err)
+		}
+	}
+	return &Spooler{opts: opts, seen: make(map[string]fileState), now: time.Now}, nil
+}
+
+// Dir devuelve el directorio vigilado
+func (s *Spooler) Dir() string {
+	return s.opts.Dir
+}
+
+// Run revisa el directorio cada Poll hasta que ctx se cancele
+func (s *Spooler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.Poll)
+	defer ticker.Stop()
+	for {
+		s.scan()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan procesa los archivos que ya terminaron de escribirse
+func (s *Spooler) scan() {
+	entries, err := os.ReadDir(s.opts.Dir)
+	if err != nil {
+		log.Printf("hotfolder: error al leer %s: %v", s.opts.Dir, err)
+		return
+	}
+	now := s.now()
+	present := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		name := e.Name()
+		// Los programas que escriben con nombre temporal y renombran al
+		// terminar usan nombres ocultos o con otra extensión
+		if strings.HasPrefix(name, ".") || !strings.EqualFold(filepath.Ext(name), ".json") {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(s.opts.Dir, name))
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		present[name] = true
+
+		prev, ok := s.seen[name]
+		if !ok || prev.size != info.Size() || !prev.mod.Equal(info.ModTime()) {
+			s.seen[name] = fileState{size: info.Size(), mod: info.ModTime(), since: now}
+			continue
+		}
+		if now.Sub(prev.since) < s.opts.StableFor {
+			continue
+		}
+		if s.process(name) {
+			delete(s.seen, name)
+		}
+	}
+	for name := range s.seen {
+		if !present[name] {
+			delete(s.seen, name)
+		}
+	}
+}
+
+// process encola un archivo y lo mueve según el resultado. Devuelve false si
+// el archivo se deja en su lugar para reintentarlo en la siguiente revisión.
+func (s *Spooler) process(name string) bool {
+	job, duplicate, err := s.enqueue(name)
+	if err != nil {
+		var retry *retryError
+		if errors.As(err, &retry) {
+			log.Printf("hotfolder: no se pudo encolar %s, se reintentará: %v", name, err)
+			return false
+		}
+		log.Printf("hotfolder: %s no se puede imprimir: %v", name, err)
+		s.fail(name, err)
+		return true
+	}
+
+	if duplicate {
+		log.Printf("hotfolder: %s es un ticket duplicado, se conserva el trabajo %s", name, job.ID)
+	} else {
+		log.Printf("Ticket %s encolado como trabajo %s para %s", name, job.ID, job.Printer)
+	}
+	if _, err := s.move(name, ProcessedDir); err != nil {
+		log.Printf("hotfolder: no se pudo mover %s a %s: %v", name, ProcessedDir, err)
+	}
+	return true
+}
+
+// retryError marca los errores que no dependen del archivo, como una cola
+// que no se pudo escribir
+type retryError struct{ err error }
+
+func (e *retryError) Error() string { return e.err.Error() }
+func (e *retryError) Unwrap() error { return e.err }
+
+// enqueue lee, valida y encola un ticket. Los reenvíos del mismo ticket dentro
+// de la ventana de deduplicación devuelven el trabajo original.
+func (s *Spooler) enqueue(name string) (*queue.Job, bool, error) {
+	data, err := s.read(name)
+	if err != nil {
+		return nil, false, err
+	}
+	ticket, err := parseTicket(data)
+	if err != nil {
+		return nil, false, err
+	}
+	findings := service.ValidateTicket(&ticket.Data)
+	if len(findings) > 0 {
+		log.Printf("hotfolder: observaciones de %s: %s", name, strings.Join(findings.Messages(), "; "))
+	}
+	if s.opts.Validation.Rejects(findings) {
+		return nil, false, fmt.Errorf("el ticket no pasó la validación:\n%s", strings.Join(findings.Messages(), "\n"))
+	}
+
+	var dest *printers.Destination
+	if s.opts.Printers != nil {
+		target := printers.TargetFromTicket(ticket)
+		if s.opts.Printer != "" {
+			target.Printer = s.opts.Printer
+		}
+		if dest, err = s.opts.Printers.Route(target); err != nil {
+			return nil, false, err
+		}
+	}
+
+	templateData, err := s.loadTemplate(dest)
+	if err != nil {
+		return nil, false, err
+	}
+
+	req := queue.Request{
+		Ticket:   data,
+		Template: templateData,
+		TicketRef: queue.TicketRef{
+			Identificador: ticket.Data.Identificador,
+			Serie:         ticket.Data.Serie,
+			Folio:         ticket.Data.Folio,
+		},
+		Warnings: s.opts.Validation.Banner(findings),
+	}
+	if dest != nil {
+		req.Printer = dest.Name
+	}
+	job, duplicate, err := s.opts.Queue.EnqueueOnce(queue.IdempotencyKey("", req.TicketRef), req)
+	if err != nil {
+		return nil, false, &retryError{err: err}
+	}
+	return job, duplicate, nil
+}
+
+// read lee un archivo del directorio vigilado. Un enlace simbólico que apunte
+// fuera del directorio se rechaza.
+func (s *Spooler) read(name string) ([]byte, error) {
+	path := filepath.Join(s.opts.Dir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > s.opts.MaxFileBytes {
+		return nil, fmt.Errorf("el archivo supera %d bytes", s.opts.MaxFileBytes)
+	}
+	return models.ReadJSONFile(s.opts.Dir, path)
+}
+
+// parseTicket interpreta un models.NewTicket o un models.Ticket. Ambos se
+// imprimen igual; el formato anterior solo carece de los datos de ruteo.
+func parseTicket(data []byte) (*models.NewTicket, error) {
+	var probe struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("JSON inválido: %w", err)
+	}
+	if probe.Data == nil {
+		return nil, fmt.Errorf("el archivo no tiene el objeto \"data\" de un ticket")
+	}
+
+	var ticket models.NewTicket
+	if isNewTicket(probe.Data) {
+		if err := json.Unmarshal(data, &ticket); err != nil {
+			return nil, fmt.Errorf("ticket inválido: %w", err)
+		}
+	} else {
+		var old models.Ticket
+		if err := json.Unmarshal(data, &old); err != nil {
+			return nil, fmt.Errorf("ticket inválido: %w", err)
+		}
+		ticket.Data.TicketData = old.Data
+	}
+	if ticket.Data.Identificador == "" {
+		return nil, fmt.Errorf("el ticket no tiene identificador")
+	}
+	return &ticket, nil
+}
+
+// isNewTicket indica si data tiene campos que solo existen en models.NewTicket
+func isNewTicket(data map[string]json.RawMessage) bool {
+	for _, key := range []string{"sucursal", "serie_identificador", "cliente_identificador", "vendedor_identificador"} {
+		if _, ok := data[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// loadTemplate lee y valida la plantilla del ticket
+func (s *Spooler) loadTemplate(dest *printers.Destination) ([]byte, error) {
+	name := s.opts.Template
+	if name == "" && dest != nil {
+		name = dest.Primary().DefaultTemplate
+	}
+	if name == "" {
+		name = s.opts.DefaultTemplate
+	}
+	if name == "" {
+		return nil, fmt.Errorf("no se indicó plantilla y no hay plantilla por defecto")
+	}
+	if !strings.HasSuffix(strings.ToLower(name), ".json") {
+		name += ".json"
+	}
+	data, err := models.ReadJSONFile(s.opts.TemplatesDir, filepath.Join(s.opts.TemplatesDir, name))
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo cargar la plantilla %q: %w", name, err)
+	}
+	check := service.NewTicketConstructor(io.Discard, nil)
+	if err := check.LoadTemplateFromJSON(data); err != nil {
+		return nil, fmt.Errorf("plantilla %q: %w", name, err)
+	}
+	return data, nil
+}
+
+// fail mueve el archivo a failed/ y escribe el motivo junto a él
+func (s *Spooler) fail(name string, cause error) {
+	moved, err := s.move(name, FailedDir)
+	if err != nil {
+		log.Printf("hotfolder: no se pudo mover %s a %s: %v", name, FailedDir, err)
+		return
+	}
+	if err := os.WriteFile(moved+".error", []byte(cause.Error()+"\n"), 0o600); err != nil {
+		log.Printf("hotfolder: no se pudo escribir el error de %s: %v", name, err)
+	}
+}
+
+// move mueve el archivo al subdirectorio sub sin sobrescribir otro con el
+// mismo nombre y devuelve la ruta nueva
+func (s *Spooler) move(name, sub string) (string, error) {
+	dst := filepath.Join(s.opts.Dir, sub, name)
+	if _, err := os.Lstat(dst); err == nil {
+		ext := filepath.Ext(name)
+		dst = filepath.Join(s.opts.Dir, sub,
+			strings.TrimSuffix(name, ext)+"-"+s.now().Format("20060102T150405.000000000")+ext)
+	}
+	if err := os.Rename(filepath.Join(s.opts.Dir, name), dst); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
@@ -0,0 +1,204 @@
+package hotfolder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"pos-daemon.adcon.dev/internal/queue"
+	"pos-daemon.adcon.dev/internal/service"
+)
+
+// spooler crea un directorio vigilado con la plantilla de los fixtures y un
+// reloj que la prueba avanza a mano
+func spooler(t *testing.T) (*Spooler, *queue.Queue, *time.Time) {
+	t.Helper()
+	templates := t.TempDir()
+	copyFixture(t, "new_ticket_template.json", filepath.Join(templates, "new_ticket_template.json"))
+	jobs, err := queue.Open(queue.Options{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("queue.Open: %v", err)
+	}
+	t.Cleanup(func() { jobs.Close() })
+
+	s, err := New(Options{
+		Dir:             filepath.Join(t.TempDir(), "entrada"),
+		Queue:           jobs,
+		TemplatesDir:    templates,
+		DefaultTemplate: "new_ticket_template",
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	now := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	return s, jobs, &now
+}
+
+func copyFixture(t *testing.T, name, dst string) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("../api/rest", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// settle revisa el directorio dos veces separadas por StableFor, lo que
+// necesita un archivo sin cambios para procesarse
+func settle(s *Spooler, now *time.Time) {
+	s.scan()
+	*now = now.Add(s.opts.StableFor)
+	s.scan()
+}
+
+func exists(t *testing.T, path string) bool {
+	t.Helper()
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+func TestSpoolTickets(t *testing.T) {
+	tests := []struct {
+		fixture string
+		ident   string
+	}{
+		{"new_ticket.json", "NTQ3"},
+		{"ticket.json", "NDc5"}, // models.Ticket, formato anterior
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			s, jobs, now := spooler(t)
+			copyFixture(t, tt.fixture, filepath.Join(s.Dir(), "venta.json"))
+
+			settle(s, now)
+			list := jobs.List(queue.StateQueued)
+			if len(list) != 1 || list[0].Identificador != tt.ident || len(list[0].Template) == 0 {
+				t.Fatalf("trabajos = %+v", list)
+			}
+			if !exists(t, filepath.Join(s.Dir(), ProcessedDir, "venta.json")) || exists(t, filepath.Join(s.Dir(), "venta.json")) {
+				t.Error("el ticket no se movió a processed/")
+			}
+		})
+	}
+}
+
+func TestSpoolInvalidTicket(t *testing.T) {
+	s, jobs, now := spooler(t)
+	if err := os.WriteFile(filepath.Join(s.Dir(), "roto.json"), []byte(`{"data": {"folio": 12}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	settle(s, now)
+	if n := len(jobs.List("")); n != 0 {
+		t.Errorf("se encolaron %d trabajos", n)
+	}
+	if !exists(t, filepath.Join(s.Dir(), FailedDir, "roto.json")) {
+		t.Error("el ticket no se movió a failed/")
+	}
+	reason, err := os.ReadFile(filepath.Join(s.Dir(), FailedDir, "roto.json.error"))
+	if err != nil || !strings.Contains(string(reason), "ticket inválido") {
+		t.Errorf("roto.json.error = %q, %v", reason, err)
+	}
+}
+
+func TestSpoolValidation(t *testing.T) {
+	// El total de new_ticket.json no coincide con la suma de sus conceptos
+	t.Run("reject", func(t *testing.T) {
+		s, jobs, now := spooler(t)
+		s.opts.Validation = service.ValidationReject
+		copyFixture(t, "new_ticket.json", filepath.Join(s.Dir(), "venta.json"))
+
+		settle(s, now)
+		if n := len(jobs.List("")); n != 0 {
+			t.Errorf("se encolaron %d trabajos", n)
+		}
+		reason, err := os.ReadFile(filepath.Join(s.Dir(), FailedDir, "venta.json.error"))
+		if err != nil || !strings.Contains(string(reason), "total:") {
+			t.Errorf("venta.json.error = %q, %v", reason, err)
+		}
+	})
+	t.Run("warn", func(t *testing.T) {
+		s, jobs, now := spooler(t)
+		copyFixture(t, "new_ticket.json", filepath.Join(s.Dir(), "venta.json"))
+
+		settle(s, now)
+		list := jobs.List(queue.StateQueued)
+		if len(list) != 1 || len(list[0].Warnings) != 1 {
+			t.Fatalf("trabajos = %+v", list)
+		}
+	})
+}
+
+func TestSpoolWaitsForStableFile(t *testing.T) {
+	s, jobs, now := spooler(t)
+	data, err := os.ReadFile("../api/rest/new_ticket.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(s.Dir(), "venta.json")
+
+	// A medio escribir: el archivo crece entre revisiones
+	if err := os.WriteFile(path, data[:100], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s.scan()
+	*now = now.Add(s.opts.StableFor)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s.scan()
+	if n := len(jobs.List("")); n != 0 || !exists(t, path) {
+		t.Fatalf("se procesó un archivo que seguía cambiando (%d trabajos)", n)
+	}
+
+	// Los nombres temporales se ignoran hasta que se renombran
+	if err := os.WriteFile(filepath.Join(s.Dir(), "otra.json.tmp"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	*now = now.Add(s.opts.StableFor)
+	s.scan()
+	if n := len(jobs.List("")); n != 1 {
+		t.Errorf("se encolaron %d trabajos; want 1", n)
+	}
+	if !exists(t, filepath.Join(s.Dir(), "otra.json.tmp")) {
+		t.Error("se movió un archivo temporal")
+	}
+}
+
+func TestSpoolDuplicateAndNameClash(t *testing.T) {
+	s, jobs, now := spooler(t)
+	copyFixture(t, "new_ticket.json", filepath.Join(s.Dir(), "venta.json"))
+	settle(s, now)
+	copyFixture(t, "new_ticket.json", filepath.Join(s.Dir(), "venta.json"))
+	settle(s, now)
+
+	if n := len(jobs.List("")); n != 1 {
+		t.Errorf("se encolaron %d trabajos; want 1", n)
+	}
+	moved, err := filepath.Glob(filepath.Join(s.Dir(), ProcessedDir, "venta*.json"))
+	if err != nil || len(moved) != 2 {
+		t.Errorf("processed/ = %v, %v; want 2 archivos", moved, err)
+	}
+}
+
+func TestSpoolSymlinkOutside(t *testing.T) {
+	s, jobs, now := spooler(t)
+	outside := filepath.Join(t.TempDir(), "fuera.json")
+	copyFixture(t, "new_ticket.json", outside)
+	if err := os.Symlink(outside, filepath.Join(s.Dir(), "enlace.json")); err != nil {
+		t.Skipf("sin enlaces simbólicos: %v", err)
+	}
+
+	settle(s, now)
+	if n := len(jobs.List("")); n != 0 {
+		t.Errorf("se encolaron %d trabajos", n)
+	}
+	if !exists(t, filepath.Join(s.Dir(), FailedDir, "enlace.json.error")) {
+		t.Error("el enlace no se movió a failed/")
+	}
+}
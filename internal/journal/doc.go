@@ -0,0 +1,3 @@
+// Package journal guarda localmente los tickets impresos para poder
+// reimprimirlos sin que el punto de venta vuelva a enviarlos.
+package journal
@@ -0,0 +1,392 @@
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNotFound se devuelve cuando el ticket buscado no está en el journal
+var ErrNotFound = errors.New("journal: ticket no encontrado")
+
+// fileName es el nombre del archivo del journal dentro de su directorio
+const fileName = "printed.log"
+
+// Options configura el journal
+type Options struct {
+	Dir string // Directorio donde se guarda el journal
+	// Retention es cuánto se conserva un ticket desde su última impresión o
+	// reimpresión
+	Retention time.Duration
+}
+
+// DefaultRetention es la retención si Options no indica otra
+const DefaultRetention = 90 * 24 * time.Hour
+
+// pruneInterval es cada cuánto se descartan los tickets vencidos y se compacta
+// el archivo mientras el daemon corre
+const pruneInterval = time.Hour
+
+// Entry es un ticket impreso junto con los datos necesarios para reimprimirlo
+type Entry struct {
+	Identificador string          `json:"identificador,omitempty"`
+	Serie         string          `json:"serie,omitempty"`
+	Folio         string          `json:"folio,omitempty"`
+	JobID         string          `json:"job_id"`             // Trabajo que imprimió el original
+	Printer       string          `json:"printer,omitempty"`  // Impresora o grupo destino del original
+	Ticket        json.RawMessage `json:"ticket,omitempty"`   // JSON original del ticket
+	Template      json.RawMessage `json:"template,omitempty"` // JSON de la plantilla usada
+	Raw           []byte          `json:"raw,omitempty"`      // Bytes ESC/POS de un trabajo raw, sin ticket
+	Text          string          `json:"text,omitempty"`     // Texto de un trabajo de texto plano, sin ticket
+	PrintedAt     time.Time       `json:"printed_at"`         // Momento de la impresión original
+	Reprints      int             `json:"reprints"`           // Reimpresiones emitidas
+	LastReprintAt time.Time       `json:"last_reprint_at,omitempty"`
+}
+
+// key identifica la entrada; el Identificador es único, Serie+Folio es el
+// respaldo. Los trabajos raw y de texto no traen datos del ticket y se
+// identifican por trabajo.
+func (e *Entry) key() string {
+	switch {
+	case e.Identificador != "":
+		return "id:" + e.Identificador
+	case e.Serie != "" || e.Folio != "":
+		return "sf:" + e.Serie + "/" + e.Folio
+	}
+	return "job:" + e.JobID
+}
+
+// lastUse es el momento de la última impresión o reimpresión de la entrada
+func (e *Entry) lastUse() time.Time {
+	if e.LastReprintAt.After(e.PrintedAt) {
+		return e.LastReprintAt
+	}
+	return e.PrintedAt
+}
+
+// Journal es un log de solo anexado de tickets impresos. Cada cambio de una
+// entrada se escribe como una línea nueva; al abrir gana la última versión.
+// Los tickets que superan la retención se descartan y el archivo se compacta a
+// una línea por ticket al abrir y cada pruneInterval.
+type Journal struct {
+	mu        sync.Mutex
+	path      string
+	file      *os.File
+	lines     int // Líneas escritas desde la última compactación
+	retention time.Duration
+	nextPrune time.Time // Próxima limpieza de tickets vencidos
+	entries   map[string]*Entry
+	bySerie   map[string]string // "serie/folio" -> key
+	byJob     map[string]string // JobID -> key
+	now       func() time.Time
+}
+
+// Open abre (o crea) el journal en opts.Dir
+func Open(opts Options) (*Journal, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("journal: no se indicó directorio")
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = DefaultRetention
+	}
+	if err := os.MkdirAll(opts.Dir, 0o750); err != nil {
+		return nil, fmt.Errorf("journal: no se pudo crear el directorio %s: %w", opts.Dir, err)
+	}
+
+	j := &Journal{
+		path:      filepath.Join(filepath.Clean(opts.Dir), fileName),
+		retention: opts.Retention,
+		entries:   make(map[string]*Entry),
+		bySerie:   make(map[string]string),
+		byJob:     make(map[string]string),
+		now:       time.Now,
+	}
+	if err := j.replay(j.path); err != nil {
+		return nil, err
+	}
+	now := j.now()
+	j.discard(now)
+	if err := j.compact(); err != nil {
+		return nil, err
+	}
+	j.nextPrune = now.Add(pruneInterval)
+
+	file, err := j.openFile()
+	if err != nil {
+		return nil, err
+	}
+	j.file = file
+	return j, nil
+}
+
+// openFile abre el archivo del journal para anexar
+func (j *Journal) openFile() (*os.File, error) {
+	file, err := os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("journal: no se pudo abrir %s: %w", j.path, err)
+	}
+	return file, nil
+}
+
+// replay reconstruye el índice en memoria a partir del archivo
+func (j *Journal) replay(path string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("journal: no se pudo leer %s: %w", path, err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("journal: error al cerrar el archivo: %v", err)
+		}
+	}()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			log.Printf("journal: se ignora la línea %d corrupta: %v", line, err)
+			continue
+		}
+		j.index(&e)
+		j.lines++
+	}
+	return scanner.Err()
+}
+
+// index guarda la entrada en los mapas de búsqueda
+func (j *Journal) index(e *Entry) {
+	k := e.key()
+	j.entries[k] = e
+	if e.Serie != "" || e.Folio != "" {
+		j.bySerie[e.Serie+"/"+e.Folio] = k
+	}
+	if e.JobID != "" {
+		j.byJob[e.JobID] = k
+	}
+}
+
+// write anexa la versión actual de la entrada al archivo
+func (j *Journal) write(e *Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("journal: no se pudo serializar la entrada: %w", err)
+	}
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("journal: no se pudo escribir la entrada: %w", err)
+	}
+	j.lines++
+	return j.file.Sync()
+}
+
+// discard quita del índice los tickets que superaron la retención. Se llama
+// con j.mu tomado.
+func (j *Journal) discard(now time.Time) {
+	removed := make(map[string]bool)
+	for k, e := range j.entries {
+		if now.Sub(e.lastUse()) > j.retention {
+			delete(j.entries, k)
+			removed[k] = true
+		}
+	}
+	if len(removed) == 0 {
+		return
+	}
+	for sf, k := range j.bySerie {
+		if removed[k] {
+			delete(j.bySerie, sf)
+		}
+	}
+	for id, k := range j.byJob {
+		if removed[k] {
+			delete(j.byJob, id)
+		}
+	}
+	log.Printf("journal: se descartan %d tickets impresos hace más de %s", len(removed), j.retention)
+}
+
+// prune descarta los tickets vencidos y compacta el archivo si tiene
+// versiones viejas, a lo más una vez cada pruneInterval. Se llama con j.mu
+// tomado.
+func (j *Journal) prune() {
+	now := j.now()
+	if now.Before(j.nextPrune) {
+		return
+	}
+	j.nextPrune = now.Add(pruneInterval)
+	j.discard(now)
+	if j.lines <= len(j.entries) {
+		return
+	}
+	if err := j.rewrite(); err != nil {
+		log.Printf("%v", err)
+	}
+}
+
+// compact reescribe el archivo con una sola línea por ticket usando un
+// renombrado atómico
+func (j *Journal) compact() error {
+	ordered := make([]*Entry, 0, len(j.entries))
+	for _, e := range j.entries {
+		ordered = append(ordered, e)
+	}
+	sort.Slice(ordered, func(a, b int) bool {
+		return ordered[a].PrintedAt.Before(ordered[b].PrintedAt)
+	})
+
+	tmp := j.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("journal: no se pudo compactar %s: %w", j.path, err)
+	}
+	w := bufio.NewWriter(file)
+	enc := json.NewEncoder(w)
+	for _, e := range ordered {
+		if err := enc.Encode(e); err != nil {
+			_ = file.Close()
+			return fmt.Errorf("journal: no se pudo compactar %s: %w", j.path, err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("journal: no se pudo compactar %s: %w", j.path, err)
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("journal: no se pudo compactar %s: %w", j.path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("journal: no se pudo compactar %s: %w", j.path, err)
+	}
+	if err := os.Rename(tmp, j.path); err != nil {
+		return fmt.Errorf("journal: no se pudo compactar %s: %w", j.path, err)
+	}
+	j.lines = len(ordered)
+	return nil
+}
+
+// rewrite compacta el archivo abierto. Se cierra antes del renombrado, que en
+// Windows falla sobre un archivo abierto; si la compactación falla se sigue
+// anexando al archivo anterior.
+func (j *Journal) rewrite() error {
+	if err := j.file.Close(); err != nil {
+		log.Printf("journal: error al cerrar el archivo: %v", err)
+	}
+	compactErr := j.compact()
+	file, err := j.openFile()
+	if err != nil {
+		return errors.Join(compactErr, err)
+	}
+	j.file = file
+	return compactErr
+}
+
+// Close cierra el archivo del journal
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.file.Close()
+}
+
+// Record registra la impresión original de un ticket. Si el ticket ya estaba
+// registrado se conserva su contador de reimpresiones. Los trabajos raw y de
+// texto se registran por su JobID.
+func (j *Journal) Record(e Entry) error {
+	if e.Identificador == "" && e.Serie == "" && e.Folio == "" && len(e.Raw) == 0 && e.Text == "" {
+		return fmt.Errorf("journal: el ticket del trabajo %s no tiene identificador ni serie/folio", e.JobID)
+	}
+	if e.PrintedAt.IsZero() {
+		e.PrintedAt = j.now()
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.prune()
+	if prev, ok := j.entries[e.key()]; ok {
+		e.Reprints = prev.Reprints
+		e.LastReprintAt = prev.LastReprintAt
+	}
+	if err := j.write(&e); err != nil {
+		return err
+	}
+	j.index(&e)
+	return nil
+}
+
+// Query indica qué ticket buscar: por Identificador, por Serie y Folio o por
+// el JobID del trabajo que lo imprimió. Los trabajos raw y de texto solo se
+// encuentran por JobID.
+type Query struct {
+	Identificador string
+	Serie         string
+	Folio         string
+	JobID         string
+}
+
+// Find busca un ticket por Identificador, por Serie y Folio o por JobID, en
+// ese orden
+func (j *Journal) Find(q Query) (*Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	e, err := j.find(q)
+	if err != nil {
+		return nil, err
+	}
+	c := *e
+	return &c, nil
+}
+
+func (j *Journal) find(q Query) (*Entry, error) {
+	var key string
+	switch {
+	case q.Identificador != "":
+		key = "id:" + q.Identificador
+	case q.Serie != "" || q.Folio != "":
+		key = j.bySerie[q.Serie+"/"+q.Folio]
+	case q.JobID != "":
+		key = j.byJob[q.JobID]
+	default:
+		return nil, fmt.Errorf("journal: se requiere identificador, serie/folio o trabajo")
+	}
+	if e, ok := j.entries[key]; ok {
+		return e, nil
+	}
+	return nil, ErrNotFound
+}
+
+// RecordReprint guarda que se emitió la reimpresión número n del ticket de e.
+// Se llama después de encolar la copia, de modo que una reimpresión que no
+// llegó a la cola no consume un número; el contador nunca retrocede.
+func (j *Journal) RecordReprint(e *Entry, n int, at time.Time) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	cur, ok := j.entries[e.key()]
+	if !ok {
+		return ErrNotFound
+	}
+	updated := *cur
+	updated.Reprints = max(updated.Reprints, n)
+	updated.LastReprintAt = at
+	if err := j.write(&updated); err != nil {
+		return err
+	}
+	j.index(&updated)
+	return nil
+}
@@ -0,0 +1,165 @@
+package journal
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournalReprints(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open(Options{Dir: dir})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	entry := Entry{
+		Identificador: "NTQ3",
+		Serie:         "ABC1",
+		Folio:         "326",
+		JobID:         "job-1",
+		Ticket:        []byte(`{"data":{"identificador":"NTQ3"}}`),
+		Template:      []byte(`{"data":{}}`),
+	}
+	if err := j.Record(entry); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	// Buscar no consume números de reimpresión
+	for range 2 {
+		found, err := j.Find(Query{Identificador: "NTQ3"})
+		if err != nil || found.Reprints != 0 {
+			t.Fatalf("Find por identificador = %v, %v; want 0 reimpresiones", found, err)
+		}
+	}
+	if err := j.RecordReprint(&entry, 1, time.Now()); err != nil {
+		t.Fatalf("RecordReprint: %v", err)
+	}
+	if err := j.RecordReprint(&entry, 2, time.Now()); err != nil {
+		t.Fatalf("RecordReprint: %v", err)
+	}
+	second, err := j.Find(Query{Serie: "ABC1", Folio: "326"})
+	if err != nil || second.Reprints != 2 {
+		t.Fatalf("Find por serie/folio = %v, %v; want 2", second, err)
+	}
+	if string(second.Ticket) != string(entry.Ticket) {
+		t.Errorf("Ticket = %s; want %s", second.Ticket, entry.Ticket)
+	}
+	// El contador nunca retrocede
+	if err := j.RecordReprint(&entry, 1, time.Now()); err != nil {
+		t.Fatalf("RecordReprint: %v", err)
+	}
+
+	if _, err := j.Find(Query{Identificador: "NO-EXISTE"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Find de ticket inexistente = %v; want ErrNotFound", err)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// El contador sobrevive a un reinicio
+	j, err = Open(Options{Dir: dir})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer j.Close()
+	third, err := j.Find(Query{Identificador: "NTQ3"})
+	if err != nil || third.Reprints != 2 {
+		t.Fatalf("Find tras reinicio = %v, %v; want 2", third, err)
+	}
+}
+
+func TestJournalRecordRaw(t *testing.T) {
+	j, err := Open(Options{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer j.Close()
+
+	if err := j.Record(Entry{JobID: "job-1", Ticket: []byte(`{"data":{}}`)}); err == nil {
+		t.Error("Record aceptó un ticket sin identificador ni serie/folio")
+	}
+	// Los trabajos raw no tienen datos del ticket y se registran por trabajo
+	for _, id := range []string{"job-2", "job-3"} {
+		if err := j.Record(Entry{JobID: id, Raw: []byte("HOLA\n")}); err != nil {
+			t.Fatalf("Record raw: %v", err)
+		}
+	}
+	if n := len(j.entries); n != 2 {
+		t.Errorf("entradas = %d; want 2", n)
+	}
+
+	// Y se reimprimen por trabajo
+	e, err := j.Find(Query{JobID: "job-3"})
+	if err != nil || e.JobID != "job-3" || string(e.Raw) != "HOLA\n" {
+		t.Fatalf("Find por trabajo = %+v, %v", e, err)
+	}
+	if _, err := j.Find(Query{JobID: "job-9"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Find de trabajo inexistente = %v; want ErrNotFound", err)
+	}
+	if _, err := j.Find(Query{}); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Find sin datos = %v", err)
+	}
+}
+
+func TestJournalRetention(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open(Options{Dir: dir, Retention: 80 * time.Hour})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	start := time.Now().Add(-100 * time.Hour)
+	old := Entry{Identificador: "VIEJO", Serie: "ABC1", Folio: "1", JobID: "job-1", PrintedAt: start}
+	reprinted := Entry{Identificador: "REIMPRESO", JobID: "job-2", PrintedAt: start}
+	for _, e := range []Entry{old, reprinted} {
+		if err := j.Record(e); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	// La reimpresión renueva la retención del ticket
+	if err := j.RecordReprint(&reprinted, 1, start.Add(24*time.Hour)); err != nil {
+		t.Fatalf("RecordReprint: %v", err)
+	}
+
+	// Pasada la retención del primero, el siguiente registro lo descarta y
+	// compacta el archivo
+	j.nextPrune = time.Time{}
+	if err := j.Record(Entry{Identificador: "NUEVO", JobID: "job-3"}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	for _, q := range []Query{{Identificador: "VIEJO"}, {Serie: "ABC1", Folio: "1"}, {JobID: "job-1"}} {
+		if _, err := j.Find(q); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Find(%+v) = %v; want ErrNotFound", q, err)
+		}
+	}
+	if e, err := j.Find(Query{Identificador: "REIMPRESO"}); err != nil || e.Reprints != 1 {
+		t.Errorf("Find del reimpreso = %+v, %v", e, err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, fileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// La versión compactada del reimpreso más el registro nuevo
+	if n := bytes.Count(data, []byte("\n")); n != 2 {
+		t.Errorf("líneas del journal = %d; want 2", n)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// Al abrir también se descartan los vencidos
+	j, err = Open(Options{Dir: dir, Retention: time.Hour})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer j.Close()
+	if _, err := j.Find(Query{Identificador: "REIMPRESO"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Find tras reabrir = %v; want ErrNotFound", err)
+	}
+	if _, err := j.Find(Query{Identificador: "NUEVO"}); err != nil {
+		t.Errorf("Find del nuevo tras reabrir = %v", err)
+	}
+}
@@ -0,0 +1,6 @@
+// Package lpd implementa un servidor LPD (RFC 1179) para que los equipos que
+// imprimen con lpr usen las impresoras del daemon. Cada cola LPD corresponde a
+// una impresora o grupo configurado; los archivos de datos se encolan como
+// trabajos raw (tipo l) o de texto plano (tipos f y p), y el texto se acomoda
+// al ancho de la impresora que lo imprime.
+package lpd
@@ -0,0 +1,417 @@
+package lpd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"pos-daemon.adcon.dev/internal/printers"
+	"pos-daemon.adcon.dev/internal/queue"
+)
+
+// Valores por defecto de Options
+const (
+	DefaultTimeout     = time.Minute
+	DefaultMaxJobBytes = 16 << 20
+)
+
+// Comandos del protocolo (RFC 1179, sección 5)
+const (
+	cmdPrintWaiting = 0x01 // Imprimir trabajos pendientes
+	cmdReceiveJob   = 0x02 // Recibir un trabajo
+	cmdStateShort   = 0x03 // Estado de la cola, formato corto
+	cmdStateLong    = 0x04 // Estado de la cola, formato largo
+	cmdRemoveJobs   = 0x05 // Quitar trabajos
+)
+
+// Subcomandos de "recibir un trabajo" (RFC 1179, sección 6)
+const (
+	subAbort   = 0x01 // Descartar el trabajo
+	subControl = 0x02 // Recibir archivo de control
+	subData    = 0x03 // Recibir archivo de datos
+)
+
+// Respuestas de confirmación
+const (
+	ack  = 0x00
+	nack = 0x01
+)
+
+// Options configura el servidor LPD
+type Options struct {
+	Addr     string             // Dirección de escucha (ej. ":515")
+	Printers *printers.Registry // Impresoras y grupos que se publican como colas
+	Queues   map[string]string  // Alias de cola -> impresora o grupo
+	Queue    *queue.Queue       // Cola donde se encolan los trabajos
+
+	Timeout     time.Duration // Tiempo máximo de espera de cada comando o archivo
+	MaxJobBytes int           // Tamaño máximo de un archivo de datos
+}
+
+// Server atiende conexiones LPD
+type Server struct {
+	opts Options
+	ln   net.Listener
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+	wg    sync.WaitGroup
+}
+
+// Listen abre el puerto indicado en opts.Addr; Serve atiende las conexiones
+func Listen(opts Options) (*Server, error) {
+	if opts.Printers == nil || opts.Queue == nil {
+		return nil, fmt.Errorf("lpd: se requieren las impresoras y la cola de trabajos")
+	}
+	for alias, name := range opts.Queues {
+		if _, err := opts.Printers.Resolve(name); err != nil {
+			return nil, fmt.Errorf("lpd: cola %q: %w", alias, err)
+		}
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.MaxJobBytes <= 0 {
+		opts.MaxJobBytes = DefaultMaxJobBytes
+	}
+	ln, err := net.Listen("tcp", opts.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("lpd: %w", err)
+	}
+	return &Server{opts: opts, ln: ln, conns: make(map[net.Conn]struct{})}, nil
+}
+
+// Addr devuelve la dirección en la que escucha
+func (s *Server) Addr() net.Addr {
+	return s.ln.Addr()
+}
+
+// Close cierra el puerto sin atender conexiones; para detener Serve se
+// cancela su contexto
+func (s *Server) Close() error {
+	return s.ln.Close()
+}
+
+// Serve atiende conexiones hasta que ctx se cancele. Los trabajos que se
+// estaban recibiendo se descartan; lpr los reintenta.
+func (s *Server) Serve(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() {
+		if err := s.ln.Close(); err != nil {
+			log.Printf("lpd: error al cerrar %s: %v", s.opts.Addr, err)
+		}
+		s.mu.Lock()
+		for conn := range s.conns {
+			_ = conn.Close()
+		}
+		s.mu.Unlock()
+	})
+	defer stop()
+
+	var err error
+	for {
+		var conn net.Conn
+		conn, err = s.ln.Accept()
+		if err != nil {
+			break
+		}
+		s.mu.Lock()
+		if ctx.Err() != nil {
+			s.mu.Unlock()
+			_ = conn.Close()
+			continue
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+	s.wg.Wait()
+	if ctx.Err() != nil {
+		return nil
+	}
+	return fmt.Errorf("lpd: %w", err)
+}
+
+// resolve devuelve la impresora o grupo de una cola LPD
+func (s *Server) resolve(name string) (*printers.Destination, error) {
+	if target, ok := s.opts.Queues[name]; ok {
+		name = target
+	}
+	return s.opts.Printers.Resolve(name)
+}
+
+// conn es una conexión LPD con lectura por líneas
+type conn struct {
+	net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+}
+
+// command lee un comando: un byte seguido de sus operandos hasta LF
+func (c *conn) command() (byte, []string, error) {
+	if err := c.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, nil, err
+	}
+	code, err := c.r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return 0, nil, err
+	}
+	return code, strings.Fields(line), nil
+}
+
+// reply envía un byte de confirmación
+func (c *conn) reply(b byte) error {
+	_, err := c.Write([]byte{b})
+	return err
+}
+
+// handle atiende un comando LPD; cada conexión lleva uno solo
+func (s *Server) handle(nc net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, nc)
+		s.mu.Unlock()
+		if err := nc.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			log.Printf("lpd: error al cerrar conexión: %v", err)
+		}
+	}()
+
+	c := &conn{Conn: nc, r: bufio.NewReader(nc), timeout: s.opts.Timeout}
+	code, args, err := c.command()
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			log.Printf("lpd: error al leer comando de %s: %v", nc.RemoteAddr(), err)
+		}
+		return
+	}
+	if len(args) == 0 {
+		log.Printf("lpd: comando 0x%02X sin cola de %s", code, nc.RemoteAddr())
+		return
+	}
+
+	switch code {
+	case cmdPrintWaiting:
+		// Los trabajos se imprimen en cuanto se reciben
+	case cmdReceiveJob:
+		s.receiveJob(c, args[0])
+	case cmdStateShort, cmdStateLong:
+		s.sendState(c, args[0], code == cmdStateLong)
+	case cmdRemoveJobs:
+		log.Printf("lpd: %s pidió quitar trabajos de %s; no se admite", nc.RemoteAddr(), args[0])
+	default:
+		log.Printf("lpd: comando desconocido 0x%02X de %s", code, nc.RemoteAddr())
+	}
+}
+
+// receiveJob recibe los archivos de control y de datos de un trabajo y lo
+// encola cuando el cliente cierra la conexión
+func (s *Server) receiveJob(c *conn, name string) {
+	dest, err := s.resolve(name)
+	if err != nil {
+		log.Printf("lpd: %s envió un trabajo a la cola %q: %v", c.RemoteAddr(), name, err)
+		_ = c.reply(nack)
+		return
+	}
+	if err := c.reply(ack); err != nil {
+		return
+	}
+
+	var controls [][]byte
+	files := make(map[string][]byte)
+	for {
+		code, args, err := c.command()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			log.Printf("lpd: se descarta el trabajo de %s: %v", c.RemoteAddr(), err)
+			return
+		}
+		switch code {
+		case subAbort:
+			controls, files = nil, make(map[string][]byte)
+			if err := c.reply(ack); err != nil {
+				return
+			}
+			continue
+		case subControl, subData:
+		default:
+			log.Printf("lpd: subcomando desconocido 0x%02X de %s", code, c.RemoteAddr())
+			_ = c.reply(nack)
+			return
+		}
+
+		data, err := s.receiveFile(c, args)
+		if err != nil {
+			log.Printf("lpd: se descarta el trabajo de %s: %v", c.RemoteAddr(), err)
+			_ = c.reply(nack)
+			return
+		}
+		if err := c.reply(ack); err != nil {
+			return
+		}
+		if code == subControl {
+			controls = append(controls, data)
+		} else {
+			files[args[1]] = data
+		}
+	}
+
+	for _, control := range controls {
+		s.enqueue(c, dest, parseControl(control), files)
+	}
+}
+
+// receiveFile confirma el subcomando "count name" y lee el archivo, terminado
+// por un byte cero
+func (s *Server) receiveFile(c *conn, args []string) ([]byte, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("subcomando inválido %q", strings.Join(args, " "))
+	}
+	size, err := strconv.Atoi(args[0])
+	if err != nil || size <= 0 {
+		return nil, fmt.Errorf("tamaño de archivo inválido %q", args[0])
+	}
+	if size > s.opts.MaxJobBytes {
+		return nil, fmt.Errorf("el archivo %s supera %d bytes", args[1], s.opts.MaxJobBytes)
+	}
+	if err := c.reply(ack); err != nil {
+		return nil, err
+	}
+
+	if err := c.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return nil, err
+	}
+	data := make([]byte, size+1)
+	if _, err := io.ReadFull(c.r, data); err != nil {
+		return nil, fmt.Errorf("error al leer %s: %w", args[1], err)
+	}
+	if data[size] != 0 {
+		return nil, fmt.Errorf("el archivo %s no termina en un byte cero", args[1])
+	}
+	return data[:size], nil
+}
+
+// control es lo que interesa de un archivo de control
+type control struct {
+	host, user, job string
+	prints          []printLine
+}
+
+// printLine es una orden de impresión del archivo de control
+type printLine struct {
+	kind byte   // Tipo de archivo: l, f, p, o...
+	file string // Nombre del archivo de datos
+}
+
+// parseControl interpreta las líneas de un archivo de control (RFC 1179,
+// sección 7). Cada línea es una letra seguida de su operando.
+func parseControl(data []byte) control {
+	var c control
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+		op := line[1:]
+		switch line[0] {
+		case 'H':
+			c.host = op
+		case 'P':
+			c.user = op
+		case 'J':
+			c.job = op
+		case 'c', 'd', 'f', 'g', 'l', 'n', 'o', 'p', 'r', 't', 'v':
+			c.prints = append(c.prints, printLine{kind: line[0], file: op})
+		}
+	}
+	return c
+}
+
+// enqueue encola un trabajo por cada orden de impresión del archivo de
+// control; las copias llegan como órdenes repetidas
+func (s *Server) enqueue(c *conn, dest *printers.Destination, ctl control, files map[string][]byte) {
+	for _, p := range ctl.prints {
+		data, ok := files[p.file]
+		if !ok {
+			log.Printf("lpd: el trabajo %q de %s@%s no envió el archivo %s", ctl.job, ctl.user, ctl.host, p.file)
+			continue
+		}
+
+		req := queue.Request{Printer: dest.Name}
+		switch p.kind {
+		case 'l':
+			req.Raw = data
+		case 'f', 'p':
+			req.Text = decodeText(data)
+		default:
+			log.Printf("lpd: el trabajo %q de %s@%s es de tipo %c; solo se imprimen los tipos l, f y p",
+				ctl.job, ctl.user, ctl.host, p.kind)
+			continue
+		}
+		if len(req.Raw) == 0 && strings.TrimSpace(req.Text) == "" {
+			continue
+		}
+
+		job, err := s.opts.Queue.Enqueue(req)
+		if err != nil {
+			log.Printf("lpd: no se pudo encolar el trabajo de %s: %v", c.RemoteAddr(), err)
+			continue
+		}
+		log.Printf("Trabajo LPD %s (%s) recibido de %s@%s para %s", job.ID, job.Kind, ctl.user, ctl.host, job.Printer)
+	}
+}
+
+// decodeText interpreta un archivo de texto como UTF-8 o, si no es válido,
+// como Latin-1, la codificación habitual de los sistemas antiguos
+func decodeText(data []byte) string {
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// sendState responde el estado de la cola con los trabajos pendientes de su
+// impresora o grupo
+func (s *Server) sendState(c *conn, name string, long bool) {
+	var buf bytes.Buffer
+	dest, err := s.resolve(name)
+	if err != nil {
+		fmt.Fprintf(&buf, "%s: cola desconocida\n", name)
+	} else {
+		var pending []*queue.Job
+		for _, job := range s.opts.Queue.List("") {
+			if job.State.Pending() && job.Printer == dest.Name {
+				pending = append(pending, job)
+			}
+		}
+		fmt.Fprintf(&buf, "%s: %d trabajos pendientes\n", name, len(pending))
+		if long {
+			for _, job := range pending {
+				fmt.Fprintf(&buf, "%s\t%s\t%s\t%s\n", job.ID, job.State, job.Kind, job.CreatedAt.Format(time.DateTime))
+			}
+		}
+	}
+	if _, err := c.Write(buf.Bytes()); err != nil {
+		log.Printf("lpd: error al enviar el estado a %s: %v", c.RemoteAddr(), err)
+	}
+}
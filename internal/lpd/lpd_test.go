@@ -0,0 +1,214 @@
+package lpd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"pos-daemon.adcon.dev/internal/connector"
+	"pos-daemon.adcon.dev/internal/printers"
+	"pos-daemon.adcon.dev/internal/queue"
+)
+
+// serve abre un servidor LPD con la impresora "mostrador" y el alias "lp" y
+// lo atiende hasta que termine la prueba
+func serve(t *testing.T) (*Server, *queue.Queue) {
+	t.Helper()
+	registry, err := printers.New([]printers.Printer{
+		{Name: "mostrador", Connector: connector.Options{Type: connector.TypeFile, Device: "ticket.bin"}},
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("printers.New: %v", err)
+	}
+	jobs, err := queue.Open(queue.Options{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("queue.Open: %v", err)
+	}
+	s, err := Listen(Options{
+		Addr:     "127.0.0.1:0",
+		Printers: registry,
+		Queues:   map[string]string{"lp": "mostrador"},
+		Queue:    jobs,
+		Timeout:  5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+		jobs.Close()
+	})
+	return s, jobs
+}
+
+// client es un cliente LPD mínimo para las pruebas
+type client struct {
+	t    *testing.T
+	conn net.Conn
+}
+
+func dial(t *testing.T, s *Server) *client {
+	t.Helper()
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	t.Cleanup(func() { conn.Close() })
+	return &client{t: t, conn: conn}
+}
+
+// send envía un comando y devuelve el byte de confirmación
+func (c *client) send(format string, args ...any) byte {
+	c.t.Helper()
+	if _, err := fmt.Fprintf(c.conn, format, args...); err != nil {
+		c.t.Fatal(err)
+	}
+	reply := make([]byte, 1)
+	if _, err := io.ReadFull(c.conn, reply); err != nil {
+		c.t.Fatalf("%q: %v", format, err)
+	}
+	return reply[0]
+}
+
+// file envía un subcomando de archivo con su contenido
+func (c *client) file(sub byte, name string, data []byte) {
+	c.t.Helper()
+	if r := c.send("%c%d %s\n", sub, len(data), name); r != ack {
+		c.t.Fatalf("subcomando 0x%02X %s = 0x%02X", sub, name, r)
+	}
+	if r := c.send("%s\x00", data); r != ack {
+		c.t.Fatalf("archivo %s = 0x%02X", name, r)
+	}
+}
+
+// waitQueued espera a que la cola tenga n trabajos
+func waitQueued(t *testing.T, jobs *queue.Queue, n int) []*queue.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		list := jobs.List(queue.StateQueued)
+		if len(list) >= n {
+			return list
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("se encolaron %d trabajos; want %d", len(list), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestTextJob(t *testing.T) {
+	s, jobs := serve(t)
+	c := dial(t, s)
+
+	if r := c.send("\x02mostrador\n"); r != ack {
+		t.Fatalf("recibir trabajo = 0x%02X", r)
+	}
+	c.file(subControl, "cfA001caja", []byte("Hcaja\nPmaria\nJventas.txt\nfdfA001caja\nUdfA001caja\n"))
+	c.file(subData, "dfA001caja", []byte("Ventas del día\n"))
+	c.conn.Close()
+
+	job := waitQueued(t, jobs, 1)[0]
+	if job.Kind != queue.KindText || job.Text != "Ventas del día\n" || job.Printer != "mostrador" {
+		t.Errorf("trabajo = %+v", job)
+	}
+}
+
+func TestRawJobDataFirst(t *testing.T) {
+	s, jobs := serve(t)
+	c := dial(t, s)
+
+	// lpr -l y algunos clientes envían los datos antes que el control; el
+	// alias "lp" lleva a "mostrador"
+	data := []byte{0x1B, 0x40, 'H', 'O', 'L', 'A', '\n', 0x1D, 0x56, 0x00}
+	if r := c.send("\x02lp\n"); r != ack {
+		t.Fatalf("recibir trabajo = 0x%02X", r)
+	}
+	c.file(subData, "dfA002caja", data)
+	c.file(subControl, "cfA002caja", []byte("Hcaja\nPmaria\nldfA002caja\nldfA002caja\n"))
+	c.conn.Close()
+
+	list := waitQueued(t, jobs, 2)
+	for _, job := range list {
+		if !job.IsRaw() || string(job.Raw) != string(data) || job.Printer != "mostrador" {
+			t.Errorf("trabajo = %+v", job)
+		}
+	}
+}
+
+func TestLatin1Text(t *testing.T) {
+	s, jobs := serve(t)
+	c := dial(t, s)
+
+	c.send("\x02mostrador\n")
+	c.file(subControl, "cfA003caja", []byte("Hcaja\npdfA003caja\n"))
+	c.file(subData, "dfA003caja", []byte("Se\xf1al\n"))
+	c.conn.Close()
+
+	if job := waitQueued(t, jobs, 1)[0]; job.Text != "Señal\n" {
+		t.Errorf("Text = %q; want %q", job.Text, "Señal\n")
+	}
+}
+
+func TestUnknownQueue(t *testing.T) {
+	s, jobs := serve(t)
+	c := dial(t, s)
+
+	if r := c.send("\x02cocina\n"); r != nack {
+		t.Errorf("cola desconocida = 0x%02X; want 0x%02X", r, nack)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := len(jobs.List("")); n != 0 {
+		t.Errorf("se encolaron %d trabajos", n)
+	}
+}
+
+func TestAbortJob(t *testing.T) {
+	s, jobs := serve(t)
+	c := dial(t, s)
+
+	c.send("\x02mostrador\n")
+	c.file(subControl, "cfA004caja", []byte("Hcaja\nfdfA004caja\n"))
+	if r := c.send("\x01\n"); r != ack {
+		t.Fatalf("abortar = 0x%02X", r)
+	}
+	c.conn.Close()
+
+	time.Sleep(50 * time.Millisecond)
+	if n := len(jobs.List("")); n != 0 {
+		t.Errorf("se encolaron %d trabajos", n)
+	}
+}
+
+func TestQueueState(t *testing.T) {
+	s, jobs := serve(t)
+	if _, err := jobs.Enqueue(queue.Request{Text: "uno", Printer: "mostrador"}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := dial(t, s)
+	if _, err := fmt.Fprintf(c.conn, "\x04lp\n"); err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	sc := bufio.NewScanner(c.conn)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	if len(lines) != 2 || lines[0] != "lp: 1 trabajos pendientes" || !strings.Contains(lines[1], "text") {
+		t.Errorf("estado = %q", lines)
+	}
+}
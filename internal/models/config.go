@@ -11,9 +11,132 @@ type ConfigData struct {
 	Printer  string `json:"printer"`   // Nombre de la impresora a utilizar
 	DebugLog bool   `json:"debug_log"` // Habilitar logs de depuración
 
+	// Conexión con la impresora (por defecto la cola de Windows con el nombre de Printer)
+	Connector ConnectorConfig `json:"connector"`
+	// Consulta del estado de la impresora única
+	Status StatusConfig `json:"status"`
+
+	// Impresoras con nombre y reglas para elegir una por ticket. Si Printers está
+	// vacío se usa Printer/Connector como única impresora.
+	Printers []PrinterConfig `json:"printers"`
+	Groups   []GroupConfig   `json:"groups"`
+	Routes   []RouteConfig   `json:"routes"`
+
+	// Puertos raw (JetDirect) para programas que solo saben imprimir a una
+	// impresora de red: cada puerto publica una impresora o grupo
+	Raw []RawConfig `json:"raw"`
+	// Servidor LPD (RFC 1179) para equipos que imprimen con lpr
+	LPD LPDConfig `json:"lpd"`
+	// Directorio vigilado para sistemas que solo pueden exportar archivos
+	HotFolder HotFolderConfig `json:"hot_folder"`
+
+	// Configuración del servidor HTTP
+	ListenAddr      string `json:"listen_addr"`      // Dirección de escucha (ej. ":8080")
+	TemplatesDir    string `json:"templates_dir"`    // Directorio de plantillas JSON
+	DefaultTemplate string `json:"default_template"` // Plantilla usada si la petición no indica una
+	ReceiptsDir     string `json:"receipts_dir"`     // Directorio de recibos libres (*.tmpl); vacío los desactiva
+
+	// Cola persistente de trabajos
+	Queue QueueConfig `json:"queue"`
+
+	// Journal de tickets impresos para reimpresiones
+	JournalDir           string `json:"journal_dir"`
+	JournalRetentionDays int    `json:"journal_retention_days"` // Días que se conserva cada ticket desde su última impresión
+
+	// Validación de los tickets antes de encolarlos
+	Validation ValidationConfig `json:"validation"`
+
 	// Configuración de puerto serial
 	SerialBaudRate int    `json:"serial_baud_rate"` // Velocidad en baudios
 	SerialDataBits int    `json:"serial_data_bits"` // Bits de datos (típicamente 8)
 	SerialStopBits int    `json:"serial_stop_bits"` // Bits de parada (típicamente 1)
 	SerialParity   string `json:"serial_parity"`    // Paridad (none, odd, even)
 }
+
+// ConnectorConfig indica cómo se conecta el daemon con la impresora
+type ConnectorConfig struct {
+	Type      string `json:"type"`       // windows, tcp, serial, usb-device o file
+	Printer   string `json:"printer"`    // Nombre en la cola de Windows (windows); por defecto el de la impresora
+	Address   string `json:"address"`    // Host o IP de la impresora (tcp)
+	Port      int    `json:"port"`       // Puerto TCP (9100 por defecto)
+	Device    string `json:"device"`     // Ruta del dispositivo o archivo (serial, usb-device, file)
+	TimeoutMs int    `json:"timeout_ms"` // Tiempo máximo de conexión y escritura en milisegundos
+}
+
+// PrinterConfig describe una impresora con nombre
+type PrinterConfig struct {
+	Name            string          `json:"name"`             // Nombre usado en rutas y peticiones (ej. "mostrador")
+	Profile         string          `json:"profile"`          // 58mm u 80mm; si está vacío se detecta por el nombre
+	Connector       ConnectorConfig `json:"connector"`        // Conexión con la impresora
+	DefaultTemplate string          `json:"default_template"` // Plantilla si la petición no indica una
+	Status          StatusConfig    `json:"status"`           // Consulta del estado
+}
+
+// StatusConfig configura la consulta periódica del estado de una impresora.
+// Solo se consulta en conexiones que permiten leer respuestas (tcp y serial).
+type StatusConfig struct {
+	Disabled bool `json:"disabled"` // No consultar el estado
+	PollMs   int  `json:"poll_ms"`  // Intervalo entre consultas en milisegundos (5000 por defecto)
+	ASB      bool `json:"asb"`      // Usar Automatic Status Back (GS a) en lugar de DLE EOT
+}
+
+// GroupConfig agrupa impresoras para reimprimir en la siguiente cuando una
+// falla. Las rutas y peticiones pueden usar el nombre del grupo como impresora.
+type GroupConfig struct {
+	Name     string   `json:"name"`     // Nombre usado en rutas y peticiones
+	Printers []string `json:"printers"` // Impresora principal seguida de las de respaldo, en orden
+}
+
+// RouteConfig elige una impresora cuando los datos del ticket coinciden con los
+// campos indicados. Los campos vacíos no se comparan; una ruta sin condiciones
+// atrapa todos los tickets.
+type RouteConfig struct {
+	Sucursal       string `json:"sucursal"`        // Código de sucursal del ticket
+	SucursalTienda string `json:"sucursal_tienda"` // Nombre de la tienda
+	TipoOperacion  string `json:"tipo_operacion"`  // NOTA_VENTA, FACTURA, etc.
+	Printer        string `json:"printer"`         // Impresora o grupo destino
+}
+
+// RawConfig publica una impresora o grupo como impresora de red raw. Los bytes
+// recibidos se encolan como un trabajo y se envían tal cual.
+type RawConfig struct {
+	Listen        string `json:"listen"`          // Dirección de escucha (ej. ":9100")
+	Printer       string `json:"printer"`         // Impresora o grupo destino; vacío usa las rutas sin condiciones
+	IdleTimeoutMs int    `json:"idle_timeout_ms"` // Silencio que da por terminado un trabajo en milisegundos (30000 por defecto)
+}
+
+// LPDConfig configura el servidor LPD. Cada cola LPD es la impresora o grupo
+// del daemon con el mismo nombre, o la que indique Queues.
+type LPDConfig struct {
+	Listen string            `json:"listen"` // Dirección de escucha (ej. ":515"); vacío lo desactiva
+	Queues map[string]string `json:"queues"` // Alias de cola -> impresora o grupo (ej. "lp": "mostrador")
+}
+
+// HotFolderConfig configura el directorio vigilado. Los tickets JSON que se
+// dejan en Dir se encolan y se mueven a processed/ o failed/.
+type HotFolderConfig struct {
+	Dir      string `json:"dir"`       // Directorio vigilado; vacío lo desactiva
+	Printer  string `json:"printer"`   // Impresora o grupo para todos los tickets; vacío usa las rutas
+	Template string `json:"template"`  // Plantilla de los tickets; vacío usa la de la impresora
+	PollMs   int    `json:"poll_ms"`   // Intervalo de revisión en milisegundos (1000 por defecto)
+	StableMs int    `json:"stable_ms"` // Tiempo sin cambios antes de leer un archivo en milisegundos (1000 por defecto)
+}
+
+// ValidationConfig decide qué pasa con los tickets inconsistentes: campos
+// obligatorios vacíos, sin conceptos, totales o cambio que no cuadran, RFC
+// inválidos o impuestos mal calculados
+type ValidationConfig struct {
+	// Mode es reject (rechaza los tickets con errores), warn (los imprime con
+	// un aviso, por defecto) o silent (los imprime sin aviso)
+	Mode string `json:"mode"`
+}
+
+// QueueConfig configura la cola persistente de trabajos de impresión
+type QueueConfig struct {
+	Dir              string `json:"dir"`                // Directorio del log de trabajos
+	MaxAttempts      int    `json:"max_attempts"`       // Intentos antes de pasar a dead-letter
+	BackoffInitialMs int    `json:"backoff_initial_ms"` // Espera tras el primer fallo en milisegundos
+	BackoffMaxMs     int    `json:"backoff_max_ms"`     // Espera máxima entre reintentos en milisegundos
+	DedupWindowMs    int    `json:"dedup_window_ms"`    // Ventana de deduplicación de envíos en milisegundos
+	RetentionHours   int    `json:"retention_hours"`    // Horas que se conservan los trabajos done y dead
+}
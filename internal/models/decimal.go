@@ -0,0 +1,186 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DecimalPlaces son los decimales que conserva Decimal, los máximos que admite
+// el SAT en importes, cantidades y tasas del CFDI
+const DecimalPlaces = 6
+
+// decimalScale es 10^DecimalPlaces
+const decimalScale = 1_000_000
+
+// Decimal es una cantidad exacta de punto fijo con DecimalPlaces decimales,
+// guardada como un entero de millonésimas. Se deserializa desde números o
+// cadenas ("96.770000", "38000", "" o null); los decimales de sobra se
+// redondean como lo pide el SAT: la mitad se aleja del cero.
+type Decimal int64
+
+// decimalPattern es un número decimal con exponente opcional de hasta tres
+// cifras. big.Rat acepta además fracciones ("1/3") y hexadecimales ("0x10",
+// "0x1p4"), que no son números JSON.
+var decimalPattern = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d{1,3})?$`)
+
+// ParseDecimal lee un número decimal, con exponente o sin él
+func ParseDecimal(s string) (Decimal, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if !decimalPattern.MatchString(s) {
+		return 0, fmt.Errorf("decimal inválido %q", s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("decimal inválido %q", s)
+	}
+	r.Mul(r, new(big.Rat).SetInt64(decimalScale))
+	n := roundRat(r)
+	if !n.IsInt64() {
+		return 0, fmt.Errorf("decimal fuera de rango %q", s)
+	}
+	return Decimal(n.Int64()), nil
+}
+
+// MustDecimal es ParseDecimal para constantes; un valor inválido provoca pánico
+func MustDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// DecimalFromInt convierte un entero
+func DecimalFromInt(n int64) Decimal {
+	return Decimal(n * decimalScale)
+}
+
+// roundRat redondea r al entero más cercano; la mitad se aleja del cero
+func roundRat(r *big.Rat) *big.Int {
+	num := new(big.Int).Abs(r.Num())
+	q, rem := new(big.Int).QuoRem(num, r.Denom(), new(big.Int))
+	if rem.Lsh(rem, 1).Cmp(r.Denom()) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if r.Sign() < 0 {
+		q.Neg(q)
+	}
+	return q
+}
+
+// Add devuelve d + o
+func (d Decimal) Add(o Decimal) Decimal { return d + o }
+
+// Sub devuelve d - o
+func (d Decimal) Sub(o Decimal) Decimal { return d - o }
+
+// Mul devuelve d * o redondeado a DecimalPlaces decimales. Un producto que no
+// cabe en Decimal se satura al máximo o al mínimo en vez de dar la vuelta, de
+// modo que no pasa por un importe verosímil.
+func (d Decimal) Mul(o Decimal) Decimal {
+	r := new(big.Rat).SetFrac(
+		new(big.Int).Mul(big.NewInt(int64(d)), big.NewInt(int64(o))),
+		big.NewInt(decimalScale),
+	)
+	n := roundRat(r)
+	if !n.IsInt64() {
+		if n.Sign() < 0 {
+			return math.MinInt64
+		}
+		return math.MaxInt64
+	}
+	return Decimal(n.Int64())
+}
+
+// Round redondea a places decimales (0 a DecimalPlaces); la mitad se aleja
+// del cero, como en los importes del CFDI
+func (d Decimal) Round(places int) Decimal {
+	if places >= DecimalPlaces {
+		return d
+	}
+	unit := int64(math.Pow10(DecimalPlaces - max(places, 0)))
+	q, rem := int64(d)/unit, int64(d)%unit
+	if rem < 0 {
+		rem = -rem
+	}
+	if 2*rem >= unit {
+		if d < 0 {
+			q--
+		} else {
+			q++
+		}
+	}
+	return Decimal(q * unit)
+}
+
+// Sign devuelve -1, 0 o 1 según el signo de d
+func (d Decimal) Sign() int {
+	switch {
+	case d < 0:
+		return -1
+	case d > 0:
+		return 1
+	}
+	return 0
+}
+
+// Float64 devuelve el valor aproximado de d
+func (d Decimal) Float64() float64 {
+	return float64(d) / decimalScale
+}
+
+// StringFixed redondea a places decimales y los escribe todos ("48.20")
+func (d Decimal) StringFixed(places int) string {
+	places = min(max(places, 0), DecimalPlaces)
+	r := d.Round(places)
+	sign := ""
+	if r < 0 {
+		sign = "-"
+		r = -r
+	}
+	whole := strconv.FormatInt(int64(r)/decimalScale, 10)
+	if places == 0 {
+		return sign + whole
+	}
+	frac := fmt.Sprintf("%0*d", DecimalPlaces, int64(r)%decimalScale)
+	return sign + whole + "." + frac[:places]
+}
+
+// String escribe d sin ceros de sobra ("3", "0.5", "48.2")
+func (d Decimal) String() string {
+	s := d.StringFixed(DecimalPlaces)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// UnmarshalJSON implementa la interfaz json.Unmarshaler para Decimal
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*d = 0
+		return nil
+	}
+	s := string(data)
+	var strVal string
+	if err := json.Unmarshal(data, &strVal); err == nil {
+		s = strVal
+	}
+	v, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// MarshalJSON escribe d como cadena, igual que la envía el ERP
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
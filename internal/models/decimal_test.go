@@ -0,0 +1,107 @@
+package models
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", "0"},
+		{"38000", "38000"},
+		{"96.770000", "96.77"},
+		{"48.199999999997", "48.2"},
+		{"-0.0000005", "-0.000001"},
+		{"1e3", "1000"},
+		{" 0.5 ", "0.5"},
+		{".5", "0.5"},
+		{"-2.5E-1", "-0.25"},
+	}
+	for _, tt := range tests {
+		d, err := ParseDecimal(tt.in)
+		if err != nil {
+			t.Errorf("ParseDecimal(%q): %v", tt.in, err)
+			continue
+		}
+		if got := d.String(); got != tt.want {
+			t.Errorf("ParseDecimal(%q) = %s; want %s", tt.in, got, tt.want)
+		}
+	}
+	for _, in := range []string{"abc", "1/3", "1e30", "0x10", "0x1p4", "0b101", "1_000", "1e1000000000", "."} {
+		if _, err := ParseDecimal(in); err == nil {
+			t.Errorf("ParseDecimal(%q) no devolvió error", in)
+		}
+	}
+}
+
+func TestDecimalRound(t *testing.T) {
+	tests := []struct {
+		in     string
+		places int
+		want   string
+	}{
+		{"2.345", 2, "2.35"},
+		{"2.344999", 2, "2.34"},
+		{"-2.345", 2, "-2.35"},
+		{"0.5", 0, "1"},
+		{"48.199999999997", 2, "48.20"},
+		{"10", 2, "10.00"},
+	}
+	for _, tt := range tests {
+		if got := MustDecimal(tt.in).StringFixed(tt.places); got != tt.want {
+			t.Errorf("StringFixed(%s, %d) = %s; want %s", tt.in, tt.places, got, tt.want)
+		}
+	}
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	// 0.1 + 0.2 es exacto, a diferencia de float64
+	if got := MustDecimal("0.1").Add(MustDecimal("0.2")); got != MustDecimal("0.3") {
+		t.Errorf("0.1 + 0.2 = %s", got)
+	}
+	if got := MustDecimal("38000").Sub(MustDecimal("37951.8")).String(); got != "48.2" {
+		t.Errorf("38000 - 37951.8 = %s", got)
+	}
+	if got := MustDecimal("0.106667").Mul(DecimalFromInt(100)).String(); got != "10.6667" {
+		t.Errorf("0.106667 * 100 = %s", got)
+	}
+	// El producto se redondea a seis decimales
+	if got := MustDecimal("0.000001").Mul(MustDecimal("0.5")).String(); got != "0.000001" {
+		t.Errorf("0.000001 * 0.5 = %s", got)
+	}
+	// Un producto fuera de rango se satura en vez de dar la vuelta
+	if got := DecimalFromInt(10_000_000).Mul(DecimalFromInt(1_000_000)); got != math.MaxInt64 {
+		t.Errorf("10000000 * 1000000 = %s; want saturado", got)
+	}
+	if got := DecimalFromInt(-10_000_000).Mul(DecimalFromInt(1_000_000)); got != math.MinInt64 {
+		t.Errorf("-10000000 * 1000000 = %s; want saturado", got)
+	}
+}
+
+func TestDecimalJSON(t *testing.T) {
+	var v struct {
+		A, B, C, D Decimal
+	}
+	data := `{"A": "96.770000", "B": 38000, "C": null, "D": ""}`
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.A != MustDecimal("96.77") || v.B != DecimalFromInt(38000) || v.C != 0 || v.D != 0 {
+		t.Errorf("Unmarshal = %+v", v)
+	}
+	if err := json.Unmarshal([]byte(`{"A": "x"}`), &v); err == nil {
+		t.Error("Unmarshal de un decimal inválido no devolvió error")
+	}
+
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"A":"96.77","B":"38000","C":"0","D":"0"}`; string(out) != want {
+		t.Errorf("Marshal = %s; want %s", out, want)
+	}
+}
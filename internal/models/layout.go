@@ -0,0 +1,109 @@
+package models
+
+// Layout describe el acomodo de un recibo como secciones ordenadas. Los textos
+// y condiciones son plantillas de text/template sobre los datos del ticket
+// (.Ticket), la plantilla (.Template), los totales calculados (.Totals) y la
+// reimpresión (.Reprint); en las filas de una tabla .Item es el elemento de la
+// fila.
+type Layout struct {
+	Sections []LayoutSection `json:"sections"` // Secciones en orden de impresión
+}
+
+// LayoutSection agrupa elementos del recibo bajo un nombre
+type LayoutSection struct {
+	Name     string          `json:"name"`            // Nombre de la sección (header, items, footer...)
+	If       string          `json:"if,omitempty"`    // Condición; la sección se omite si es falsa
+	Style    LayoutStyle     `json:"style,omitempty"` // Estilo de todos sus elementos
+	Elements []LayoutElement `json:"elements"`        // Elementos en orden de impresión
+}
+
+// Tipos de LayoutElement
+const (
+	LayoutText  = "text"  // Una línea de texto, simple o formada por Spans
+	LayoutBlock = "block" // Grupo de elementos con condición y estilo comunes
+	LayoutFeed  = "feed"  // Avance de papel
+	LayoutTable = "table" // Tabla con una fila por elemento de Rows
+	LayoutImage = "image" // Imagen desde un archivo
+	LayoutQR    = "qr"    // Código QR
+)
+
+// LayoutStyle es el estilo de texto de un elemento. La justificación se
+// conserva después del elemento, como en la impresora; el énfasis, la fuente y
+// el tamaño se restauran al terminar.
+type LayoutStyle struct {
+	Align string `json:"align,omitempty"` // left, center o right
+	Bold  *bool  `json:"bold,omitempty"`  // Énfasis
+	Font  string `json:"font,omitempty"`  // A o B
+	Size  string `json:"size,omitempty"`  // Ampliación de 1 a 8 ("2") o ancho por alto ("2x1"); admite plantilla
+}
+
+// LayoutElement es un elemento del recibo; Type indica qué campos aplican
+type LayoutElement struct {
+	Type  string      `json:"type"`
+	If    string      `json:"if,omitempty"`    // Condición; el elemento se omite si es falsa
+	Style LayoutStyle `json:"style,omitempty"` // Estilo del elemento
+
+	// text
+	Text  string       `json:"text,omitempty"`  // Texto de la línea
+	Spans []LayoutSpan `json:"spans,omitempty"` // Partes de la línea con estilo propio
+
+	// block
+	Elements []LayoutElement `json:"elements,omitempty"`
+
+	// feed
+	Lines int `json:"lines,omitempty"`
+
+	// table
+	Rows        string         `json:"rows,omitempty"`         // Campo con los elementos de la tabla (ej. ".Ticket.Conceptos")
+	Header      bool           `json:"header,omitempty"`       // Imprimir los títulos de las columnas
+	HeaderStyle LayoutStyle    `json:"header_style,omitempty"` // Estilo de la fila de títulos
+	Columns     []LayoutColumn `json:"columns,omitempty"`
+
+	// image
+	Path   string `json:"path,omitempty"`   // Archivo de la imagen
+	Width  string `json:"width,omitempty"`  // Ancho en puntos; admite plantilla
+	Margin int    `json:"margin,omitempty"` // Líneas de avance antes y después de la imagen
+
+	// qr
+	Data string `json:"data,omitempty"` // Contenido del código; admite plantilla
+	Size int    `json:"size,omitempty"` // Tamaño de la imagen en puntos (256 por defecto)
+}
+
+// LayoutSpan es una parte de una línea de texto
+type LayoutSpan struct {
+	Text  string      `json:"text"`
+	If    string      `json:"if,omitempty"`
+	Style LayoutStyle `json:"style,omitempty"`
+}
+
+// Valores de LayoutColumn.Overflow
+const (
+	OverflowTruncate = "truncate" // Recortar el contenido y terminarlo en "..."
+	OverflowWrap     = "wrap"     // Partir el contenido entre palabras en filas adicionales
+	OverflowScroll   = "scroll"   // Continuar el contenido en líneas de todo el ancho bajo la fila
+)
+
+// LayoutColumn es una columna de una tabla. Los anchos se calculan al imprimir
+// con los caracteres que caben en la línea según el perfil y la fuente: cada
+// columna parte de Width, el espacio sobrante se reparte entre las columnas con
+// Grow y, si la tabla no cabe, las columnas se reducen hasta MinWidth.
+type LayoutColumn struct {
+	Name  string `json:"name"`         // Nombre de la columna, para los mensajes de error
+	Title string `json:"title"`        // Título en la fila de encabezado
+	Text  string `json:"text"`         // Contenido de la celda
+	If    string `json:"if,omitempty"` // Condición; la columna se omite si es falsa
+	Align string `json:"align"`        // left, center o right
+
+	// Overflow indica qué hacer con el contenido que no cabe en la columna
+	// (OverflowTruncate por defecto)
+	Overflow string `json:"overflow,omitempty"`
+
+	Width    int `json:"width,omitempty"`     // Ancho inicial en caracteres; 0 usa el del contenido más largo
+	MinWidth int `json:"min_width,omitempty"` // Ancho mínimo si la tabla no cabe; 0 no permite reducirla
+	MaxWidth int `json:"max_width,omitempty"` // Ancho máximo; 0 no limita
+	Grow     int `json:"grow,omitempty"`      // Proporción del espacio sobrante que toma
+
+	// Padding son caracteres del ancho que no ocupa el contenido partido con
+	// wrap o scroll
+	Padding int `json:"padding,omitempty"`
+}
@@ -2,7 +2,8 @@ package models
 
 // NewTicket representa la estructura de un nuevo ticket a procesar
 type NewTicket struct {
-	Data NewTicketData `json:"data"` // Datos del nuevo ticket
+	Data    NewTicketData `json:"data"`              // Datos del nuevo ticket
+	Printer string        `json:"printer,omitempty"` // Impresora explícita; omite las reglas de ruteo
 }
 
 // NewTicketData contiene los datos para crear un nuevo ticket
@@ -10,45 +10,64 @@ import (
 	"strings"
 )
 
+// allowedJSONDir es el único directorio del que JSONFileToBytes lee archivos
+const allowedJSONDir = "./internal/api/rest"
+
 // JSONFileToBytes lee un archivo JSON y devuelve su contenido como bytes
 func JSONFileToBytes(filepath string) ([]byte, error) {
+	return ReadJSONFile(allowedJSONDir, filepath)
+}
+
+// SafeJSONPath valida que path sea un archivo JSON dentro de dir y devuelve
+// su ruta absoluta. Los enlaces simbólicos se resuelven antes de comparar, de
+// modo que un enlace dentro de dir no puede apuntar fuera de él.
+func SafeJSONPath(dir, path string) (string, error) {
 	// Normalizar la ruta para evitar ataques con ../ y similares
-	filepath = fp.Clean(filepath)
+	path = fp.Clean(path)
 
 	// Validar extensión del archivo
-	if !strings.HasSuffix(strings.ToLower(filepath), ".json") {
-		return nil, fmt.Errorf("solo se permiten archivos JSON")
+	if !strings.HasSuffix(strings.ToLower(path), ".json") {
+		return "", fmt.Errorf("solo se permiten archivos JSON")
 	}
 
-	// Validar que el archivo esté dentro de un directorio permitido
-	allowedDir := "./internal/api/rest"
-	absPath, err := fp.Abs(filepath)
+	absPath, err := fp.Abs(path)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
 	// Resolver enlaces simbólicos si existen
 	absPath, err = fp.EvalSymlinks(absPath)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	absAllowedDir, err := fp.Abs(allowedDir)
+	absDir, err := fp.Abs(dir)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	absAllowedDir, err = fp.EvalSymlinks(absAllowedDir)
+	absDir, err = fp.EvalSymlinks(absDir)
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+
+	// Validar que el archivo esté dentro del directorio permitido
+	rel, err := fp.Rel(absDir, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(fp.Separator)) {
+		return "", fmt.Errorf("acceso denegado al archivo fuera del directorio permitido")
 	}
+	return absPath, nil
+}
 
-	if !strings.HasPrefix(absPath, absAllowedDir) {
-		return nil, fmt.Errorf("acceso denegado al archivo fuera del directorio permitido")
+// ReadJSONFile lee un archivo JSON que debe estar dentro de dir
+func ReadJSONFile(dir, path string) ([]byte, error) {
+	absPath, err := SafeJSONPath(dir, path)
+	if err != nil {
+		return nil, err
 	}
 
 	// Abrir el archivo después de todas las validaciones
-	file, err := os.Open(filepath)
+	file, err := os.Open(absPath)
 	if err != nil {
 		return nil, err
 	}
@@ -17,12 +17,12 @@ type TicketData struct {
 	Anulada       BoolFlex `json:"anulada"`        // Indica si el ticket está anulado
 
 	// Montos del ticket
-	Descuento         float64  `json:"descuento,string"`                        // Monto de descuento aplicado
-	DescuentoNotaCred *float64 `json:"descuento_nota_credito,string,omitempty"` // Descuento por nota de crédito
-	Total             float64  `json:"total,string"`                            // Monto total del ticket
-	Saldo             float64  `json:"saldo,string"`                            // Saldo pendiente
-	Pagado            float64  `json:"pagado,string"`                           // Monto pagado
-	Cambio            float64  `json:"cambio,string"`                           // Cambio entregado al cliente
+	Descuento         Decimal  `json:"descuento"`                        // Monto de descuento aplicado
+	DescuentoNotaCred *Decimal `json:"descuento_nota_credito,omitempty"` // Descuento por nota de crédito
+	Total             Decimal  `json:"total"`                            // Monto total del ticket
+	Saldo             Decimal  `json:"saldo"`                            // Saldo pendiente
+	Pagado            Decimal  `json:"pagado"`                           // Monto pagado
+	Cambio            Decimal  `json:"cambio"`                           // Cambio entregado al cliente
 
 	Cliente
 
@@ -81,10 +81,10 @@ type Sucursal struct {
 type Concepto struct {
 	Clave                 string     `json:"clave"`                   // Código del producto
 	Descripcion           string     `json:"descripcion"`             // Descripción del producto
-	Cantidad              float64    `json:"cantidad,string"`         // Cantidad vendida
+	Cantidad              Decimal    `json:"cantidad"`                // Cantidad vendida
 	Unidad                string     `json:"unidad"`                  // Unidad de medida
-	PrecioVenta           float64    `json:"precio_venta,string"`     // Precio unitario
-	Total                 float64    `json:"total,string"`            // Total por concepto
+	PrecioVenta           Decimal    `json:"precio_venta"`            // Precio unitario
+	Total                 Decimal    `json:"total"`                   // Total por concepto
 	ClaveProductoServicio string     `json:"clave_producto_servicio"` // Clave SAT
 	ClaveUnidadSAT        string     `json:"clave_unidad_sat"`        // Clave de unidad SAT
 	VentaGranel           BoolFlex   `json:"venta_granel"`            // Indica si es venta a granel
@@ -94,31 +94,31 @@ type Concepto struct {
 
 // Impuesto representa un impuesto aplicado a un concepto
 type Impuesto struct {
-	Factor  string  `json:"factor"`         // Tasa o Cuota
-	Base    float64 `json:"base,string"`    // Base del impuesto
-	Importe float64 `json:"importe,string"` // Importe calculado
-	Codigo  string  `json:"impuestos"`      // Código (001, 002, 003) o nombre
-	Tasa    float64 `json:"tasa,string"`    // Tasa aplicada
-	Entidad string  `json:"entidad"`        // Federal o Local
-	Tipo    string  `json:"tipo"`           // T (trasladado) o R (retenido)
+	Factor  string  `json:"factor"`    // Tasa o Cuota
+	Base    Decimal `json:"base"`      // Base del impuesto
+	Importe Decimal `json:"importe"`   // Importe calculado
+	Codigo  string  `json:"impuestos"` // Código (001, 002, 003) o nombre
+	Tasa    Decimal `json:"tasa"`      // Tasa aplicada
+	Entidad string  `json:"entidad"`   // Federal o Local
+	Tipo    string  `json:"tipo"`      // T (trasladado) o R (retenido)
 }
 
 // DocumentoPago representa un documento de pago asociado al ticket
 type DocumentoPago struct {
-	Total      float64  `json:"total,string"`       // Total del documento
-	TipoCambio float64  `json:"tipo_cambio,string"` // Tipo de cambio aplicado
-	Saldo      float64  `json:"saldo,string"`       // Saldo pendiente
-	Nota       string   `json:"nota"`               // Nota adicional
-	Sistema    string   `json:"sistema"`            // Fecha y hora del sistema
-	Anulado    BoolFlex `json:"anulado"`            // Indica si está anulado
-	Cambio     float64  `json:"cambio,string"`      // Cambio entregado
-	FechaPago  string   `json:"fecha_pago"`         // Fecha del pago
-	FormasPago []Pago   `json:"formas_pago"`        // Formas de pago utilizadas
+	Total      Decimal  `json:"total"`       // Total del documento
+	TipoCambio Decimal  `json:"tipo_cambio"` // Tipo de cambio aplicado
+	Saldo      Decimal  `json:"saldo"`       // Saldo pendiente
+	Nota       string   `json:"nota"`        // Nota adicional
+	Sistema    string   `json:"sistema"`     // Fecha y hora del sistema
+	Anulado    BoolFlex `json:"anulado"`     // Indica si está anulado
+	Cambio     Decimal  `json:"cambio"`      // Cambio entregado
+	FechaPago  string   `json:"fecha_pago"`  // Fecha del pago
+	FormasPago []Pago   `json:"formas_pago"` // Formas de pago utilizadas
 }
 
 // FormaPago representa una forma de pago utilizada en el ticket
 type Pago struct {
 	FormaPago     string  `json:"forma_pago"`               // Descripción de la forma de pago
-	Cantidad      float64 `json:"cantidad,string"`          // Cantidad pagada
+	Cantidad      Decimal `json:"cantidad"`                 // Cantidad pagada
 	Identificador string  `json:"forma_pago_identificador"` // ID de la forma de pago
 }
@@ -42,6 +42,10 @@ type TicketTemplateData struct {
 	CambiarReclamacion string `json:"cambiar_reclamacion"` // Texto para reclamaciones
 	CambiarPie         string `json:"cambiar_pie"`         // Texto personalizado de pie
 
+	// Acomodo del recibo; si falta se usa el acomodo por defecto, que respeta
+	// los campos ver_* de la plantilla
+	Layout *Layout `json:"layout,omitempty"`
+
 	// Configuración del logo
 	Logo struct {
 		Path       string `json:"path"`        // Ruta al archivo del logo
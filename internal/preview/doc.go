@@ -0,0 +1,7 @@
+// Package preview dibuja tickets en un bitmap que imita la salida de una
+// impresora térmica, para revisar plantillas sin tener una impresora conectada.
+//
+// Renderer implementa service.Printer: el ancho imprimible sale del DotsPerLine
+// del perfil, las fuentes A y B usan las métricas de celda de la impresora y el
+// énfasis se simula con doble golpe, igual que en el papel.
+package preview
@@ -0,0 +1,78 @@
+package preview
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/AdConDev/pos-printer/types"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/opentype"
+)
+
+// Métricas de celda por defecto (en puntos) de las fuentes ESC/POS
+const (
+	fontAWidth  = 12
+	fontAHeight = 24
+	fontBWidth  = 9
+	fontBHeight = 17
+
+	// lineSpacing es el espacio extra entre líneas (ESC 2 equivale a ~30 puntos en Font A)
+	lineSpacing = 6
+
+	// monoAdvance es el avance de Go Mono en unidades de em
+	monoAdvance = 0.6
+)
+
+// cell describe la celda de un carácter en una fuente
+type cell struct {
+	width  int
+	height int
+}
+
+// scaled devuelve la celda ampliada por s
+func (c cell) scaled(s size) cell {
+	return cell{width: c.width * s.width, height: c.height * s.height}
+}
+
+var (
+	monoOnce sync.Once
+	monoFont *opentype.Font
+	monoErr  error
+)
+
+// cellFor devuelve la celda de la fuente usando el ancho del perfil si lo define
+func cellFor(f types.Font, widths map[string]int) cell {
+	if f == types.FontB {
+		w := widths["FontB"]
+		if w <= 0 {
+			w = fontBWidth
+		}
+		return cell{width: w, height: w * fontBHeight / fontBWidth}
+	}
+	w := widths["FontA"]
+	if w <= 0 {
+		w = fontAWidth
+	}
+	return cell{width: w, height: w * fontAHeight / fontAWidth}
+}
+
+// newFace crea una cara de Go Mono cuyo avance coincide con el ancho de celda.
+// Las caras no son seguras para uso concurrente; cada Renderer tiene las suyas.
+func newFace(c cell) (font.Face, error) {
+	monoOnce.Do(func() {
+		monoFont, monoErr = opentype.Parse(gomono.TTF)
+	})
+	if monoErr != nil {
+		return nil, fmt.Errorf("preview: error al cargar fuente: %w", monoErr)
+	}
+	face, err := opentype.NewFace(monoFont, &opentype.FaceOptions{
+		Size:    float64(c.width) / monoAdvance,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("preview: error al crear fuente de %d puntos: %w", c.width, err)
+	}
+	return face, nil
+}
@@ -0,0 +1,36 @@
+package preview
+
+import (
+	"image"
+
+	"github.com/AdConDev/pos-printer/profile"
+	"pos-daemon.adcon.dev/internal/service"
+)
+
+// RenderTicket construye el ticket sobre un Renderer y devuelve la imagen
+// resultante con las marcas de opts, como service.RenderTicket.
+func RenderTicket(templateData, ticketData []byte, prof *profile.Profile, opts service.TicketOptions) (*image.Gray, error) {
+	r := NewRenderer(prof)
+	if err := service.BuildTicket(r, templateData, ticketData, opts); err != nil {
+		return nil, err
+	}
+	return r.Image()
+}
+
+// RenderText dibuja texto plano acomodado al ancho del perfil
+func RenderText(text string, prof *profile.Profile) (*image.Gray, error) {
+	r := NewRenderer(prof)
+	if err := service.PrintText(r, text); err != nil {
+		return nil, err
+	}
+	return r.Image()
+}
+
+// RenderReceipt dibuja un recibo libre con los datos del ticket
+func RenderReceipt(receipt *service.Receipt, ticketData []byte, prof *profile.Profile) (*image.Gray, error) {
+	r := NewRenderer(prof)
+	if err := receipt.Print(r, ticketData); err != nil {
+		return nil, err
+	}
+	return r.Image()
+}
@@ -0,0 +1,406 @@
+package preview
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+
+	posprinter "github.com/AdConDev/pos-printer"
+	"github.com/AdConDev/pos-printer/imaging"
+	"github.com/AdConDev/pos-printer/profile"
+	"github.com/AdConDev/pos-printer/types"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+	"pos-daemon.adcon.dev/internal/service"
+)
+
+const (
+	// verticalMargin es el papel en blanco arriba y abajo del ticket
+	verticalMargin = 16
+	// defaultDotsPerLine se usa si el perfil no indica el ancho imprimible
+	defaultDotsPerLine = 576
+	// cutDash es la longitud de cada trazo de la línea de corte
+	cutDash = 8
+)
+
+// size es la ampliación de ancho y alto de los caracteres
+type size struct {
+	width, height int
+}
+
+// run es un fragmento de texto con el mismo formato
+type run struct {
+	text string
+	font types.Font
+	bold bool
+	size size
+}
+
+// glyph es un carácter ya asignado a una fila
+type glyph struct {
+	ch   rune
+	font types.Font
+	bold bool
+	size size
+}
+
+// Renderer dibuja en un bitmap lo que imprimiría una impresora térmica.
+// Implementa service.Printer; no es seguro para uso concurrente.
+type Renderer struct {
+	prof      *profile.Profile
+	printable int // Ancho imprimible en puntos (DotsPerLine del perfil original)
+	margin    int // Margen a cada lado hasta el borde del papel
+
+	canvas *image.Gray
+	y      int // Siguiente fila a imprimir
+
+	align types.Alignment
+	font  types.Font
+	bold  bool
+	size  size
+	line  []run // Texto en el buffer, pendiente de un salto de línea
+
+	faces map[int]font.Face
+}
+
+var _ service.Printer = (*Renderer)(nil)
+
+// NewRenderer crea un renderer para el perfil indicado. El perfil se copia,
+// de modo que los ajustes que haga el ticket no afectan al original.
+func NewRenderer(prof *profile.Profile) *Renderer {
+	p := *prof
+	printable := p.DotsPerLine
+	if printable <= 0 {
+		printable = defaultDotsPerLine
+	}
+	margin := 0
+	if paper := int(float64(p.DPI) * p.PaperWidth / 25.4); paper > printable {
+		margin = (paper - printable) / 2
+	}
+	return &Renderer{
+		prof:      &p,
+		printable: printable,
+		margin:    margin,
+		canvas:    image.NewGray(image.Rect(0, 0, printable+2*margin, 0)),
+		y:         verticalMargin,
+		size:      size{1, 1},
+		faces:     make(map[int]font.Face),
+	}
+}
+
+// SetJustification implementa service.Printer
+func (r *Renderer) SetJustification(alignment types.Alignment) error {
+	r.align = alignment
+	return nil
+}
+
+// SetEmphasis implementa service.Printer
+func (r *Renderer) SetEmphasis(on types.EmphasizedMode) error {
+	r.bold = on != types.EmphOff
+	return nil
+}
+
+// SetFont implementa service.Printer
+func (r *Renderer) SetFont(f types.Font) error {
+	r.font = f
+	return nil
+}
+
+// SetTextSize implementa service.Printer. Como GS !, amplía cada punto de los
+// caracteres siguientes width por height veces.
+func (r *Renderer) SetTextSize(width, height int) error {
+	if width < 1 || width > 8 || height < 1 || height > 8 {
+		return fmt.Errorf("preview: tamaño de texto inválido: %dx%d", width, height)
+	}
+	r.size = size{width, height}
+	return nil
+}
+
+// Text agrega texto al buffer; cada '\n' imprime la línea acumulada
+func (r *Renderer) Text(str string) error {
+	start := 0
+	for i, ch := range str {
+		if ch != '\n' {
+			continue
+		}
+		r.buffer(str[start:i])
+		if err := r.flush(); err != nil {
+			return err
+		}
+		start = i + 1
+	}
+	r.buffer(str[start:])
+	return nil
+}
+
+// TextLn imprime el texto seguido de un salto de línea
+func (r *Renderer) TextLn(str string) error {
+	return r.Text(str + "\n")
+}
+
+// Feed imprime el buffer pendiente y avanza el papel n líneas
+func (r *Renderer) Feed(lines int) error {
+	if err := r.flushPending(); err != nil {
+		return err
+	}
+	r.y += lines * r.lineHeight()
+	return nil
+}
+
+// Cut avanza el papel y dibuja la línea de corte
+func (r *Renderer) Cut(_ types.CutMode, lines int) error {
+	if err := r.Feed(lines); err != nil {
+		return err
+	}
+	r.grow(r.y + 1)
+	for x := 0; x < r.canvas.Rect.Dx(); x++ {
+		if (x/cutDash)%2 == 0 {
+			r.canvas.SetGray(x, r.y, color.Gray{Y: 0x80})
+		}
+	}
+	r.y++
+	return nil
+}
+
+// PrintImage dibuja una imagen con las opciones por defecto de la impresora
+func (r *Renderer) PrintImage(img image.Image) error {
+	return r.PrintImageWithOptions(img, posprinter.DefaultPrintImageOptions())
+}
+
+// PrintImageWithOptions dibuja una imagen redimensionada y convertida a blanco y
+// negro igual que lo hace GenericPrinter antes de enviarla como raster
+func (r *Renderer) PrintImageWithOptions(img image.Image, opts posprinter.PrintImageOptions) error {
+	if !r.prof.HasImageSupport() {
+		return fmt.Errorf("preview: el perfil %s no soporta imágenes", r.prof.Model)
+	}
+	if err := r.flushPending(); err != nil {
+		return err
+	}
+
+	resized := imaging.ResizeToWidth(img, opts.Width, r.prof.DotsPerLine)
+	printImg := imaging.NewPrintImage(resized, opts.DitherMode)
+	printImg.Threshold = opts.Threshold
+	if opts.DitherMode != imaging.DitherNone {
+		if err := printImg.ApplyDithering(opts.DitherMode); err != nil {
+			return fmt.Errorf("preview: error al aplicar dithering: %w", err)
+		}
+	}
+
+	// La impresora descarta lo que no cabe en el área imprimible
+	width := min(printImg.Width, r.printable)
+	left := r.margin + r.offset(width)
+	r.grow(r.y + printImg.Height)
+	for y := 0; y < printImg.Height; y++ {
+		for x := 0; x < width; x++ {
+			if printImg.GetPixel(x, y) {
+				r.canvas.SetGray(left+x, r.y+y, color.Gray{})
+			}
+		}
+	}
+	r.y += printImg.Height
+	return nil
+}
+
+// GetProfile implementa service.Printer
+func (r *Renderer) GetProfile() *profile.Profile {
+	return r.prof
+}
+
+// SetProfile implementa service.Printer. El ancho imprimible no cambia: es una
+// característica física de la impresora que se está simulando.
+func (r *Renderer) SetProfile(newProfile *profile.Profile) {
+	r.prof = newProfile
+}
+
+// Image imprime el buffer pendiente y devuelve el ticket dibujado hasta ahora
+func (r *Renderer) Image() (*image.Gray, error) {
+	if err := r.flushPending(); err != nil {
+		return nil, err
+	}
+	bottom := r.y + verticalMargin
+	r.grow(bottom)
+	return r.canvas.SubImage(image.Rect(0, 0, r.canvas.Rect.Dx(), bottom)).(*image.Gray), nil
+}
+
+// WritePNG escribe el ticket dibujado como PNG
+func (r *Renderer) WritePNG(w io.Writer) error {
+	img, err := r.Image()
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+// buffer agrega texto con el formato actual a la línea pendiente
+func (r *Renderer) buffer(text string) {
+	if text == "" {
+		return
+	}
+	r.line = append(r.line, run{text: text, font: r.font, bold: r.bold, size: r.size})
+}
+
+// flushPending imprime la línea pendiente solo si tiene texto
+func (r *Renderer) flushPending() error {
+	if len(r.line) == 0 {
+		return nil
+	}
+	return r.flush()
+}
+
+// flush imprime la línea pendiente. Igual que en la impresora, el texto que no
+// cabe en el ancho imprimible continúa en la fila siguiente.
+func (r *Renderer) flush() error {
+	var rows [][]glyph
+	var row []glyph
+	x := 0
+	for _, rn := range r.line {
+		c := r.cell(rn.font).scaled(rn.size)
+		for _, ch := range rn.text {
+			if ch == '\r' {
+				continue
+			}
+			if x+c.width > r.printable && len(row) > 0 {
+				rows = append(rows, row)
+				row, x = nil, 0
+			}
+			row = append(row, glyph{ch: ch, font: rn.font, bold: rn.bold, size: rn.size})
+			x += c.width
+		}
+	}
+	rows = append(rows, row)
+	r.line = r.line[:0]
+
+	for _, row := range rows {
+		if err := r.drawRow(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drawRow dibuja una fila alineada según la justificación actual
+func (r *Renderer) drawRow(row []glyph) error {
+	width, height := 0, r.cell(r.font).height
+	for i, g := range row {
+		c := r.cell(g.font).scaled(g.size)
+		width += c.width
+		if i == 0 || c.height > height {
+			height = c.height
+		}
+	}
+	r.grow(r.y + height + lineSpacing)
+
+	x := r.margin + r.offset(width)
+	for _, g := range row {
+		base := r.cell(g.font)
+		c := base.scaled(g.size)
+		face, err := r.face(base)
+		if err != nil {
+			return err
+		}
+		// Las celdas de distinta altura comparten la línea base inferior
+		top := r.y + (height - c.height)
+		if g.size == (size{1, 1}) {
+			r.drawCell(r.canvas, face, base, x, top, g)
+		} else {
+			r.drawScaled(face, base, x, top, g)
+		}
+		x += c.width
+	}
+	r.y += height + lineSpacing
+	return nil
+}
+
+// drawCell dibuja un carácter sin ampliar en la celda c con esquina superior
+// izquierda en (x, top)
+func (r *Renderer) drawCell(dst draw.Image, face font.Face, c cell, x, top int, g glyph) {
+	m := face.Metrics()
+	ascent, descent := m.Ascent.Round(), m.Descent.Round()
+	baseline := top + (c.height+ascent-descent)/2
+	drawGlyph(dst, face, x, baseline, g.ch)
+	if g.bold {
+		// El énfasis de la impresora es un doble golpe desplazado un punto
+		drawGlyph(dst, face, x+1, baseline, g.ch)
+	}
+}
+
+// drawScaled dibuja un carácter ampliado: igual que la impresora, cada punto
+// del carácter normal se vuelve un bloque de size.width por size.height
+func (r *Renderer) drawScaled(face font.Face, c cell, x, top int, g glyph) {
+	small := image.NewGray(image.Rect(0, 0, c.width+1, c.height))
+	draw.Draw(small, small.Rect, image.White, image.Point{}, draw.Src)
+	r.drawCell(small, face, c, 0, 0, g)
+
+	w, h := g.size.width, g.size.height
+	for sy := 0; sy < c.height; sy++ {
+		for sx := 0; sx < c.width+1; sx++ {
+			// La impresora no tiene grises: cada punto se imprime o no
+			if small.GrayAt(sx, sy).Y >= 0x80 {
+				continue
+			}
+			block := image.Rect(x+sx*w, top+sy*h, x+(sx+1)*w, top+(sy+1)*h)
+			draw.Draw(r.canvas, block, image.Black, image.Point{}, draw.Src)
+		}
+	}
+}
+
+// drawGlyph dibuja un carácter con su origen en (x, baseline)
+func drawGlyph(dst draw.Image, face font.Face, x, baseline int, ch rune) {
+	dr, mask, maskp, _, ok := face.Glyph(fixed.P(x, baseline), ch)
+	if !ok {
+		dr, mask, maskp, _, ok = face.Glyph(fixed.P(x, baseline), '?')
+		if !ok {
+			return
+		}
+	}
+	draw.DrawMask(dst, dr, image.Black, image.Point{}, mask, maskp, draw.Over)
+}
+
+// offset calcula el desplazamiento de un bloque de ancho width según la justificación
+func (r *Renderer) offset(width int) int {
+	switch r.align {
+	case types.AlignCenter:
+		return max(0, (r.printable-width)/2)
+	case types.AlignRight:
+		return max(0, r.printable-width)
+	}
+	return 0
+}
+
+// cell devuelve la celda de la fuente según el perfil actual
+func (r *Renderer) cell(f types.Font) cell {
+	return cellFor(f, r.prof.Fonts)
+}
+
+// lineHeight es el avance de una línea con la fuente actual
+func (r *Renderer) lineHeight() int {
+	return r.cell(r.font).height + lineSpacing
+}
+
+// face devuelve la cara para la celda, creándola la primera vez
+func (r *Renderer) face(c cell) (font.Face, error) {
+	if face, ok := r.faces[c.width]; ok {
+		return face, nil
+	}
+	face, err := newFace(c)
+	if err != nil {
+		return nil, err
+	}
+	r.faces[c.width] = face
+	return face, nil
+}
+
+// grow amplía el lienzo en blanco hasta al menos height filas
+func (r *Renderer) grow(height int) {
+	if height <= r.canvas.Rect.Dy() {
+		return
+	}
+	size := max(height, 2*r.canvas.Rect.Dy())
+	canvas := image.NewGray(image.Rect(0, 0, r.canvas.Rect.Dx(), size))
+	draw.Draw(canvas, canvas.Rect, image.White, image.Point{}, draw.Src)
+	draw.Draw(canvas, r.canvas.Rect, r.canvas, image.Point{}, draw.Src)
+	r.canvas = canvas
+}
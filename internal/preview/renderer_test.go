@@ -0,0 +1,157 @@
+package preview
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AdConDev/pos-printer/profile"
+	"github.com/AdConDev/pos-printer/types"
+	"pos-daemon.adcon.dev/internal/service"
+)
+
+// inkBounds devuelve el rectángulo que contiene todos los puntos negros de las filas [y0, y1)
+func inkBounds(img *image.Gray, y0, y1 int) image.Rectangle {
+	var out image.Rectangle
+	for y := y0; y < y1; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			if img.GrayAt(x, y).Y < 0x80 {
+				out = out.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return out
+}
+
+func TestRendererAlignment(t *testing.T) {
+	prof := profile.CreateProfile80mm()
+	r := NewRenderer(prof)
+
+	steps := []func() error{
+		func() error { return r.SetJustification(types.AlignLeft) },
+		func() error { return r.TextLn("IZQ") },
+		func() error { return r.SetJustification(types.AlignCenter) },
+		func() error { return r.TextLn("CENTRO") },
+		func() error { return r.SetJustification(types.AlignRight) },
+		func() error { return r.TextLn("DER") },
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	img, err := r.Image()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	row := fontAHeight + lineSpacing
+	left := inkBounds(img, verticalMargin, verticalMargin+row)
+	center := inkBounds(img, verticalMargin+row, verticalMargin+2*row)
+	right := inkBounds(img, verticalMargin+2*row, verticalMargin+3*row)
+
+	if left.Min.X < r.margin || left.Min.X > r.margin+fontAWidth {
+		t.Errorf("texto a la izquierda empieza en %d, margen %d", left.Min.X, r.margin)
+	}
+	mid := r.margin + r.printable/2
+	if c := (center.Min.X + center.Max.X) / 2; c < mid-fontAWidth || c > mid+fontAWidth {
+		t.Errorf("texto centrado en %d, se esperaba cerca de %d", c, mid)
+	}
+	if end := r.margin + r.printable; right.Max.X > end || right.Max.X < end-fontAWidth {
+		t.Errorf("texto a la derecha termina en %d, se esperaba cerca de %d", right.Max.X, end)
+	}
+}
+
+func TestRendererWrapsLongLines(t *testing.T) {
+	prof := profile.CreateProfile58mm()
+	r := NewRenderer(prof)
+
+	perLine := r.printable / cellFor(types.FontA, prof.Fonts).width
+	long := bytes.Repeat([]byte("X"), perLine+1)
+	if err := r.TextLn(string(long)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := r.y, verticalMargin+2*(fontAHeight+lineSpacing); got != want {
+		t.Errorf("una línea de %d caracteres en %d columnas avanzó a y=%d, se esperaba %d", len(long), perLine, got, want)
+	}
+}
+
+func TestRendererTextSize(t *testing.T) {
+	r := NewRenderer(profile.CreateProfile80mm())
+	if err := r.TextLn("HOLA"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetTextSize(2, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.TextLn("HOLA"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetTextSize(9, 1); err == nil {
+		t.Error("se aceptó una ampliación de 9")
+	}
+	img, err := r.Image()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	row := fontAHeight + lineSpacing
+	normal := inkBounds(img, verticalMargin, verticalMargin+row)
+	big := inkBounds(img, verticalMargin+row, verticalMargin+row+3*fontAHeight+lineSpacing)
+	if w := big.Dx(); w < 2*normal.Dx()-2 || w > 2*normal.Dx()+2 {
+		t.Errorf("ancho ampliado = %d, normal = %d", w, normal.Dx())
+	}
+	if h := big.Dy(); h < 3*normal.Dy()-3 || h > 3*normal.Dy()+3 {
+		t.Errorf("alto ampliado = %d, normal = %d", h, normal.Dy())
+	}
+	if got, want := r.y, verticalMargin+row+3*fontAHeight+lineSpacing; got != want {
+		t.Errorf("y = %d; want %d", got, want)
+	}
+}
+
+func TestRenderTicket(t *testing.T) {
+	const fixtures = "../api/rest"
+
+	tests := []struct {
+		name     string
+		template string
+		profile  func() *profile.Profile
+	}{
+		{"80mm", "new_ticket_template.json", profile.CreateProfile80mm},
+		{"58mm", "ticket_template_58mm.json", profile.CreateProfile58mm},
+	}
+	ticketData, err := os.ReadFile(filepath.Join(fixtures, "new_ticket.json"))
+	if err != nil {
+		t.Fatalf("error al leer ticket: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templateData, err := os.ReadFile(filepath.Join(fixtures, tt.template))
+			if err != nil {
+				t.Fatalf("error al leer plantilla: %v", err)
+			}
+			prof := tt.profile()
+			img, err := RenderTicket(templateData, ticketData, prof, service.TicketOptions{})
+			if err != nil {
+				t.Fatalf("RenderTicket: %v", err)
+			}
+
+			paper := int(float64(prof.DPI) * prof.PaperWidth / 25.4)
+			if img.Rect.Dx() < prof.DotsPerLine || img.Rect.Dx() > paper {
+				t.Errorf("ancho %d fuera del rango [%d, %d]", img.Rect.Dx(), prof.DotsPerLine, paper)
+			}
+			if ink := inkBounds(img, 0, img.Rect.Dy()); ink.Empty() {
+				t.Fatal("la imagen no tiene nada impreso")
+			}
+
+			if err := png.Encode(io.Discard, img); err != nil {
+				t.Fatalf("png.Encode: %v", err)
+			}
+		})
+	}
+}
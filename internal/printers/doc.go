@@ -0,0 +1,5 @@
+// Package printers mantiene las impresoras configuradas del daemon y elige a
+// cuál se envía cada ticket según reglas sobre la sucursal, la tienda o el tipo
+// de operación, o según la impresora indicada explícitamente en la petición.
+// Un grupo nombra una impresora principal y sus respaldos en orden.
+package printers
@@ -0,0 +1,297 @@
+package printers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AdConDev/pos-printer/profile"
+	"pos-daemon.adcon.dev/internal/connector"
+	"pos-daemon.adcon.dev/internal/models"
+)
+
+var (
+	// ErrNoRoute se devuelve cuando ninguna regla coincide con el ticket
+	ErrNoRoute = errors.New("printers: ninguna ruta coincide con el ticket")
+	// ErrUnknownPrinter se devuelve cuando se pide una impresora o grupo no configurado
+	ErrUnknownPrinter = errors.New("printers: impresora no configurada")
+)
+
+// DefaultPoll es el intervalo entre consultas de estado si la configuración no
+// indica otro
+const DefaultPoll = 5 * time.Second
+
+// Perfiles de papel soportados
+const (
+	Profile58mm = "58mm"
+	Profile80mm = "80mm"
+)
+
+// Printer es una impresora configurada
+type Printer struct {
+	Name            string
+	Profile         string // 58mm u 80mm
+	Connector       connector.Options
+	DefaultTemplate string // Plantilla si la petición no indica una (vacío = la global)
+
+	Poll time.Duration // Intervalo entre consultas de estado (0 = no se consulta)
+	ASB  bool          // Consultar el estado con Automatic Status Back en lugar de DLE EOT
+}
+
+// NewProfile crea un perfil nuevo para la impresora. Se crea uno por trabajo
+// porque la impresión del ticket lo ajusta al ancho de la plantilla.
+func (p *Printer) NewProfile() *profile.Profile {
+	var prof *profile.Profile
+	if p.Profile == Profile58mm {
+		prof = profile.CreateProfile58mm()
+	} else {
+		prof = profile.CreateProfile80mm()
+	}
+	prof.Model = p.Name
+	prof.Vendor = "Generic"
+	return prof
+}
+
+// DetectProfile deduce el perfil por el nombre de la impresora: 58mm si lo
+// menciona, 80mm en cualquier otro caso
+func DetectProfile(name string) string {
+	if strings.Contains(strings.ToLower(name), Profile58mm) {
+		return Profile58mm
+	}
+	return Profile80mm
+}
+
+// Group es una impresora principal seguida de sus respaldos, en orden
+type Group struct {
+	Name     string
+	Printers []string
+}
+
+// Destination es el destino de un ticket: una impresora suelta o un grupo.
+// Printers empieza por la principal; las demás se usan si las anteriores fallan.
+type Destination struct {
+	Name     string
+	Printers []*Printer
+}
+
+// Primary devuelve la impresora principal del destino
+func (d *Destination) Primary() *Printer {
+	return d.Printers[0]
+}
+
+// Rule elige una impresora o grupo cuando los campos no vacíos coinciden con el ticket
+type Rule struct {
+	Sucursal       string
+	SucursalTienda string
+	TipoOperacion  string
+	Printer        string // Impresora o grupo
+}
+
+// Target son los datos del ticket que se usan para elegir impresora
+type Target struct {
+	Printer        string // Impresora o grupo explícito; si no está vacío se ignoran las reglas
+	Sucursal       string
+	SucursalTienda string
+	TipoOperacion  string
+}
+
+// TargetFromTicket extrae los datos de ruteo de un ticket
+func TargetFromTicket(ticket *models.NewTicket) Target {
+	return Target{
+		Printer:        ticket.Printer,
+		Sucursal:       ticket.Data.Sucursal,
+		SucursalTienda: ticket.Data.SucursalTienda,
+		TipoOperacion:  ticket.Data.TipoOperacion,
+	}
+}
+
+// matches indica si la regla aplica al ticket
+func (r Rule) matches(t Target) bool {
+	return field(r.Sucursal, t.Sucursal) &&
+		field(r.SucursalTienda, t.SucursalTienda) &&
+		field(r.TipoOperacion, t.TipoOperacion)
+}
+
+// field compara un campo de regla; vacío coincide con cualquier valor
+func field(rule, value string) bool {
+	return rule == "" || strings.EqualFold(strings.TrimSpace(rule), strings.TrimSpace(value))
+}
+
+// Registry contiene las impresoras configuradas, sus grupos y las reglas de ruteo
+type Registry struct {
+	printers map[string]*Printer
+	order    []string
+	groups   map[string]*Destination
+	rules    []Rule
+}
+
+// New valida las impresoras, grupos y reglas. Las reglas se evalúan en orden y
+// gana la primera que coincide.
+func New(printers []Printer, groups []Group, rules []Rule) (*Registry, error) {
+	if len(printers) == 0 {
+		return nil, fmt.Errorf("printers: no hay impresoras configuradas")
+	}
+	r := &Registry{
+		printers: make(map[string]*Printer),
+		groups:   make(map[string]*Destination),
+		rules:    rules,
+	}
+	for i := range printers {
+		p := printers[i]
+		if p.Name == "" {
+			return nil, fmt.Errorf("printers: la impresora %d no tiene nombre", i+1)
+		}
+		if _, dup := r.printers[p.Name]; dup {
+			return nil, fmt.Errorf("printers: impresora %q duplicada", p.Name)
+		}
+		if p.Profile == "" {
+			p.Profile = DetectProfile(p.Name)
+		}
+		if p.Profile != Profile58mm && p.Profile != Profile80mm {
+			return nil, fmt.Errorf("printers: perfil %q de la impresora %q no soportado", p.Profile, p.Name)
+		}
+		r.printers[p.Name] = &p
+		r.order = append(r.order, p.Name)
+	}
+	for _, g := range groups {
+		if err := r.addGroup(g); err != nil {
+			return nil, err
+		}
+	}
+	for i, rule := range rules {
+		if _, err := r.Resolve(rule.Printer); err != nil {
+			return nil, fmt.Errorf("printers: la ruta %d apunta a %q, que no es una impresora ni un grupo configurado", i+1, rule.Printer)
+		}
+	}
+	return r, nil
+}
+
+// addGroup valida y registra un grupo de impresoras
+func (r *Registry) addGroup(g Group) error {
+	if g.Name == "" {
+		return fmt.Errorf("printers: hay un grupo sin nombre")
+	}
+	if _, dup := r.printers[g.Name]; dup {
+		return fmt.Errorf("printers: el grupo %q tiene el nombre de una impresora", g.Name)
+	}
+	if _, dup := r.groups[g.Name]; dup {
+		return fmt.Errorf("printers: grupo %q duplicado", g.Name)
+	}
+	if len(g.Printers) == 0 {
+		return fmt.Errorf("printers: el grupo %q no tiene impresoras", g.Name)
+	}
+	dest := &Destination{Name: g.Name}
+	seen := make(map[string]bool)
+	for _, name := range g.Printers {
+		p, ok := r.printers[name]
+		if !ok {
+			return fmt.Errorf("printers: el grupo %q incluye la impresora %q, que no está configurada", g.Name, name)
+		}
+		if seen[name] {
+			return fmt.Errorf("printers: el grupo %q repite la impresora %q", g.Name, name)
+		}
+		seen[name] = true
+		dest.Printers = append(dest.Printers, p)
+	}
+	r.groups[g.Name] = dest
+	return nil
+}
+
+// FromConfig construye el registro desde la configuración. Sin lista de
+// impresoras, Printer/Connector se usan como única impresora con una ruta
+// que atrapa todos los tickets, como antes de existir el ruteo.
+func FromConfig(cfg *models.ConfigData) (*Registry, error) {
+	if len(cfg.Printers) == 0 {
+		if cfg.Printer == "" {
+			return nil, fmt.Errorf("printers: no hay impresoras configuradas")
+		}
+		legacy := Printer{
+			Name:      cfg.Printer,
+			Connector: connector.OptionsFromConfig(cfg),
+			ASB:       cfg.Status.ASB,
+		}
+		legacy.Poll = pollInterval(cfg.Status, legacy.Connector)
+		return New([]Printer{legacy}, nil, []Rule{{Printer: cfg.Printer}})
+	}
+
+	printers := make([]Printer, 0, len(cfg.Printers))
+	for _, pc := range cfg.Printers {
+		opts := connector.OptionsFor(cfg, pc.Name, pc.Connector)
+		printers = append(printers, Printer{
+			Name:            pc.Name,
+			Profile:         pc.Profile,
+			Connector:       opts,
+			DefaultTemplate: pc.DefaultTemplate,
+			Poll:            pollInterval(pc.Status, opts),
+			ASB:             pc.Status.ASB,
+		})
+	}
+	groups := make([]Group, 0, len(cfg.Groups))
+	for _, gc := range cfg.Groups {
+		groups = append(groups, Group(gc))
+	}
+	rules := make([]Rule, 0, len(cfg.Routes))
+	for _, rc := range cfg.Routes {
+		rules = append(rules, Rule(rc))
+	}
+	return New(printers, groups, rules)
+}
+
+// pollInterval decide cada cuánto se consulta el estado de una impresora. Las
+// conexiones de solo escritura no pueden consultarse.
+func pollInterval(c models.StatusConfig, opts connector.Options) time.Duration {
+	if c.Disabled || !opts.Bidirectional() {
+		return 0
+	}
+	if c.PollMs > 0 {
+		return time.Duration(c.PollMs) * time.Millisecond
+	}
+	return DefaultPoll
+}
+
+// Route elige el destino del ticket: el explícito si se indicó, o el de la
+// primera regla que coincide. Nunca cae en una impresora por defecto.
+func (r *Registry) Route(t Target) (*Destination, error) {
+	if t.Printer != "" {
+		return r.Resolve(t.Printer)
+	}
+	for _, rule := range r.rules {
+		if rule.matches(t) {
+			return r.Resolve(rule.Printer)
+		}
+	}
+	return nil, fmt.Errorf("%w (sucursal %q, tienda %q, tipo de operación %q)",
+		ErrNoRoute, t.Sucursal, t.SucursalTienda, t.TipoOperacion)
+}
+
+// Resolve devuelve el destino con el nombre indicado: un grupo, o una impresora
+// suelta sin respaldos
+func (r *Registry) Resolve(name string) (*Destination, error) {
+	if g, ok := r.groups[name]; ok {
+		return g, nil
+	}
+	p, err := r.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return &Destination{Name: p.Name, Printers: []*Printer{p}}, nil
+}
+
+// Get devuelve la impresora con el nombre indicado
+func (r *Registry) Get(name string) (*Printer, error) {
+	p, ok := r.printers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPrinter, name)
+	}
+	return p, nil
+}
+
+// List devuelve las impresoras en el orden de la configuración
+func (r *Registry) List() []*Printer {
+	out := make([]*Printer, 0, len(r.order))
+	for _, name := range r.order {
+		out = append(out, r.printers[name])
+	}
+	return out
+}
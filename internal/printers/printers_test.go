@@ -0,0 +1,170 @@
+package printers
+
+import (
+	"errors"
+	"testing"
+
+	"pos-daemon.adcon.dev/internal/connector"
+	"pos-daemon.adcon.dev/internal/models"
+)
+
+func newTestRegistry(t *testing.T) *Registry {
+	t.Helper()
+	r, err := New([]Printer{
+		{Name: "mostrador", Profile: Profile80mm},
+		{Name: "movil 58mm"},
+		{Name: "cocina", DefaultTemplate: "comanda"},
+	}, []Group{
+		{Name: "caja", Printers: []string{"mostrador", "movil 58mm"}},
+	}, []Rule{
+		{TipoOperacion: "pedido", Printer: "cocina"},
+		{TipoOperacion: "factura", Printer: "caja"},
+		{Sucursal: "S0002", Printer: "movil 58mm"},
+		{Sucursal: "S0001", SucursalTienda: "Almacen Principal", Printer: "mostrador"},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return r
+}
+
+func TestRoute(t *testing.T) {
+	r := newTestRegistry(t)
+
+	tests := []struct {
+		name   string
+		target Target
+		want   string
+	}{
+		{"Tipo de operación", Target{Sucursal: "S0001", TipoOperacion: "PEDIDO"}, "cocina"},
+		{"Sucursal", Target{Sucursal: "S0002", TipoOperacion: "venta"}, "movil 58mm"},
+		{"Sucursal y tienda", Target{Sucursal: "S0001", SucursalTienda: " almacen principal "}, "mostrador"},
+		{"Explícita", Target{Printer: "cocina", Sucursal: "S0002"}, "cocina"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := r.Route(tt.target)
+			if err != nil {
+				t.Fatalf("Route: %v", err)
+			}
+			if p.Name != tt.want || len(p.Printers) != 1 || p.Primary().Name != tt.want {
+				t.Errorf("Route = %q %v; want %q", p.Name, p.Printers, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteGroup(t *testing.T) {
+	r := newTestRegistry(t)
+
+	for _, target := range []Target{{TipoOperacion: "FACTURA"}, {Printer: "caja"}} {
+		dest, err := r.Route(target)
+		if err != nil {
+			t.Fatalf("Route(%+v): %v", target, err)
+		}
+		var names []string
+		for _, p := range dest.Printers {
+			names = append(names, p.Name)
+		}
+		if dest.Name != "caja" || len(names) != 2 || names[0] != "mostrador" || names[1] != "movil 58mm" {
+			t.Errorf("Route(%+v) = %q %v; want caja [mostrador movil 58mm]", target, dest.Name, names)
+		}
+	}
+}
+
+func TestRouteErrors(t *testing.T) {
+	r := newTestRegistry(t)
+
+	if _, err := r.Route(Target{Sucursal: "S0001", SucursalTienda: "Otra"}); !errors.Is(err, ErrNoRoute) {
+		t.Errorf("Route sin regla = %v; want ErrNoRoute", err)
+	}
+	if _, err := r.Route(Target{Printer: "bodega", Sucursal: "S0002"}); !errors.Is(err, ErrUnknownPrinter) {
+		t.Errorf("Route con impresora desconocida = %v; want ErrUnknownPrinter", err)
+	}
+}
+
+func TestNewProfile(t *testing.T) {
+	r := newTestRegistry(t)
+
+	p, err := r.Get("movil 58mm")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if p.Profile != Profile58mm {
+		t.Errorf("Profile = %q; want %q", p.Profile, Profile58mm)
+	}
+	if prof := p.NewProfile(); prof.PaperWidth != 58 || prof.Model != "movil 58mm" {
+		t.Errorf("NewProfile = %.0fmm %q", prof.PaperWidth, prof.Model)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		printers []Printer
+		groups   []Group
+		rules    []Rule
+	}{
+		{"Sin impresoras", nil, nil, nil},
+		{"Sin nombre", []Printer{{}}, nil, nil},
+		{"Duplicada", []Printer{{Name: "a"}, {Name: "a"}}, nil, nil},
+		{"Perfil inválido", []Printer{{Name: "a", Profile: "110mm"}}, nil, nil},
+		{"Ruta a impresora inexistente", []Printer{{Name: "a"}}, nil, []Rule{{Printer: "b"}}},
+		{"Grupo vacío", []Printer{{Name: "a"}}, []Group{{Name: "g"}}, nil},
+		{"Grupo con nombre de impresora", []Printer{{Name: "a"}}, []Group{{Name: "a", Printers: []string{"a"}}}, nil},
+		{"Grupo con impresora inexistente", []Printer{{Name: "a"}}, []Group{{Name: "g", Printers: []string{"a", "b"}}}, nil},
+		{"Grupo con impresora repetida", []Printer{{Name: "a"}}, []Group{{Name: "g", Printers: []string{"a", "a"}}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.printers, tt.groups, tt.rules); err == nil {
+				t.Error("New no devolvió error")
+			}
+		})
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	t.Run("Impresora única", func(t *testing.T) {
+		r, err := FromConfig(&models.ConfigData{Printer: "POS-58mm"})
+		if err != nil {
+			t.Fatalf("FromConfig: %v", err)
+		}
+		dest, err := r.Route(Target{Sucursal: "cualquiera"})
+		if err != nil {
+			t.Fatalf("Route: %v", err)
+		}
+		p := dest.Primary()
+		if p.Name != "POS-58mm" || p.Profile != Profile58mm || p.Connector.Printer != "POS-58mm" {
+			t.Errorf("Route = %+v", p)
+		}
+	})
+
+	t.Run("Lista de impresoras", func(t *testing.T) {
+		r, err := FromConfig(&models.ConfigData{
+			Printer: "ignorada",
+			Printers: []models.PrinterConfig{
+				{Name: "mostrador", Connector: models.ConnectorConfig{Type: "tcp", Address: "192.168.1.50"}},
+				{Name: "cocina", Profile: Profile58mm},
+			},
+			Groups: []models.GroupConfig{{Name: "caja", Printers: []string{"mostrador", "cocina"}}},
+			Routes: []models.RouteConfig{{TipoOperacion: "pedido", Printer: "caja"}},
+		})
+		if err != nil {
+			t.Fatalf("FromConfig: %v", err)
+		}
+		if _, err := r.Route(Target{TipoOperacion: "venta"}); !errors.Is(err, ErrNoRoute) {
+			t.Errorf("Route sin regla = %v; want ErrNoRoute", err)
+		}
+		if dest, err := r.Route(Target{TipoOperacion: "pedido"}); err != nil || len(dest.Printers) != 2 {
+			t.Errorf("Route al grupo = %v, %v", dest, err)
+		}
+		p, err := r.Get("mostrador")
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if p.Connector.Type != connector.TypeTCP || p.Connector.Address != "192.168.1.50" {
+			t.Errorf("Connector = %s", p.Connector)
+		}
+	})
+}
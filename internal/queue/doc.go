@@ -0,0 +1,3 @@
+// Package queue implementa la cola persistente de trabajos de impresión con
+// entrega al menos una vez, reintentos con backoff y estado dead-letter.
+package queue
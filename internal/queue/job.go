@@ -0,0 +1,110 @@
+package queue
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// State representa el estado de un trabajo de impresión
+type State string
+
+const (
+	StateQueued   State = "queued"   // En espera de ser impreso
+	StatePrinting State = "printing" // Enviándose a la impresora
+	StateDone     State = "done"     // Impreso correctamente
+	StateFailed   State = "failed"   // Falló, se reintentará tras el backoff
+	StateDead     State = "dead"     // Agotó los reintentos (dead-letter)
+)
+
+// Pending indica si el trabajo aún debe procesarse
+func (s State) Pending() bool {
+	return s == StateQueued || s == StatePrinting || s == StateFailed
+}
+
+// Kind distingue cómo se construyen los bytes de un trabajo
+type Kind string
+
+const (
+	KindTicket  Kind = "ticket"  // Ticket JSON que se arma con la plantilla
+	KindRaw     Kind = "raw"     // Bytes ESC/POS recibidos tal cual, por ejemplo por el puerto 9100
+	KindText    Kind = "text"    // Texto plano que se acomoda al ancho de cada impresora
+	KindReceipt Kind = "receipt" // Ticket JSON que se imprime con un recibo libre
+)
+
+// TicketRef identifica el ticket de un trabajo para que los clientes puedan correlacionarlo
+type TicketRef struct {
+	Identificador string `json:"identificador,omitempty"`
+	Serie         string `json:"serie,omitempty"`
+	Folio         string `json:"folio,omitempty"`
+}
+
+// Job es un trabajo de impresión persistido en la cola
+type Job struct {
+	ID       string          `json:"id"`
+	State    State           `json:"state"`
+	Kind     Kind            `json:"kind,omitempty"`     // Vacío en trabajos anteriores a los trabajos raw: ticket
+	Ticket   json.RawMessage `json:"ticket,omitempty"`   // JSON original del ticket
+	Template json.RawMessage `json:"template,omitempty"` // JSON de la plantilla resuelta al encolar
+	Raw      []byte          `json:"raw,omitempty"`      // Bytes ESC/POS de un trabajo raw
+	Text     string          `json:"text,omitempty"`     // Texto de un trabajo text
+	Receipt  string          `json:"receipt,omitempty"`  // Nombre del recibo libre de un trabajo receipt
+	TicketRef
+
+	// Printer es la impresora o grupo destino elegido al encolar (vacío sin ruteo)
+	Printer string `json:"printer,omitempty"`
+	// PrintedBy es la impresora que finalmente imprimió el trabajo; difiere de
+	// Printer si el ticket se reimprimió en un respaldo del grupo
+	PrintedBy string `json:"printed_by,omitempty"`
+
+	// IdempotencyKey agrupa envíos repetidos del mismo ticket (vacío en reimpresiones)
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+
+	// Reprint está presente si el trabajo es una reimpresión de un ticket ya impreso
+	Reprint *Reprint `json:"reprint,omitempty"`
+	// Warnings son las observaciones de la validación que se imprimen en un
+	// aviso al inicio del ticket
+	Warnings []string `json:"warnings,omitempty"`
+
+	Attempts      int       `json:"attempts"`                  // Intentos de impresión realizados
+	LastError     string    `json:"last_error,omitempty"`      // Último error registrado
+	CreatedAt     time.Time `json:"created_at"`                // Momento en que se encoló
+	UpdatedAt     time.Time `json:"updated_at"`                // Último cambio de estado
+	NextAttemptAt time.Time `json:"next_attempt_at,omitempty"` // Siguiente reintento si State es failed o el trabajo se pospuso
+}
+
+// Reprint describe una reimpresión explícita
+type Reprint struct {
+	Number int       `json:"number"` // Número de reimpresión del ticket (1, 2, ...)
+	At     time.Time `json:"at"`     // Momento en que se solicitó
+}
+
+// IsRaw indica si el trabajo se envía tal cual, sin plantilla
+func (j *Job) IsRaw() bool {
+	return j.Kind == KindRaw
+}
+
+// clone devuelve una copia independiente del trabajo
+func (j *Job) clone() *Job {
+	c := *j
+	if j.Reprint != nil {
+		r := *j.Reprint
+		c.Reprint = &r
+	}
+	c.Warnings = append([]string(nil), j.Warnings...)
+	return &c
+}
+
+// IdempotencyKey deriva la llave de deduplicación de un envío. Tiene prioridad la
+// llave explícita del cliente, luego el Identificador y por último Serie+Folio.
+// Devuelve "" si no hay datos suficientes para deduplicar.
+func IdempotencyKey(explicit string, ref TicketRef) string {
+	switch {
+	case explicit != "":
+		return "key:" + explicit
+	case ref.Identificador != "":
+		return "id:" + ref.Identificador
+	case ref.Serie != "" || ref.Folio != "":
+		return "sf:" + ref.Serie + "/" + ref.Folio
+	}
+	return ""
+}
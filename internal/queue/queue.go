@@ -0,0 +1,538 @@
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNotFound se devuelve cuando un trabajo no existe en la cola
+var ErrNotFound = errors.New("queue: trabajo no encontrado")
+
+// Processor imprime un trabajo. Un error provoca un reintento con backoff,
+// salvo que se marque con Permanent. Si termina sin error, lo que anote en
+// job.PrintedBy queda registrado en el trabajo.
+type Processor func(ctx context.Context, job *Job) error
+
+// permanentError marca un error que no se resuelve reintentando
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent envuelve err para que el trabajo pase directo a dead-letter
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent indica si err fue marcado con Permanent
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// deferredError marca un trabajo que no pudo intentarse, por ejemplo porque
+// la impresora informa que no tiene papel
+type deferredError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *deferredError) Error() string { return e.err.Error() }
+func (e *deferredError) Unwrap() error { return e.err }
+
+// Defer envuelve err para que el trabajo vuelva a la cola y se intente después
+// de after, sin contar el intento ni acercarlo a dead-letter
+func Defer(err error, after time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &deferredError{err: err, after: after}
+}
+
+// Options configura la cola de trabajos
+type Options struct {
+	Dir            string        // Directorio donde se guarda el log de trabajos
+	MaxAttempts    int           // Intentos antes de pasar a dead-letter
+	BackoffInitial time.Duration // Espera tras el primer fallo
+	BackoffMax     time.Duration // Espera máxima entre reintentos
+	DedupWindow    time.Duration // Ventana en la que un envío repetido devuelve el trabajo original
+	// Retention es cuánto se conservan los trabajos done y dead desde su último
+	// cambio; nunca es menor que DedupWindow
+	Retention time.Duration
+
+	// Notify se invoca con una copia del trabajo tras cada cambio de estado
+	Notify func(job *Job)
+}
+
+// Valores por defecto de Options
+const (
+	DefaultMaxAttempts    = 5
+	DefaultBackoffInitial = 2 * time.Second
+	DefaultBackoffMax     = 5 * time.Minute
+	DefaultDedupWindow    = 24 * time.Hour
+	DefaultRetention      = 7 * 24 * time.Hour
+)
+
+// pruneInterval es cada cuánto se descartan los trabajos vencidos y se
+// compacta el log mientras la cola corre
+const pruneInterval = 10 * time.Minute
+
+// Queue es una cola persistente con entrega al menos una vez.
+// Los trabajos se procesan de uno en uno en orden de llegada.
+type Queue struct {
+	opts  Options
+	store *store
+
+	mu    sync.Mutex
+	jobs  map[string]*Job
+	byKey map[string]string // IdempotencyKey -> ID del envío más reciente
+
+	nextPrune time.Time // Próxima limpieza de trabajos vencidos
+
+	wake chan struct{}
+	now  func() time.Time
+}
+
+// Open abre la cola en opts.Dir y recupera los trabajos pendientes
+func Open(opts Options) (*Queue, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("queue: no se indicó directorio")
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+	if opts.BackoffInitial <= 0 {
+		opts.BackoffInitial = DefaultBackoffInitial
+	}
+	if opts.BackoffMax <= 0 {
+		opts.BackoffMax = DefaultBackoffMax
+	}
+	if opts.DedupWindow <= 0 {
+		opts.DedupWindow = DefaultDedupWindow
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = DefaultRetention
+	}
+	// Un trabajo descartado ya no puede deduplicar sus reenvíos
+	opts.Retention = max(opts.Retention, opts.DedupWindow)
+
+	st, jobs, err := openStore(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &Queue{
+		opts:  opts,
+		store: st,
+		jobs:  jobs,
+		byKey: make(map[string]string),
+		wake:  make(chan struct{}, 1),
+		now:   time.Now,
+	}
+	for _, job := range q.jobs {
+		q.index(job)
+	}
+
+	// Un trabajo que estaba imprimiéndose cuando el proceso murió se vuelve a encolar
+	resumed := 0
+	for _, job := range jobs {
+		if job.State == StatePrinting {
+			job.State = StateQueued
+			job.UpdatedAt = q.now()
+			if err := st.append(job); err != nil {
+				_ = st.close()
+				return nil, err
+			}
+		}
+		if job.State.Pending() {
+			resumed++
+		}
+	}
+	if resumed > 0 {
+		log.Printf("queue: se reanudan %d trabajos pendientes", resumed)
+	}
+	if q.opts.Notify == nil {
+		q.opts.Notify = func(*Job) {}
+	}
+	return q, nil
+}
+
+// Close cierra el log de la cola
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.store.close()
+}
+
+// Request describe un ticket a encolar
+type Request struct {
+	Ticket   []byte // JSON original del ticket
+	Template []byte // JSON de la plantilla resuelta
+	TicketRef
+	Printer string // Impresora destino elegida por el ruteo
+
+	// Raw son bytes ESC/POS que se envían tal cual. Si no está vacío el trabajo
+	// es de tipo raw y no lleva ticket ni plantilla.
+	Raw []byte
+	// Text es texto plano que se acomoda al ancho de la impresora al imprimir.
+	// Si no está vacío el trabajo es de tipo text.
+	Text string
+	// Receipt es el nombre del recibo libre con el que se imprime Ticket. Si
+	// no está vacío el trabajo es de tipo receipt y no lleva plantilla.
+	Receipt string
+	// Warnings son las observaciones de la validación que se imprimen en un
+	// aviso al inicio del ticket
+	Warnings []string
+}
+
+// Enqueue agrega un trabajo nuevo en estado queued sin deduplicar
+func (q *Queue) Enqueue(req Request) (*Job, error) {
+	job, _, err := q.enqueue("", newJob(req))
+	return job, err
+}
+
+// EnqueueReprint agrega la reimpresión explícita de un ticket. Las reimpresiones
+// nunca se deduplican.
+func (q *Queue) EnqueueReprint(req Request, reprint Reprint) (*Job, error) {
+	job := newJob(req)
+	job.Reprint = &reprint
+	out, _, err := q.enqueue("", job)
+	return out, err
+}
+
+// EnqueueOnce agrega un trabajo salvo que ya exista otro con la misma llave
+// dentro de la ventana de deduplicación; en ese caso devuelve el original y
+// duplicate = true. Un original en dead-letter nunca se imprimió, por lo que
+// no bloquea un envío nuevo. Con key vacía se comporta como Enqueue.
+func (q *Queue) EnqueueOnce(key string, req Request) (job *Job, duplicate bool, err error) {
+	return q.enqueue(key, newJob(req))
+}
+
+// newJob prepara un trabajo sin ID ni fechas
+func newJob(req Request) *Job {
+	switch {
+	case len(req.Raw) > 0:
+		return &Job{
+			State:   StateQueued,
+			Kind:    KindRaw,
+			Raw:     append([]byte(nil), req.Raw...),
+			Printer: req.Printer,
+		}
+	case req.Text != "":
+		return &Job{State: StateQueued, Kind: KindText, Text: req.Text, Printer: req.Printer}
+	case req.Receipt != "":
+		return &Job{
+			State:     StateQueued,
+			Kind:      KindReceipt,
+			Ticket:    append([]byte(nil), req.Ticket...),
+			Receipt:   req.Receipt,
+			TicketRef: req.TicketRef,
+			Printer:   req.Printer,
+		}
+	}
+	return &Job{
+		State:     StateQueued,
+		Kind:      KindTicket,
+		Ticket:    append([]byte(nil), req.Ticket...),
+		Template:  append([]byte(nil), req.Template...),
+		TicketRef: req.TicketRef,
+		Printer:   req.Printer,
+		Warnings:  append([]string(nil), req.Warnings...),
+	}
+}
+
+// enqueue persiste job salvo que key lo deduplique
+func (q *Queue) enqueue(key string, job *Job) (*Job, bool, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, false, err
+	}
+
+	q.mu.Lock()
+	now := q.now()
+	if orig := q.lookup(key, now); orig != nil {
+		out := orig.clone()
+		q.mu.Unlock()
+		return out, true, nil
+	}
+
+	job.ID = id
+	job.IdempotencyKey = key
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	if err := q.store.append(job); err != nil {
+		q.mu.Unlock()
+		return nil, false, err
+	}
+	q.jobs[id] = job
+	q.index(job)
+	out := job.clone()
+	q.mu.Unlock()
+
+	q.opts.Notify(out.clone())
+	q.signal()
+	return out, false, nil
+}
+
+// lookup devuelve el trabajo que deduplica key en el instante now, si existe
+func (q *Queue) lookup(key string, now time.Time) *Job {
+	if key == "" {
+		return nil
+	}
+	orig, ok := q.jobs[q.byKey[key]]
+	if !ok || orig.State == StateDead || now.Sub(orig.CreatedAt) > q.opts.DedupWindow {
+		return nil
+	}
+	return orig
+}
+
+// index registra la llave de idempotencia del trabajo
+func (q *Queue) index(job *Job) {
+	if job.IdempotencyKey == "" {
+		return
+	}
+	if prev, ok := q.jobs[q.byKey[job.IdempotencyKey]]; ok && prev.CreatedAt.After(job.CreatedAt) {
+		return
+	}
+	q.byKey[job.IdempotencyKey] = job.ID
+}
+
+// Get devuelve una copia del trabajo con el ID indicado
+func (q *Queue) Get(id string) (*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return job.clone(), nil
+}
+
+// List devuelve los trabajos en el estado indicado (todos si state es vacío),
+// ordenados por fecha de creación
+func (q *Queue) List(state State) []*Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var out []*Job
+	for _, job := range q.jobs {
+		if state == "" || job.State == state {
+			out = append(out, job.clone())
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].CreatedAt.Before(out[j].CreatedAt)
+	})
+	return out
+}
+
+// Requeue devuelve a la cola un trabajo en dead-letter reiniciando sus intentos
+func (q *Queue) Requeue(id string) (*Job, error) {
+	q.mu.Lock()
+	job, ok := q.jobs[id]
+	if !ok {
+		q.mu.Unlock()
+		return nil, ErrNotFound
+	}
+	if job.State != StateDead {
+		q.mu.Unlock()
+		return nil, fmt.Errorf("queue: el trabajo %s no está en dead-letter (%s)", id, job.State)
+	}
+	job.State = StateQueued
+	job.Attempts = 0
+	job.NextAttemptAt = time.Time{}
+	job.UpdatedAt = q.now()
+	if err := q.store.append(job); err != nil {
+		q.mu.Unlock()
+		return nil, err
+	}
+	out := job.clone()
+	q.mu.Unlock()
+
+	q.opts.Notify(out.clone())
+	q.signal()
+	return out, nil
+}
+
+// Run procesa trabajos hasta que ctx se cancele
+func (q *Queue) Run(ctx context.Context, process Processor) {
+	for {
+		job, wait := q.next()
+		if job == nil {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-q.wake:
+				timer.Stop()
+			case <-timer.C:
+			}
+			continue
+		}
+
+		processed := job.clone()
+		err := process(ctx, processed)
+		if ctx.Err() != nil {
+			// El trabajo queda en printing y se reanudará al reiniciar
+			return
+		}
+		q.finish(processed, err)
+	}
+}
+
+// next toma el siguiente trabajo listo y lo marca como printing.
+// Si no hay ninguno devuelve cuánto esperar antes de volver a revisar.
+func (q *Queue) next() (*Job, time.Duration) {
+	q.mu.Lock()
+
+	now := q.now()
+	if !now.Before(q.nextPrune) {
+		q.prune(now)
+		q.nextPrune = now.Add(pruneInterval)
+	}
+	var ready *Job
+	wait := q.nextPrune.Sub(now)
+	for _, job := range q.jobs {
+		switch job.State {
+		case StateQueued, StateFailed:
+			if d := job.NextAttemptAt.Sub(now); d > 0 {
+				if d < wait {
+					wait = d
+				}
+				continue
+			}
+		default:
+			continue
+		}
+		if ready == nil || job.CreatedAt.Before(ready.CreatedAt) {
+			ready = job
+		}
+	}
+	if ready == nil {
+		q.mu.Unlock()
+		return nil, wait
+	}
+
+	ready.State = StatePrinting
+	ready.Attempts++
+	ready.UpdatedAt = now
+	if err := q.store.append(ready); err != nil {
+		log.Printf("queue: %v", err)
+	}
+	out := ready.clone()
+	q.mu.Unlock()
+
+	q.opts.Notify(out.clone())
+	return out, 0
+}
+
+// prune descarta los trabajos done y dead que superaron la retención y compacta
+// el log si tiene versiones viejas. Se llama con q.mu tomado.
+func (q *Queue) prune(now time.Time) {
+	removed := 0
+	for id, job := range q.jobs {
+		if job.State.Pending() || now.Sub(job.UpdatedAt) <= q.opts.Retention {
+			continue
+		}
+		delete(q.jobs, id)
+		if job.IdempotencyKey != "" && q.byKey[job.IdempotencyKey] == id {
+			delete(q.byKey, job.IdempotencyKey)
+		}
+		removed++
+	}
+	if removed > 0 {
+		log.Printf("queue: se descartan %d trabajos terminados hace más de %s", removed, q.opts.Retention)
+	}
+	if q.store.lines <= len(q.jobs) {
+		return
+	}
+	if err := q.store.rewrite(q.jobs); err != nil {
+		log.Printf("queue: %v", err)
+	}
+}
+
+// finish registra el resultado de un intento de impresión de processed
+func (q *Queue) finish(processed *Job, procErr error) {
+	id := processed.ID
+	q.mu.Lock()
+	job, ok := q.jobs[id]
+	if !ok {
+		q.mu.Unlock()
+		return
+	}
+	now := q.now()
+	job.UpdatedAt = now
+	var deferred *deferredError
+	switch {
+	case errors.As(procErr, &deferred):
+		job.State = StateQueued
+		job.Attempts--
+		job.LastError = procErr.Error()
+		job.NextAttemptAt = now.Add(deferred.after)
+		log.Printf("queue: trabajo %s pospuesto hasta las %s: %v", id, job.NextAttemptAt.Format(time.TimeOnly), procErr)
+	case procErr == nil:
+		job.State = StateDone
+		job.PrintedBy = processed.PrintedBy
+		job.LastError = ""
+		job.NextAttemptAt = time.Time{}
+	case job.Attempts >= q.opts.MaxAttempts || IsPermanent(procErr):
+		job.State = StateDead
+		job.LastError = procErr.Error()
+		job.NextAttemptAt = time.Time{}
+		log.Printf("queue: trabajo %s enviado a dead-letter tras %d intentos: %v", id, job.Attempts, procErr)
+	default:
+		job.State = StateFailed
+		job.LastError = procErr.Error()
+		job.NextAttemptAt = now.Add(q.backoff(job.Attempts))
+		log.Printf("queue: trabajo %s falló (intento %d/%d), reintento a las %s: %v",
+			id, job.Attempts, q.opts.MaxAttempts, job.NextAttemptAt.Format(time.TimeOnly), procErr)
+	}
+	if err := q.store.append(job); err != nil {
+		log.Printf("queue: %v", err)
+	}
+	out := job.clone()
+	q.mu.Unlock()
+
+	q.opts.Notify(out)
+}
+
+// backoff calcula la espera exponencial tras el intento n
+func (q *Queue) backoff(attempt int) time.Duration {
+	d := q.opts.BackoffInitial
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= q.opts.BackoffMax {
+			return q.opts.BackoffMax
+		}
+	}
+	return d
+}
+
+// signal despierta al worker si está esperando
+func (q *Queue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// newID genera un identificador aleatorio para un trabajo
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("queue: no se pudo generar el ID del trabajo: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,293 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestQueue(t *testing.T, dir string) *Queue {
+	t.Helper()
+	q, err := Open(Options{
+		Dir:            dir,
+		MaxAttempts:    3,
+		BackoffInitial: time.Millisecond,
+		BackoffMax:     5 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return q
+}
+
+// runUntil procesa la cola hasta que el trabajo llegue al estado esperado
+func runUntil(t *testing.T, q *Queue, id string, want State, process Processor) *Job {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		q.Run(ctx, process)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := q.Get(id)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if job.State == want {
+			return job
+		}
+		time.Sleep(time.Millisecond)
+	}
+	job, _ := q.Get(id)
+	t.Fatalf("el trabajo quedó en %s; want %s", job.State, want)
+	return nil
+}
+
+func TestQueueRetriesUntilDone(t *testing.T) {
+	q := openTestQueue(t, t.TempDir())
+	defer q.Close()
+
+	job, err := q.Enqueue(Request{Ticket: []byte(`{"data":{}}`), Template: []byte(`{"data":{}}`), Printer: "mostrador"})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	calls := 0
+	got := runUntil(t, q, job.ID, StateDone, func(_ context.Context, job *Job) error {
+		calls++
+		if calls < 2 {
+			job.PrintedBy = "mostrador"
+			return errors.New("impresora fuera de línea")
+		}
+		job.PrintedBy = "respaldo"
+		return nil
+	})
+	if got.Attempts != 2 {
+		t.Errorf("Attempts = %d; want 2", got.Attempts)
+	}
+	if got.Printer != "mostrador" || got.PrintedBy != "respaldo" {
+		t.Errorf("Printer = %q, PrintedBy = %q; want mostrador, respaldo", got.Printer, got.PrintedBy)
+	}
+}
+
+func TestQueueDeadLetter(t *testing.T) {
+	q := openTestQueue(t, t.TempDir())
+	defer q.Close()
+
+	job, err := q.Enqueue(Request{Ticket: []byte(`{}`), Template: []byte(`{}`)})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	got := runUntil(t, q, job.ID, StateDead, func(context.Context, *Job) error {
+		return errors.New("sin papel")
+	})
+	if got.Attempts != 3 || got.LastError != "sin papel" {
+		t.Errorf("Attempts = %d, LastError = %q; want 3, %q", got.Attempts, got.LastError, "sin papel")
+	}
+	if dead := q.List(StateDead); len(dead) != 1 {
+		t.Errorf("List(StateDead) = %d trabajos; want 1", len(dead))
+	}
+
+	// Un error permanente no se reintenta
+	job, _ = q.Enqueue(Request{Ticket: []byte(`{}`), Template: []byte(`{}`)})
+	got = runUntil(t, q, job.ID, StateDead, func(context.Context, *Job) error {
+		return Permanent(errors.New("JSON inválido"))
+	})
+	if got.Attempts != 1 {
+		t.Errorf("Attempts = %d; want 1", got.Attempts)
+	}
+}
+
+func TestQueueDeferDoesNotCountAttempts(t *testing.T) {
+	q := openTestQueue(t, t.TempDir())
+	defer q.Close()
+
+	job, err := q.Enqueue(Request{Ticket: []byte(`{}`), Template: []byte(`{}`)})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	// Más aplazamientos que MaxAttempts no envían el trabajo a dead-letter
+	calls := 0
+	got := runUntil(t, q, job.ID, StateDone, func(context.Context, *Job) error {
+		calls++
+		if calls <= 5 {
+			return Defer(errors.New("tapa abierta"), time.Millisecond)
+		}
+		return nil
+	})
+	if got.Attempts != 1 {
+		t.Errorf("Attempts = %d; want 1", got.Attempts)
+	}
+}
+
+func TestQueueResumesAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestQueue(t, dir)
+	job, err := q.Enqueue(Request{Ticket: []byte(`{"data":{"folio":"1"}}`), Template: []byte(`{}`)})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	// Simular una caída a mitad de la impresión
+	if next, _ := q.next(); next == nil || next.ID != job.ID {
+		t.Fatalf("next() no devolvió el trabajo encolado")
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	q = openTestQueue(t, dir)
+	defer q.Close()
+	got, err := q.Get(job.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.State != StateQueued {
+		t.Fatalf("State = %s; want %s", got.State, StateQueued)
+	}
+	if string(got.Ticket) != `{"data":{"folio":"1"}}` {
+		t.Errorf("Ticket = %s", got.Ticket)
+	}
+	runUntil(t, q, job.ID, StateDone, func(context.Context, *Job) error { return nil })
+}
+
+func TestQueueRetention(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestQueue(t, dir)
+	done, _, err := q.EnqueueOnce("NTQ3", Request{Raw: []byte("uno")})
+	if err != nil {
+		t.Fatalf("EnqueueOnce: %v", err)
+	}
+	runUntil(t, q, done.ID, StateDone, func(context.Context, *Job) error { return nil })
+	dead, err := q.Enqueue(Request{Raw: []byte("dos")})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	runUntil(t, q, dead.ID, StateDead, func(context.Context, *Job) error {
+		return Permanent(errors.New("sin papel"))
+	})
+	pending, err := q.Enqueue(Request{Raw: []byte("tres")})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	// Pasada la retención, el worker descarta los trabajos terminados y
+	// compacta el log al buscar el siguiente
+	later := time.Now().Add(DefaultRetention + time.Hour)
+	q.now = func() time.Time { return later }
+	if next, _ := q.next(); next == nil || next.ID != pending.ID {
+		t.Fatalf("next() no devolvió el trabajo pendiente")
+	}
+	for _, id := range []string{done.ID, dead.ID} {
+		if _, err := q.Get(id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%s) = %v; want ErrNotFound", id, err)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(dir, logFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// La versión compactada del pendiente más su paso a printing
+	if n := bytes.Count(data, []byte("\n")); n != 2 {
+		t.Errorf("líneas del log = %d; want 2", n)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	q = openTestQueue(t, dir)
+	defer q.Close()
+	if jobs := q.List(""); len(jobs) != 1 || jobs[0].ID != pending.ID {
+		t.Errorf("trabajos tras reiniciar = %+v", jobs)
+	}
+	// Sin el original, el reenvío es un trabajo nuevo
+	if _, duplicate, err := q.EnqueueOnce("NTQ3", Request{Raw: []byte("uno")}); err != nil || duplicate {
+		t.Errorf("EnqueueOnce = %v, %v; want trabajo nuevo", duplicate, err)
+	}
+}
+
+func TestQueueRawJob(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestQueue(t, dir)
+	raw := []byte{0x1B, 0x40, 'H', 'O', 'L', 'A', '\n', 0x1D, 0x56, 0x00}
+	job, err := q.Enqueue(Request{Raw: raw, Printer: "mostrador"})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	q = openTestQueue(t, dir)
+	defer q.Close()
+	got, err := q.Get(job.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if !got.IsRaw() || string(got.Raw) != string(raw) || got.Ticket != nil || got.Printer != "mostrador" {
+		t.Errorf("trabajo = %+v", got)
+	}
+}
+
+func TestEnqueueOnceDeduplicates(t *testing.T) {
+	q := openTestQueue(t, t.TempDir())
+	defer q.Close()
+	now := time.Now()
+	q.now = func() time.Time { return now }
+
+	ref := TicketRef{Identificador: "NTQ3", Serie: "ABC1", Folio: "326"}
+	key := IdempotencyKey("", ref)
+	first, dup, err := q.EnqueueOnce(key, Request{Ticket: []byte(`{}`), Template: []byte(`{}`), TicketRef: ref})
+	if err != nil || dup {
+		t.Fatalf("EnqueueOnce = %v, %v", dup, err)
+	}
+
+	second, dup, err := q.EnqueueOnce(key, Request{Ticket: []byte(`{}`), Template: []byte(`{}`), TicketRef: ref})
+	if err != nil || !dup || second.ID != first.ID {
+		t.Fatalf("reenvío: dup = %v, ID = %s; want true, %s (err %v)", dup, second.ID, first.ID, err)
+	}
+
+	// Una reimpresión explícita nunca se deduplica
+	reprint, err := q.Enqueue(Request{Ticket: []byte(`{}`), Template: []byte(`{}`), TicketRef: ref})
+	if err != nil || reprint.ID == first.ID {
+		t.Fatalf("Enqueue devolvió el trabajo original")
+	}
+
+	// Fuera de la ventana se acepta un trabajo nuevo
+	now = now.Add(DefaultDedupWindow + time.Second)
+	third, dup, err := q.EnqueueOnce(key, Request{Ticket: []byte(`{}`), Template: []byte(`{}`), TicketRef: ref})
+	if err != nil || dup || third.ID == first.ID {
+		t.Fatalf("fuera de ventana: dup = %v (err %v)", dup, err)
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		explicit string
+		ref      TicketRef
+		expected string
+	}{
+		{"Llave explícita", "abc", TicketRef{Identificador: "NTQ3"}, "key:abc"},
+		{"Identificador", "", TicketRef{Identificador: "NTQ3", Serie: "A", Folio: "1"}, "id:NTQ3"},
+		{"Serie y folio", "", TicketRef{Serie: "A", Folio: "1"}, "sf:A/1"},
+		{"Sin datos", "", TicketRef{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IdempotencyKey(tt.explicit, tt.ref); got != tt.expected {
+				t.Errorf("IdempotencyKey(%q, %+v) = %q; want %q", tt.explicit, tt.ref, got, tt.expected)
+			}
+		})
+	}
+}
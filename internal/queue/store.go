@@ -0,0 +1,172 @@
+package queue
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// logFileName es el nombre del log de trabajos dentro del directorio de la cola
+const logFileName = "jobs.log"
+
+// store persiste los trabajos en un log de solo anexado (una línea JSON por cambio).
+// Al abrirse se reproduce el log y se compacta a una línea por trabajo; la cola
+// lo vuelve a compactar mientras corre con rewrite.
+type store struct {
+	path  string
+	file  *os.File
+	lines int // Líneas escritas desde la última compactación
+}
+
+// openStore abre (o crea) el log en dir y devuelve los trabajos reconstruidos
+func openStore(dir string) (*store, map[string]*Job, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, nil, fmt.Errorf("queue: no se pudo crear el directorio %s: %w", dir, err)
+	}
+	path := filepath.Join(filepath.Clean(dir), logFileName)
+
+	jobs, err := replay(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := compact(path, jobs); err != nil {
+		return nil, nil, err
+	}
+
+	file, err := openLog(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &store{path: path, file: file, lines: len(jobs)}, jobs, nil
+}
+
+// openLog abre el log para anexar
+func openLog(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("queue: no se pudo abrir el log: %w", err)
+	}
+	return file, nil
+}
+
+// replay lee el log y conserva la última versión de cada trabajo
+func replay(path string) (map[string]*Job, error) {
+	jobs := make(map[string]*Job)
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return jobs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("queue: no se pudo leer el log: %w", err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("queue: error al cerrar el log: %v", err)
+		}
+	}()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var job Job
+		if err := json.Unmarshal(data, &job); err != nil {
+			// Una línea truncada suele deberse a una caída durante la escritura
+			log.Printf("queue: se ignora la línea %d corrupta del log: %v", line, err)
+			continue
+		}
+		jobs[job.ID] = &job
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("queue: error al leer el log: %w", err)
+	}
+	return jobs, nil
+}
+
+// compact reescribe el log con una sola línea por trabajo usando un renombrado atómico
+func compact(path string, jobs map[string]*Job) error {
+	ordered := make([]*Job, 0, len(jobs))
+	for _, job := range jobs {
+		ordered = append(ordered, job)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].CreatedAt.Before(ordered[j].CreatedAt)
+	})
+
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("queue: no se pudo compactar el log: %w", err)
+	}
+	w := bufio.NewWriter(file)
+	enc := json.NewEncoder(w)
+	for _, job := range ordered {
+		if err := enc.Encode(job); err != nil {
+			_ = file.Close()
+			return fmt.Errorf("queue: no se pudo compactar el log: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("queue: no se pudo compactar el log: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("queue: no se pudo compactar el log: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("queue: no se pudo compactar el log: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// append agrega una versión del trabajo al log y la sincroniza a disco
+func (s *store) append(job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("queue: no se pudo serializar el trabajo %s: %w", job.ID, err)
+	}
+	data = append(data, '\n')
+	if _, err := s.file.Write(data); err != nil {
+		return fmt.Errorf("queue: no se pudo escribir el trabajo %s: %w", job.ID, err)
+	}
+	s.lines++
+	return s.file.Sync()
+}
+
+// rewrite compacta el log a una línea por cada trabajo de jobs. El archivo se
+// cierra antes del renombrado, que en Windows falla sobre un archivo abierto;
+// si la compactación falla se sigue anexando al log anterior.
+func (s *store) rewrite(jobs map[string]*Job) error {
+	if err := s.file.Close(); err != nil {
+		log.Printf("queue: error al cerrar el log: %v", err)
+	}
+	compactErr := compact(s.path, jobs)
+	file, err := openLog(s.path)
+	if err != nil {
+		return errors.Join(compactErr, err)
+	}
+	s.file = file
+	if compactErr != nil {
+		return compactErr
+	}
+	s.lines = len(jobs)
+	return nil
+}
+
+// close cierra el archivo del log
+func (s *store) close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,7 @@
+// Package rawport publica impresoras del daemon como impresoras de red raw
+// (JetDirect, normalmente el puerto TCP 9100). Los programas de punto de venta
+// que solo saben enviar ESC/POS a una IP imprimen a través del daemon: cada
+// conexión es un trabajo que pasa por la misma cola, ruteo, journal y respaldos
+// que los tickets JSON. Las consultas DLE EOT al inicio de la conexión se
+// contestan con el estado que conoce el monitor.
+package rawport
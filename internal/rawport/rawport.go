@@ -0,0 +1,238 @@
+package rawport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"pos-daemon.adcon.dev/internal/printers"
+	"pos-daemon.adcon.dev/internal/queue"
+	"pos-daemon.adcon.dev/internal/status"
+)
+
+// Valores por defecto de Options
+const (
+	DefaultIdleTimeout = 30 * time.Second
+	DefaultMaxJobBytes = 16 << 20
+)
+
+// Options configura un puerto raw
+type Options struct {
+	Addr        string                // Dirección de escucha (ej. ":9100")
+	Destination *printers.Destination // Impresora o grupo que recibe los trabajos
+	Queue       *queue.Queue          // Cola donde se encolan los trabajos
+	Monitor     *status.Monitor       // Estado con que se contesta DLE EOT; nil contesta lista
+
+	// IdleTimeout da por terminado el trabajo si la conexión queda en silencio;
+	// algunos programas no cierran la conexión al terminar
+	IdleTimeout time.Duration
+	MaxJobBytes int // Tamaño máximo de un trabajo
+}
+
+// Listener acepta trabajos raw en un puerto TCP
+type Listener struct {
+	opts Options
+	ln   net.Listener
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+	wg    sync.WaitGroup
+}
+
+// Listen abre el puerto indicado en opts.Addr; Serve atiende las conexiones
+func Listen(opts Options) (*Listener, error) {
+	if opts.Destination == nil || len(opts.Destination.Printers) == 0 {
+		return nil, fmt.Errorf("rawport: no se indicó impresora destino")
+	}
+	if opts.Queue == nil {
+		return nil, fmt.Errorf("rawport: no se indicó la cola de trabajos")
+	}
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = DefaultIdleTimeout
+	}
+	if opts.MaxJobBytes <= 0 {
+		opts.MaxJobBytes = DefaultMaxJobBytes
+	}
+	ln, err := net.Listen("tcp", opts.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("rawport: %w", err)
+	}
+	return &Listener{opts: opts, ln: ln, conns: make(map[net.Conn]struct{})}, nil
+}
+
+// Addr devuelve la dirección en la que escucha
+func (l *Listener) Addr() net.Addr {
+	return l.ln.Addr()
+}
+
+// Destination devuelve la impresora o grupo que recibe los trabajos
+func (l *Listener) Destination() *printers.Destination {
+	return l.opts.Destination
+}
+
+// Close cierra el puerto sin atender conexiones; para detener Serve se
+// cancela su contexto
+func (l *Listener) Close() error {
+	return l.ln.Close()
+}
+
+// Serve atiende conexiones hasta que ctx se cancele. Al terminar corta las
+// conexiones abiertas; sus trabajos incompletos se descartan y el programa que
+// los envió verá el error.
+func (l *Listener) Serve(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() {
+		if err := l.ln.Close(); err != nil {
+			log.Printf("rawport: error al cerrar %s: %v", l.opts.Addr, err)
+		}
+		l.mu.Lock()
+		for conn := range l.conns {
+			_ = conn.Close()
+		}
+		l.mu.Unlock()
+	})
+	defer stop()
+
+	var err error
+	for {
+		var conn net.Conn
+		conn, err = l.ln.Accept()
+		if err != nil {
+			break
+		}
+		l.mu.Lock()
+		if ctx.Err() != nil {
+			// Aceptada mientras se cerraba el puerto
+			l.mu.Unlock()
+			_ = conn.Close()
+			continue
+		}
+		l.conns[conn] = struct{}{}
+		l.wg.Add(1)
+		l.mu.Unlock()
+		go l.handle(ctx, conn)
+	}
+	l.wg.Wait()
+	if ctx.Err() != nil {
+		return nil
+	}
+	return fmt.Errorf("rawport: %w", err)
+}
+
+// handle lee un trabajo de la conexión y lo encola al cerrarse o al quedar en
+// silencio
+func (l *Listener) handle(ctx context.Context, conn net.Conn) {
+	defer l.wg.Done()
+	defer func() {
+		l.mu.Lock()
+		delete(l.conns, conn)
+		l.mu.Unlock()
+		if err := conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			log.Printf("rawport: error al cerrar conexión: %v", err)
+		}
+	}()
+
+	var (
+		data    bytes.Buffer
+		pending []byte // Consulta de estado partida entre lecturas
+		buf     = make([]byte, 32*1024)
+	)
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(l.opts.IdleTimeout)); err != nil {
+			log.Printf("rawport: %v", err)
+			return
+		}
+		n, err := conn.Read(buf)
+		if n > 0 {
+			chunk := append(pending, buf[:n]...)
+			pending = nil
+			if data.Len() == 0 {
+				// Solo se contestan las consultas previas al trabajo para no
+				// confundirlas con los bytes de una imagen
+				var replies []byte
+				chunk, replies, pending = l.split(chunk)
+				if len(replies) > 0 {
+					if _, err := conn.Write(replies); err != nil {
+						log.Printf("rawport: error al contestar estado a %s: %v", conn.RemoteAddr(), err)
+						return
+					}
+				}
+			}
+			if data.Len()+len(chunk) > l.opts.MaxJobBytes {
+				log.Printf("rawport: se descarta el trabajo de %s: supera %d bytes", conn.RemoteAddr(), l.opts.MaxJobBytes)
+				return
+			}
+			data.Write(chunk)
+		}
+		if err != nil {
+			var nerr net.Error
+			switch {
+			case ctx.Err() != nil:
+				return
+			case errors.Is(err, io.EOF), errors.As(err, &nerr) && nerr.Timeout():
+				// Fin del trabajo
+			default:
+				log.Printf("rawport: se descarta el trabajo de %s: %v", conn.RemoteAddr(), err)
+				return
+			}
+			break
+		}
+	}
+	// Una consulta que quedó incompleta al terminar es parte del trabajo
+	data.Write(pending)
+	if data.Len() == 0 {
+		return
+	}
+
+	job, err := l.opts.Queue.Enqueue(queue.Request{Raw: data.Bytes(), Printer: l.opts.Destination.Name})
+	if err != nil {
+		log.Printf("rawport: no se pudo encolar el trabajo de %s: %v", conn.RemoteAddr(), err)
+		return
+	}
+	log.Printf("Trabajo raw %s recibido de %s para %s (%d bytes)", job.ID, conn.RemoteAddr(), job.Printer, data.Len())
+}
+
+// split contesta las consultas DLE EOT al inicio de chunk. Devuelve los datos
+// del trabajo desde el primer byte que no es una consulta, las respuestas y el
+// final de chunk si es una consulta incompleta.
+func (l *Listener) split(chunk []byte) (job, replies, rest []byte) {
+	for i := 0; i < len(chunk); i += 3 {
+		if len(chunk)-i < 3 {
+			if chunk[i] == 0x10 && (len(chunk)-i == 1 || chunk[i+1] == 0x04) {
+				return nil, replies, chunk[i:]
+			}
+			return chunk[i:], replies, nil
+		}
+		if chunk[i] != 0x10 || chunk[i+1] != 0x04 {
+			return chunk[i:], replies, nil
+		}
+		replies = append(replies, status.EncodeEOT(l.status(), chunk[i+2]))
+	}
+	return nil, replies, nil
+}
+
+// status es el estado que se informa a los programas: lista si alguna
+// impresora del destino puede imprimir, o el estado de la principal si
+// ninguna puede. Los trabajos se encolan de todas formas.
+func (l *Listener) status() status.PrinterStatus {
+	ready := status.PrinterStatus{Online: true}
+	m := l.opts.Monitor
+	if m == nil {
+		return ready
+	}
+	for _, p := range l.opts.Destination.Printers {
+		if m.Ready(p.Name) == nil {
+			return ready
+		}
+	}
+	st, err := m.Status(l.opts.Destination.Primary().Name)
+	if err != nil {
+		return ready
+	}
+	return st
+}
@@ -0,0 +1,175 @@
+package rawport
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"pos-daemon.adcon.dev/internal/connector"
+	"pos-daemon.adcon.dev/internal/printers"
+	"pos-daemon.adcon.dev/internal/queue"
+)
+
+// serve abre un puerto raw hacia la impresora "mostrador" y lo atiende hasta
+// que termine la prueba
+func serve(t *testing.T, idle time.Duration) (*Listener, *queue.Queue) {
+	t.Helper()
+	registry, err := printers.New([]printers.Printer{
+		{Name: "mostrador", Connector: connector.Options{Type: connector.TypeFile, Device: "ticket.bin"}},
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("printers.New: %v", err)
+	}
+	dest, err := registry.Resolve("mostrador")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs, err := queue.Open(queue.Options{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("queue.Open: %v", err)
+	}
+	l, err := Listen(Options{Addr: "127.0.0.1:0", Destination: dest, Queue: jobs, IdleTimeout: idle})
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- l.Serve(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+		jobs.Close()
+	})
+	return l, jobs
+}
+
+// waitQueued espera a que la cola tenga n trabajos
+func waitQueued(t *testing.T, jobs *queue.Queue, n int) []*queue.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		list := jobs.List(queue.StateQueued)
+		if len(list) >= n {
+			return list
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("se encolaron %d trabajos; want %d", len(list), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRawJob(t *testing.T) {
+	l, jobs := serve(t, time.Minute)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// Consulta de estado antes del trabajo
+	if _, err := conn.Write([]byte{0x10, 0x04, 0x01}); err != nil {
+		t.Fatal(err)
+	}
+	reply := make([]byte, 1)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		t.Fatalf("DLE EOT 1: %v", err)
+	}
+	if reply[0] != 0x12 {
+		t.Errorf("DLE EOT 1 = %#x; want 0x12", reply[0])
+	}
+
+	data := []byte{0x1B, 0x40, 'H', 'O', 'L', 'A', '\n', 0x1D, 0x56, 0x00}
+	if _, err := conn.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	job := waitQueued(t, jobs, 1)[0]
+	if !job.IsRaw() || !bytes.Equal(job.Raw, data) || job.Printer != "mostrador" {
+		t.Errorf("trabajo = %+v", job)
+	}
+}
+
+func TestRawJobIdleTimeout(t *testing.T) {
+	l, jobs := serve(t, 50*time.Millisecond)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Dos trabajos separados por el silencio en la misma conexión no se
+	// juntan: el primero se encola y la conexión se cierra
+	if _, err := conn.Write([]byte("PRIMERO\n")); err != nil {
+		t.Fatal(err)
+	}
+	job := waitQueued(t, jobs, 1)[0]
+	if string(job.Raw) != "PRIMERO\n" {
+		t.Errorf("Raw = %q", job.Raw)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Error("la conexión siguió abierta tras el silencio")
+	}
+}
+
+func TestRawConnectionWithoutData(t *testing.T) {
+	l, jobs := serve(t, time.Minute)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Solo consultas de estado: no hay trabajo
+	if _, err := conn.Write([]byte{0x10, 0x04, 0x04}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(conn, make([]byte, 1)); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	time.Sleep(50 * time.Millisecond)
+	if n := len(jobs.List("")); n != 0 {
+		t.Errorf("se encolaron %d trabajos", n)
+	}
+}
+
+func TestRawJobEndsLikeQuery(t *testing.T) {
+	l, jobs := serve(t, time.Minute)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if _, err := conn.Write([]byte{0x10, 0x04, 0x01}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(conn, make([]byte, 1)); err != nil {
+		t.Fatalf("DLE EOT 1: %v", err)
+	}
+	// Los bytes que parecen el inicio de otra consulta no se pierden al
+	// cerrar la conexión
+	if _, err := conn.Write([]byte{0x10, 0x04}); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	job := waitQueued(t, jobs, 1)[0]
+	if !bytes.Equal(job.Raw, []byte{0x10, 0x04}) {
+		t.Errorf("Raw = % X; want 10 04", job.Raw)
+	}
+}
@@ -0,0 +1,8 @@
+// Package receipt interpreta un flujo de bytes ESC/POS y lo convierte en un
+// documento estructurado: líneas de texto con su formato, imágenes raster,
+// códigos QR, avances de papel y cortes.
+//
+// Sirve para reconstruir lo que recibió una impresora a partir de una captura
+// (.bin) y verlo como texto, HTML o PNG, y para probar lo que envía
+// service.TicketConstructor sin depender de bytes concretos.
+package receipt
@@ -0,0 +1,91 @@
+package receipt
+
+import (
+	"fmt"
+	"image"
+	"strings"
+
+	"github.com/AdConDev/pos-printer/types"
+)
+
+// Document es el recibo reconstruido a partir de un flujo ESC/POS
+type Document struct {
+	Blocks   []Block
+	Warnings []string // Comandos desconocidos, truncados o ignorados
+}
+
+// Block es un elemento del recibo: *Line, *Image, *QR, *Barcode, *Feed o *Cut
+type Block interface {
+	block()
+}
+
+// Span es un fragmento de texto con el mismo formato
+type Span struct {
+	Text      string
+	Bold      bool
+	Underline bool
+	Font      types.Font
+	Width     int // Multiplicador de ancho (1-8)
+	Height    int // Multiplicador de alto (1-8)
+}
+
+// Line es una línea de texto terminada por LF o por un comando que imprime el buffer.
+// Una línea sin fragmentos es una línea en blanco.
+type Line struct {
+	Align types.Alignment
+	Spans []Span
+}
+
+// Text devuelve el texto de la línea sin formato
+func (l *Line) Text() string {
+	var sb strings.Builder
+	for _, span := range l.Spans {
+		sb.WriteString(span.Text)
+	}
+	return sb.String()
+}
+
+// Image es una imagen raster (GS v 0) en blanco y negro
+type Image struct {
+	Align  types.Alignment
+	Bitmap *image.Gray // Negro = punto impreso
+}
+
+// QR es un código QR impreso con GS ( k
+type QR struct {
+	Align           types.Alignment
+	Data            string
+	ModuleSize      int  // Tamaño del módulo en puntos
+	ErrorCorrection byte // 'L', 'M', 'Q' o 'H'
+}
+
+// Barcode es un código de barras impreso con GS k
+type Barcode struct {
+	Align  types.Alignment
+	System byte // Valor m del comando
+	Data   string
+}
+
+// Feed es un avance de papel, en líneas (ESC d) o en puntos (ESC J)
+type Feed struct {
+	Lines int
+	Dots  int
+}
+
+// Cut es un corte de papel (GS V)
+type Cut struct {
+	Partial bool
+	Feed    int // Avance previo al corte en las variantes con avance
+}
+
+func (*Line) block()    {}
+func (*Image) block()   {}
+func (*QR) block()      {}
+func (*Barcode) block() {}
+func (*Feed) block()    {}
+func (*Cut) block()     {}
+
+// warnf registra una advertencia del intérprete
+func (d *Document) warnf(offset int, format string, args ...interface{}) {
+	d.Warnings = append(d.Warnings, fmt.Sprintf("offset %d: ", offset)+fmt.Sprintf(format, args...))
+}
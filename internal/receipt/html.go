@@ -0,0 +1,118 @@
+package receipt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"image"
+	"image/png"
+	"strings"
+
+	"github.com/AdConDev/pos-printer/types"
+)
+
+// htmlHead da al recibo el aspecto de un rollo térmico
+const htmlHead = `<!DOCTYPE html>
+<html lang="es">
+<head>
+<meta charset="utf-8">
+<title>Recibo</title>
+<style>
+body { background: #ddd; }
+.receipt { background: #fff; margin: 1em auto; padding: 1em; width: %dch; font: 14px/1.3 monospace; }
+.line { white-space: pre-wrap; overflow-wrap: anywhere; min-height: 1.3em; }
+.center { text-align: center; } .right { text-align: right; }
+.b { font-weight: bold; } .u { text-decoration: underline; } .fb { font-size: 75%%; }
+.cut { border: 0; border-top: 1px dashed #888; margin: 1em -1em; }
+img { image-rendering: pixelated; max-width: 100%%; }
+</style>
+</head>
+<body>
+<div class="receipt">
+`
+
+// HTML representa el documento como una página autocontenida de columns
+// columnas de ancho; las imágenes y los QR se incrustan como PNG
+func (d *Document) HTML(columns int) (string, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, htmlHead, columns)
+	for _, b := range d.Blocks {
+		switch b := b.(type) {
+		case *Line:
+			fmt.Fprintf(&sb, "<div class=\"%s\">", lineClass(b.Align))
+			for _, span := range b.Spans {
+				writeSpan(&sb, span)
+			}
+			sb.WriteString("</div>\n")
+		case *Image:
+			if err := writeImage(&sb, b.Bitmap, b.Align); err != nil {
+				return "", err
+			}
+		case *QR:
+			img, err := qrImage(b)
+			if err != nil {
+				return "", err
+			}
+			if err := writeImage(&sb, img, b.Align); err != nil {
+				return "", err
+			}
+		case *Barcode:
+			fmt.Fprintf(&sb, "<div class=\"%s\">[código de barras %s]</div>\n", lineClass(b.Align), html.EscapeString(b.Data))
+		case *Feed:
+			for i := 0; i < b.Lines+b.Dots/dotsPerTextLine; i++ {
+				sb.WriteString("<div class=\"line\"></div>\n")
+			}
+		case *Cut:
+			sb.WriteString("<hr class=\"cut\">\n")
+		}
+	}
+	sb.WriteString("</div>\n</body>\n</html>\n")
+	return sb.String(), nil
+}
+
+// lineClass devuelve las clases CSS de una línea con la justificación indicada
+func lineClass(align types.Alignment) string {
+	switch align {
+	case types.AlignCenter:
+		return "line center"
+	case types.AlignRight:
+		return "line right"
+	}
+	return "line"
+}
+
+// writeSpan escribe un fragmento de texto con su formato
+func writeSpan(sb *strings.Builder, span Span) {
+	var classes []string
+	if span.Bold {
+		classes = append(classes, "b")
+	}
+	if span.Underline {
+		classes = append(classes, "u")
+	}
+	if span.Font == types.FontB {
+		classes = append(classes, "fb")
+	}
+	style := ""
+	if span.Width > 1 || span.Height > 1 {
+		style = fmt.Sprintf(` style="display:inline-block;transform:scale(%d,%d);transform-origin:left bottom"`, span.Width, span.Height)
+	}
+	text := html.EscapeString(span.Text)
+	if len(classes) == 0 && style == "" {
+		sb.WriteString(text)
+		return
+	}
+	fmt.Fprintf(sb, `<span class="%s"%s>%s</span>`, strings.Join(classes, " "), style, text)
+}
+
+// writeImage incrusta una imagen como PNG en base64
+func writeImage(sb *strings.Builder, img image.Image, align types.Alignment) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return fmt.Errorf("receipt: error al codificar imagen: %w", err)
+	}
+	fmt.Fprintf(sb, "<div class=\"%s\"><img alt=\"\" width=\"%d\" src=\"data:image/png;base64,%s\"></div>\n",
+		lineClass(align), img.Bounds().Dx(), base64.StdEncoding.EncodeToString(buf.Bytes()))
+	return nil
+}
@@ -0,0 +1,566 @@
+package receipt
+
+import (
+	"image"
+	"image/color"
+	"unicode/utf8"
+
+	"github.com/AdConDev/pos-printer/types"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Bytes de control ESC/POS
+const (
+	nul = 0x00
+	ht  = 0x09
+	lf  = 0x0A
+	ff  = 0x0C
+	cr  = 0x0D
+	dle = 0x10
+	esc = 0x1B
+	fs  = 0x1C
+	gs  = 0x1D
+)
+
+// codePages relaciona el valor n de ESC t n con su tabla de caracteres
+var codePages = map[byte]*charmap.Charmap{
+	0:  charmap.CodePage437,
+	2:  charmap.CodePage850,
+	3:  charmap.CodePage860,
+	4:  charmap.CodePage863,
+	5:  charmap.CodePage865,
+	16: charmap.Windows1252,
+	17: charmap.CodePage866,
+	18: charmap.CodePage852,
+	19: charmap.CodePage858,
+}
+
+// Comandos sin efecto en el documento y el número de parámetros fijos que se omiten
+var (
+	ignoredESC = map[byte]int{
+		'2': 0, '3': 1, ' ': 1, 'R': 1, 'V': 1, '{': 1, 'U': 1, '=': 1, 'r': 1,
+		'S': 0, 'L': 0, 'c': 2, 'p': 3, '$': 2, '\\': 2, 'e': 1, 'K': 1, '%': 1,
+	}
+	ignoredGS = map[byte]int{
+		'B': 1, 'H': 1, 'h': 1, 'w': 1, 'f': 1, 'b': 1, 'a': 1, 'I': 1, 'r': 1,
+		'L': 2, 'W': 2, 'P': 2, '$': 2, '\\': 2,
+	}
+	ignoredFS = map[byte]int{
+		'&': 0, '.': 0, '!': 1, '-': 1, 'C': 1, 'W': 1, 'S': 2, 'p': 2,
+	}
+)
+
+// autoCharset indica que aún no se recibió ESC t: el texto se toma como UTF-8 si
+// es válido (lo que envía pos-printer) y como CP437 en caso contrario
+const autoCharset = -1
+
+// format es el estado de formato vigente en la impresora
+type format struct {
+	align     types.Alignment
+	bold      bool
+	underline bool
+	font      types.Font
+	width     int
+	height    int
+	charset   int
+}
+
+// defaultFormat es el estado tras ESC @
+func defaultFormat() format {
+	return format{width: 1, height: 1, charset: autoCharset}
+}
+
+// parser recorre el flujo manteniendo el estado de la impresora
+type parser struct {
+	data  []byte
+	pos   int
+	doc   *Document
+	state format
+
+	line *Line  // Línea en el buffer de impresión, nil si está vacío
+	raw  []byte // Texto pendiente con el formato actual
+
+	qr QR // Parámetros de QR acumulados con GS ( k
+
+	// partial indica que pueden llegar más datos: un comando truncado no es un
+	// error sino que espera al resto (Stream)
+	partial bool
+	short   bool // El último comando quedó truncado en modo partial
+}
+
+func newParser() *parser {
+	return &parser{
+		doc:   &Document{},
+		state: defaultFormat(),
+		qr:    QR{ModuleSize: 3, ErrorCorrection: 'L'},
+	}
+}
+
+// Parse interpreta un flujo ESC/POS. Es tolerante a errores: los comandos
+// desconocidos o truncados se registran en Document.Warnings y se continúa.
+func Parse(data []byte) *Document {
+	p := newParser()
+	p.data = data
+	p.run()
+	p.flush()
+	return p.doc
+}
+
+// Stream interpreta un flujo ESC/POS que llega por partes, como el de una
+// conexión. Cada Write interpreta solo los bytes nuevos; un comando partido
+// entre dos Write se interpreta cuando llega completo.
+type Stream struct {
+	p *parser
+}
+
+// NewStream crea un intérprete incremental
+func NewStream() *Stream {
+	p := newParser()
+	p.partial = true
+	return &Stream{p: p}
+}
+
+// Write agrega datos al flujo y devuelve los bloques que completaron
+func (s *Stream) Write(data []byte) []Block {
+	n := len(s.p.doc.Blocks)
+	s.p.data = append(s.p.data, data...)
+	s.p.run()
+	return s.p.doc.Blocks[n:]
+}
+
+// Close termina el flujo y devuelve el documento completo, igual al que
+// devolvería Parse con todos los datos
+func (s *Stream) Close() *Document {
+	s.p.partial = false
+	s.p.run()
+	s.p.flush()
+	return s.p.doc
+}
+
+// run interpreta los comandos desde p.pos. En modo partial se detiene antes
+// de un comando truncado y descarta lo que haya registrado.
+func (p *parser) run() {
+	for p.pos < len(p.data) {
+		start, warnings := p.pos, len(p.doc.Warnings)
+		p.step()
+		if p.short {
+			p.pos, p.short = start, false
+			p.doc.Warnings = p.doc.Warnings[:warnings]
+			return
+		}
+	}
+}
+
+// flush imprime el texto que quedó en el buffer al final del flujo
+func (p *parser) flush() {
+	if p.line != nil || len(p.raw) > 0 {
+		p.doc.warnf(len(p.data), "el flujo termina con texto sin imprimir")
+		p.printLine()
+	}
+}
+
+// step interpreta el byte en p.pos y los parámetros del comando que inicia
+func (p *parser) step() {
+	b := p.data[p.pos]
+	p.pos++
+	switch b {
+	case lf:
+		p.printLine()
+	case esc:
+		p.parseESC()
+	case gs:
+		p.parseGS()
+	case dle:
+		p.parseDLE()
+	case fs:
+		p.parseFS()
+	case ht:
+		p.text(' ')
+	case cr, nul, ff:
+	default:
+		if b < 0x20 {
+			p.doc.warnf(p.pos-1, "byte de control 0x%02X ignorado", b)
+			return
+		}
+		p.text(b)
+	}
+}
+
+// next lee n parámetros; devuelve false si el flujo está truncado
+func (p *parser) next(n int) ([]byte, bool) {
+	if p.pos+n > len(p.data) {
+		if p.partial {
+			p.short = true
+			p.pos = len(p.data)
+			return nil, false
+		}
+		p.doc.warnf(p.pos, "comando truncado: faltan %d bytes", p.pos+n-len(p.data))
+		p.pos = len(p.data)
+		return nil, false
+	}
+	out := p.data[p.pos : p.pos+n]
+	p.pos += n
+	return out, true
+}
+
+// text agrega un byte de texto al buffer de impresión
+func (p *parser) text(b byte) {
+	if p.line == nil {
+		p.line = &Line{Align: p.state.align}
+	}
+	p.raw = append(p.raw, b)
+}
+
+// endSpan cierra el fragmento pendiente antes de un cambio de formato
+func (p *parser) endSpan() {
+	if len(p.raw) == 0 {
+		return
+	}
+	p.line.Spans = append(p.line.Spans, Span{
+		Text:      p.decode(p.raw),
+		Bold:      p.state.bold,
+		Underline: p.state.underline,
+		Font:      p.state.font,
+		Width:     p.state.width,
+		Height:    p.state.height,
+	})
+	p.raw = nil
+}
+
+// decode convierte el texto según la tabla de caracteres activa
+func (p *parser) decode(raw []byte) string {
+	cm := charmap.CodePage437
+	if p.state.charset == autoCharset {
+		if utf8.Valid(raw) {
+			return string(raw)
+		}
+	} else if c, ok := codePages[byte(p.state.charset)]; ok {
+		cm = c
+	}
+	s, err := cm.NewDecoder().Bytes(raw)
+	if err != nil {
+		return string(raw)
+	}
+	return string(s)
+}
+
+// printLine imprime el buffer como una línea (LF)
+func (p *parser) printLine() {
+	p.endSpan()
+	if p.line == nil {
+		p.line = &Line{Align: p.state.align}
+	}
+	p.doc.Blocks = append(p.doc.Blocks, p.line)
+	p.line = nil
+}
+
+// printBuffer imprime el buffer solo si tiene texto; devuelve si imprimió algo
+func (p *parser) printBuffer() bool {
+	if p.line == nil && len(p.raw) == 0 {
+		return false
+	}
+	p.printLine()
+	return true
+}
+
+// setFormat cierra el fragmento actual y aplica un cambio de formato
+func (p *parser) setFormat(apply func(f *format)) {
+	p.endSpan()
+	apply(&p.state)
+}
+
+func (p *parser) parseESC() {
+	start := p.pos - 1
+	cmd, ok := p.next(1)
+	if !ok {
+		return
+	}
+	switch c := cmd[0]; c {
+	case '@':
+		// Inicializar descarta el buffer de impresión y restablece el formato
+		p.line, p.raw = nil, nil
+		p.state = defaultFormat()
+	case 'a':
+		if n, ok := p.next(1); ok {
+			p.state.align = alignment(n[0])
+			if p.line != nil && len(p.line.Spans) == 0 && len(p.raw) == 0 {
+				p.line.Align = p.state.align
+			}
+		}
+	case 'E', 'G':
+		if n, ok := p.next(1); ok {
+			p.setFormat(func(f *format) { f.bold = n[0]&1 == 1 })
+		}
+	case '-':
+		if n, ok := p.next(1); ok {
+			p.setFormat(func(f *format) { f.underline = n[0]&3 != 0 })
+		}
+	case 'M':
+		if n, ok := p.next(1); ok {
+			p.setFormat(func(f *format) { f.font = font(n[0]) })
+		}
+	case '!':
+		if n, ok := p.next(1); ok {
+			p.setFormat(func(f *format) {
+				f.font = font(n[0] & 1)
+				f.bold = n[0]&0x08 != 0
+				f.height = 1 + int(n[0]>>4&1)
+				f.width = 1 + int(n[0]>>5&1)
+				f.underline = n[0]&0x80 != 0
+			})
+		}
+	case 't':
+		if n, ok := p.next(1); ok {
+			if _, known := codePages[n[0]]; !known {
+				p.doc.warnf(start, "tabla de caracteres ESC t %d no soportada, se usa CP437", n[0])
+			}
+			p.setFormat(func(f *format) { f.charset = int(n[0]) })
+		}
+	case 'd':
+		if n, ok := p.next(1); ok {
+			lines := int(n[0])
+			if p.printBuffer() {
+				lines-- // La línea impresa ya avanzó una
+			}
+			if lines > 0 {
+				p.doc.Blocks = append(p.doc.Blocks, &Feed{Lines: lines})
+			}
+		}
+	case 'J':
+		if n, ok := p.next(1); ok {
+			p.printBuffer()
+			p.doc.Blocks = append(p.doc.Blocks, &Feed{Dots: int(n[0])})
+		}
+	case 'i', 'm':
+		p.printBuffer()
+		p.doc.Blocks = append(p.doc.Blocks, &Cut{Partial: true})
+	case '*':
+		p.skipBitImage(start)
+	default:
+		if n, known := ignoredESC[c]; known {
+			p.next(n)
+			return
+		}
+		p.doc.warnf(start, "comando desconocido ESC 0x%02X", c)
+	}
+}
+
+func (p *parser) parseGS() {
+	start := p.pos - 1
+	cmd, ok := p.next(1)
+	if !ok {
+		return
+	}
+	switch c := cmd[0]; c {
+	case '!':
+		if n, ok := p.next(1); ok {
+			p.setFormat(func(f *format) {
+				f.width = 1 + int(n[0]>>4&7)
+				f.height = 1 + int(n[0]&7)
+			})
+		}
+	case 'V':
+		m, ok := p.next(1)
+		if !ok {
+			return
+		}
+		cut := &Cut{Partial: m[0] == 1 || m[0] == 49 || m[0] == 66 || m[0] == 98 || m[0] == 104}
+		if m[0] >= 65 {
+			n, ok := p.next(1)
+			if !ok {
+				return
+			}
+			cut.Feed = int(n[0])
+		}
+		p.printBuffer()
+		p.doc.Blocks = append(p.doc.Blocks, cut)
+	case 'v':
+		p.parseRaster(start)
+	case '(':
+		p.parseExtended(start)
+	case 'k':
+		p.parseBarcode(start)
+	default:
+		if n, known := ignoredGS[c]; known {
+			p.next(n)
+			return
+		}
+		p.doc.warnf(start, "comando desconocido GS 0x%02X", c)
+	}
+}
+
+func (p *parser) parseDLE() {
+	start := p.pos - 1
+	cmd, ok := p.next(1)
+	if !ok {
+		return
+	}
+	switch cmd[0] {
+	case 0x04, 0x05: // DLE EOT n, DLE ENQ n
+		p.next(1)
+	case 0x14: // DLE DC4 fn a b
+		p.next(3)
+	default:
+		p.doc.warnf(start, "comando desconocido DLE 0x%02X", cmd[0])
+	}
+}
+
+func (p *parser) parseFS() {
+	start := p.pos - 1
+	cmd, ok := p.next(1)
+	if !ok {
+		return
+	}
+	if n, known := ignoredFS[cmd[0]]; known {
+		p.next(n)
+		return
+	}
+	p.doc.warnf(start, "comando desconocido FS 0x%02X", cmd[0])
+}
+
+// parseRaster interpreta GS v 0 m xL xH yL yH d1...dk
+func (p *parser) parseRaster(start int) {
+	head, ok := p.next(6)
+	if !ok {
+		return
+	}
+	if head[0] != '0' {
+		p.doc.warnf(start, "comando desconocido GS v 0x%02X", head[0])
+		return
+	}
+	mode := head[1] & 3
+	widthBytes := int(head[2]) | int(head[3])<<8
+	height := int(head[4]) | int(head[5])<<8
+	data, ok := p.next(widthBytes * height)
+	if !ok {
+		return
+	}
+
+	// Modos de doble ancho (1) y doble alto (2)
+	sx, sy := 1+int(mode&1), 1+int(mode>>1)
+	bitmap := image.NewGray(image.Rect(0, 0, widthBytes*8*sx, height*sy))
+	for i := range bitmap.Pix {
+		bitmap.Pix[i] = 0xFF
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < widthBytes*8; x++ {
+			if data[y*widthBytes+x/8]&(0x80>>(x%8)) == 0 {
+				continue
+			}
+			for dy := 0; dy < sy; dy++ {
+				for dx := 0; dx < sx; dx++ {
+					bitmap.SetGray(x*sx+dx, y*sy+dy, color.Gray{})
+				}
+			}
+		}
+	}
+	p.printBuffer()
+	p.doc.Blocks = append(p.doc.Blocks, &Image{Align: p.state.align, Bitmap: bitmap})
+}
+
+// parseExtended interpreta GS ( X pL pH ...; solo GS ( k para QR tiene efecto
+func (p *parser) parseExtended(start int) {
+	head, ok := p.next(3)
+	if !ok {
+		return
+	}
+	body, ok := p.next(int(head[1]) | int(head[2])<<8)
+	if !ok || head[0] != 'k' {
+		return
+	}
+	if len(body) < 2 {
+		p.doc.warnf(start, "GS ( k sin función")
+		return
+	}
+	cn, fn, params := body[0], body[1], body[2:]
+	if cn != 49 {
+		p.doc.warnf(start, "símbolo 2D GS ( k cn=%d no soportado", cn)
+		return
+	}
+	switch fn {
+	case 67: // Tamaño del módulo
+		if len(params) > 0 {
+			p.qr.ModuleSize = int(params[0])
+		}
+	case 69: // Nivel de corrección
+		if len(params) > 0 && params[0] >= 48 && params[0] <= 51 {
+			p.qr.ErrorCorrection = "LMQH"[params[0]-48]
+		}
+	case 80: // Guardar datos
+		if len(params) > 0 {
+			p.qr.Data = string(params[1:])
+		}
+	case 81: // Imprimir el símbolo guardado
+		p.printBuffer()
+		qr := p.qr
+		qr.Align = p.state.align
+		p.doc.Blocks = append(p.doc.Blocks, &qr)
+	}
+}
+
+// parseBarcode interpreta GS k m d1...dk NUL y GS k m n d1...dn
+func (p *parser) parseBarcode(start int) {
+	m, ok := p.next(1)
+	if !ok {
+		return
+	}
+	var data []byte
+	if m[0] <= 6 {
+		end := p.pos
+		for end < len(p.data) && p.data[end] != nul {
+			end++
+		}
+		if end == len(p.data) {
+			if p.partial {
+				p.short = true
+				return
+			}
+			p.doc.warnf(start, "código de barras sin terminador NUL")
+		}
+		data = p.data[p.pos:end]
+		p.pos = min(end+1, len(p.data))
+	} else {
+		n, ok := p.next(1)
+		if !ok {
+			return
+		}
+		if data, ok = p.next(int(n[0])); !ok {
+			return
+		}
+	}
+	p.printBuffer()
+	p.doc.Blocks = append(p.doc.Blocks, &Barcode{Align: p.state.align, System: m[0], Data: string(data)})
+}
+
+// skipBitImage omite ESC * m nL nH d1...dk, que no se reconstruye
+func (p *parser) skipBitImage(start int) {
+	head, ok := p.next(3)
+	if !ok {
+		return
+	}
+	n := int(head[1]) | int(head[2])<<8
+	if head[0] == 32 || head[0] == 33 {
+		n *= 3
+	}
+	p.doc.warnf(start, "imagen ESC * omitida")
+	p.next(n)
+}
+
+// alignment convierte el parámetro de ESC a
+func alignment(n byte) types.Alignment {
+	switch n {
+	case 1, '1':
+		return types.AlignCenter
+	case 2, '2':
+		return types.AlignRight
+	}
+	return types.AlignLeft
+}
+
+// font convierte el parámetro de ESC M
+func font(n byte) types.Font {
+	if n == 1 || n == '1' {
+		return types.FontB
+	}
+	return types.FontA
+}
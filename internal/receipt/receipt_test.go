@@ -0,0 +1,198 @@
+package receipt
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/AdConDev/pos-printer/profile"
+	"github.com/AdConDev/pos-printer/types"
+	"pos-daemon.adcon.dev/internal/service"
+)
+
+var update = flag.Bool("update", false, "regenerar los archivos golden de testdata")
+
+func TestParseFormatting(t *testing.T) {
+	stream := []byte("\x1b@\x1ba\x01\x1bE\x01TOTAL\x1bE\x00 $10\n" +
+		"\x1d!\x11GRANDE\n" +
+		"\x1bt\x02\xa4and\xa3\n" + // "ñandú" en CP850
+		"\x1bd\x03\x1dVB\x03")
+	doc := Parse(stream)
+	if len(doc.Warnings) > 0 {
+		t.Fatalf("advertencias inesperadas: %v", doc.Warnings)
+	}
+	if len(doc.Blocks) != 5 {
+		t.Fatalf("se esperaban 5 bloques, hay %d", len(doc.Blocks))
+	}
+
+	first := doc.Blocks[0].(*Line)
+	if first.Align != types.AlignCenter || len(first.Spans) != 2 {
+		t.Fatalf("primera línea = %+v", first)
+	}
+	if !first.Spans[0].Bold || first.Spans[0].Text != "TOTAL" || first.Spans[1].Bold {
+		t.Errorf("énfasis mal interpretado: %+v", first.Spans)
+	}
+	if big := doc.Blocks[1].(*Line).Spans[0]; big.Width != 2 || big.Height != 2 {
+		t.Errorf("GS ! 0x11 = %dx%d; want 2x2", big.Width, big.Height)
+	}
+	if got := doc.Blocks[2].(*Line).Text(); got != "ñandú" {
+		t.Errorf("texto CP850 = %q; want %q", got, "ñandú")
+	}
+	if feed := doc.Blocks[3].(*Feed); feed.Lines != 3 {
+		t.Errorf("ESC d 3 = %+v", feed)
+	}
+	if cut := doc.Blocks[4].(*Cut); cut.Feed != 3 || !cut.Partial {
+		t.Errorf("GS V B 3 = %+v", cut)
+	}
+}
+
+func TestParseRasterAndQR(t *testing.T) {
+	// Imagen de 8x2 puntos: primera fila negra, segunda en blanco
+	raster := []byte{0x1d, 'v', '0', 0, 1, 0, 2, 0, 0xFF, 0x00}
+	qr := []byte("\x1d(k\x03\x001C\x06" + "\x1d(k\x03\x001E1" + "\x1d(k\x07\x001P0hola" + "\x1d(k\x03\x001Q0")
+	doc := Parse(append(append([]byte("\x1ba\x01"), raster...), qr...))
+	if len(doc.Warnings) > 0 {
+		t.Fatalf("advertencias inesperadas: %v", doc.Warnings)
+	}
+	if len(doc.Blocks) != 2 {
+		t.Fatalf("se esperaban 2 bloques, hay %d", len(doc.Blocks))
+	}
+
+	img := doc.Blocks[0].(*Image)
+	if img.Bitmap.Rect.Dx() != 8 || img.Bitmap.Rect.Dy() != 2 || img.Align != types.AlignCenter {
+		t.Fatalf("imagen = %v alineada %d", img.Bitmap.Rect, img.Align)
+	}
+	if img.Bitmap.GrayAt(3, 0).Y != 0 || img.Bitmap.GrayAt(3, 1).Y != 0xFF {
+		t.Errorf("píxeles de la imagen mal decodificados")
+	}
+
+	code := doc.Blocks[1].(*QR)
+	if code.Data != "hola" || code.ModuleSize != 6 || code.ErrorCorrection != 'M' {
+		t.Errorf("QR = %+v", code)
+	}
+}
+
+func TestParseWarnings(t *testing.T) {
+	doc := Parse([]byte("\x1b\x7fhola\n\x1d(k\x10\x00"))
+	if len(doc.Warnings) != 2 {
+		t.Fatalf("advertencias = %v; want comando desconocido y truncado", doc.Warnings)
+	}
+	if got := doc.Blocks[0].(*Line).Text(); got != "hola" {
+		t.Errorf("el texto tras un comando desconocido = %q", got)
+	}
+}
+
+func TestStream(t *testing.T) {
+	data := []byte("\x1b@\x1ba\x01\x1bE\x01TOTAL\x1bE\x00 $10\n" +
+		"\x1d(k\x07\x001P0hola\x1d(k\x03\x001Q0" +
+		"\x1dk\x04A-1\x00" +
+		"\x1b*\x00\x02\x00\xff\xff" +
+		"\x1bd\x02\x1dVB\x03" +
+		"\x1b\x7fsin salto")
+	want := Parse(data)
+
+	// Partido en trozos de cualquier tamaño, el resultado es el mismo que el
+	// de Parse, y cada Write devuelve los bloques que completó
+	for size := 1; size <= len(data); size++ {
+		s := NewStream()
+		var blocks []Block
+		for i := 0; i < len(data); i += size {
+			blocks = append(blocks, s.Write(data[i:min(i+size, len(data))])...)
+		}
+		got := s.Close()
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("trozos de %d: documento =\n%+v\nwant\n%+v", size, got, want)
+		}
+		// El texto final sin salto de línea solo se imprime al cerrar
+		if len(blocks) != len(want.Blocks)-1 {
+			t.Errorf("trozos de %d: Write devolvió %d bloques; want %d", size, len(blocks), len(want.Blocks)-1)
+		}
+	}
+}
+
+// TestTicketConstructorGolden fija el recibo que resulta de los bytes que envía
+// TicketConstructor, interpretados de vuelta como texto
+func TestTicketConstructorGolden(t *testing.T) {
+	const fixtures = "../api/rest"
+
+	tests := []struct {
+		name     string
+		ticket   string
+		template string
+		profile  func() *profile.Profile
+		columns  int
+	}{
+		{"new_ticket_80mm", "new_ticket.json", "new_ticket_template.json", profile.CreateProfile80mm, 48},
+		{"ticket_58mm", "ticket.json", "ticket_template_58mm.json", profile.CreateProfile58mm, 32},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ticketData, err := os.ReadFile(filepath.Join(fixtures, tt.ticket))
+			if err != nil {
+				t.Fatalf("error al leer ticket: %v", err)
+			}
+			templateData, err := os.ReadFile(filepath.Join(fixtures, tt.template))
+			if err != nil {
+				t.Fatalf("error al leer plantilla: %v", err)
+			}
+			data, err := service.RenderTicket(templateData, ticketData, tt.profile(), service.TicketOptions{})
+			if err != nil {
+				t.Fatalf("RenderTicket: %v", err)
+			}
+
+			doc := Parse(data)
+			if len(doc.Warnings) > 0 {
+				t.Errorf("advertencias: %v", doc.Warnings)
+			}
+			assertGolden(t, filepath.Join("testdata", tt.name+".txt"), doc.Text(tt.columns))
+
+			html, err := doc.HTML(tt.columns)
+			if err != nil {
+				t.Fatalf("HTML: %v", err)
+			}
+			if !strings.Contains(html, "data:image/png;base64,") {
+				t.Errorf("el HTML no incluye las imágenes del ticket")
+			}
+			var png bytes.Buffer
+			if err := doc.PNG(&png, tt.profile()); err != nil {
+				t.Fatalf("PNG: %v", err)
+			}
+		})
+	}
+}
+
+// assertGolden compara got con el archivo golden o lo regenera con -update
+func assertGolden(t *testing.T, path, got string) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o600); err != nil {
+			t.Fatalf("error al escribir %s: %v", path, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("error al leer %s (use -update para generarlo): %v", path, err)
+	}
+	if got != string(want) {
+		gotLines := strings.Split(got, "\n")
+		wantLines := strings.Split(string(want), "\n")
+		for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+			var g, w string
+			if i < len(gotLines) {
+				g = gotLines[i]
+			}
+			if i < len(wantLines) {
+				w = wantLines[i]
+			}
+			if g != w {
+				t.Fatalf("%s difiere en la línea %d:\n got: %s\nwant: %s\n(use -update si el cambio es intencional)", path, i+1, g, w)
+			}
+		}
+	}
+}
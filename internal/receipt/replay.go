@@ -0,0 +1,129 @@
+package receipt
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+
+	posprinter "github.com/AdConDev/pos-printer"
+	"github.com/AdConDev/pos-printer/imaging"
+	"github.com/AdConDev/pos-printer/profile"
+	"github.com/AdConDev/pos-printer/types"
+	"github.com/skip2/go-qrcode"
+	"pos-daemon.adcon.dev/internal/preview"
+	"pos-daemon.adcon.dev/internal/service"
+)
+
+// Replay vuelve a enviar el documento a una impresora. Sobre un
+// preview.Renderer produce la imagen del recibo; sobre una ESCPOSPrinter lo
+// reimprime tal como se capturó.
+func (d *Document) Replay(printer service.Printer) error {
+	for _, b := range d.Blocks {
+		if err := replayBlock(printer, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PNG dibuja el documento como lo imprimiría una impresora con el perfil indicado
+func (d *Document) PNG(w io.Writer, prof *profile.Profile) error {
+	r := preview.NewRenderer(prof)
+	if err := d.Replay(r); err != nil {
+		return err
+	}
+	return r.WritePNG(w)
+}
+
+func replayBlock(printer service.Printer, b Block) error {
+	switch b := b.(type) {
+	case *Line:
+		if err := printer.SetJustification(b.Align); err != nil {
+			return err
+		}
+		for _, span := range b.Spans {
+			if err := printer.SetFont(span.Font); err != nil {
+				return err
+			}
+			emph := types.EmphOff
+			if span.Bold {
+				emph = types.EmphOn
+			}
+			if err := printer.SetEmphasis(emph); err != nil {
+				return err
+			}
+			if err := printer.SetTextSize(max(span.Width, 1), max(span.Height, 1)); err != nil {
+				return err
+			}
+			if err := printer.Text(span.Text); err != nil {
+				return err
+			}
+		}
+		return printer.TextLn("")
+	case *Image:
+		if err := printer.SetJustification(b.Align); err != nil {
+			return err
+		}
+		return printBitmap(printer, b.Bitmap)
+	case *QR:
+		if err := printer.SetJustification(b.Align); err != nil {
+			return err
+		}
+		img, err := qrImage(b)
+		if err != nil {
+			return err
+		}
+		return printBitmap(printer, img)
+	case *Barcode:
+		if err := printer.SetJustification(b.Align); err != nil {
+			return err
+		}
+		return printer.TextLn(b.Data)
+	case *Feed:
+		if lines := b.Lines + b.Dots/dotsPerTextLine; lines > 0 {
+			return printer.Feed(lines)
+		}
+	case *Cut:
+		return printer.Cut(types.CutFeed, b.Feed)
+	}
+	return nil
+}
+
+// printBitmap imprime una imagen ya en blanco y negro sin redimensionarla
+func printBitmap(printer service.Printer, img image.Image) error {
+	opts := posprinter.DefaultPrintImageOptions()
+	opts.Width = img.Bounds().Dx()
+	opts.DitherMode = imaging.DitherNone
+	return printer.PrintImageWithOptions(img, opts)
+}
+
+// qrImage genera el símbolo QR con el tamaño de módulo indicado en el flujo
+func qrImage(q *QR) (image.Image, error) {
+	levels := map[byte]qrcode.RecoveryLevel{
+		'L': qrcode.Low, 'M': qrcode.Medium, 'Q': qrcode.High, 'H': qrcode.Highest,
+	}
+	code, err := qrcode.New(q.Data, levels[q.ErrorCorrection])
+	if err != nil {
+		return nil, fmt.Errorf("receipt: error al generar QR: %w", err)
+	}
+	code.DisableBorder = true
+
+	bitmap := code.Bitmap()
+	module := max(1, q.ModuleSize)
+	img := image.NewGray(image.Rect(0, 0, len(bitmap)*module, len(bitmap)*module))
+	for y, row := range bitmap {
+		for x, dark := range row {
+			c := color.Gray{Y: 0xFF}
+			if dark {
+				c = color.Gray{}
+			}
+			for dy := 0; dy < module; dy++ {
+				for dx := 0; dx < module; dx++ {
+					img.SetGray(x*module+dx, y*module+dy, c)
+				}
+			}
+		}
+	}
+	return img, nil
+}
@@ -0,0 +1,88 @@
+package receipt
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/AdConDev/pos-printer/types"
+)
+
+// dotsPerTextLine convierte avances en puntos (ESC J) a líneas de texto
+const dotsPerTextLine = 30
+
+// Text representa el documento como texto plano de columns columnas. Cada carácter
+// ocupa una columna multiplicada por su ancho; las diferencias entre Font A y
+// Font B se ignoran. Las imágenes, QR y códigos de barras se muestran como
+// marcadores entre corchetes.
+func (d *Document) Text(columns int) string {
+	var sb strings.Builder
+	for _, b := range d.Blocks {
+		switch b := b.(type) {
+		case *Line:
+			for _, row := range wrapSpans(b.Spans, columns) {
+				writeAligned(&sb, row.text, row.width, b.Align, columns)
+			}
+		case *Image:
+			writeMarker(&sb, fmt.Sprintf("[imagen %dx%d]", b.Bitmap.Rect.Dx(), b.Bitmap.Rect.Dy()), b.Align, columns)
+		case *QR:
+			writeMarker(&sb, "[QR "+b.Data+"]", b.Align, columns)
+		case *Barcode:
+			writeMarker(&sb, "[código de barras "+b.Data+"]", b.Align, columns)
+		case *Feed:
+			sb.WriteString(strings.Repeat("\n", b.Lines+b.Dots/dotsPerTextLine))
+		case *Cut:
+			label := "-- corte "
+			sb.WriteString(label + strings.Repeat("-", max(0, columns-len(label))) + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// textRow es una fila de texto y las columnas que ocupa
+type textRow struct {
+	text  string
+	width int
+}
+
+// wrapSpans divide el texto de una línea en filas de columns columnas,
+// igual que la impresora continúa en la fila siguiente lo que no cabe
+func wrapSpans(spans []Span, columns int) []textRow {
+	var rows []textRow
+	var row strings.Builder
+	used := 0
+	for _, span := range spans {
+		w := max(1, span.Width)
+		for _, ch := range span.Text {
+			if used+w > columns && used > 0 {
+				rows = append(rows, textRow{text: row.String(), width: used})
+				row.Reset()
+				used = 0
+			}
+			row.WriteRune(ch)
+			used += w
+		}
+	}
+	return append(rows, textRow{text: row.String(), width: used})
+}
+
+// writeMarker escribe un marcador de una columna por carácter
+func writeMarker(sb *strings.Builder, s string, align types.Alignment, columns int) {
+	writeAligned(sb, s, utf8.RuneCountInString(s), align, columns)
+}
+
+// writeAligned escribe s, que ocupa width columnas, justificado en columns columnas
+func writeAligned(sb *strings.Builder, s string, width int, align types.Alignment, columns int) {
+	pad := 0
+	if free := columns - width; free > 0 {
+		switch align {
+		case types.AlignCenter:
+			pad = free / 2
+		case types.AlignRight:
+			pad = free
+		}
+	}
+	sb.WriteString(strings.Repeat(" ", pad))
+	sb.WriteString(s)
+	sb.WriteByte('\n')
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// code128Patterns son los anchos de barra y espacio de cada símbolo Code 128,
+// indexados por su valor. El último es el símbolo de paro.
+var code128Patterns = [...]string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+	"221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+	"221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+	"212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+	"231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+	"314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+	"112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+	"214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+	"114131", "311141", "411131", "211412", "211214", "211232", "2331112",
+}
+
+const (
+	code128StartB = 104
+	code128StartC = 105
+	code128Stop   = 106
+
+	// code128Quiet es la zona en blanco a cada lado, en módulos
+	code128Quiet = 10
+)
+
+// code128Values codifica data como valores Code 128, incluidos inicio, dígito
+// verificador y paro. Usa el juego C si data son solo dígitos en número par y
+// el juego B en otro caso.
+func code128Values(data string) ([]int, error) {
+	if data == "" {
+		return nil, fmt.Errorf("code128: datos vacíos")
+	}
+	var values []int
+	if isDigits(data) && len(data)%2 == 0 {
+		values = append(values, code128StartC)
+		for i := 0; i < len(data); i += 2 {
+			values = append(values, int(data[i]-'0')*10+int(data[i+1]-'0'))
+		}
+	} else {
+		values = append(values, code128StartB)
+		for _, r := range data {
+			if r < 32 || r > 126 {
+				return nil, fmt.Errorf("code128: carácter %q fuera del juego B", r)
+			}
+			values = append(values, int(r-32))
+		}
+	}
+	check := values[0]
+	for i, v := range values[1:] {
+		check += (i + 1) * v
+	}
+	return append(values, check%103, code128Stop), nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// code128Modules devuelve el ancho en módulos del código de data, con sus zonas
+// en blanco
+func code128Modules(values []int) int {
+	modules := 2 * code128Quiet
+	for _, v := range values {
+		for _, w := range code128Patterns[v] {
+			modules += int(w - '0')
+		}
+	}
+	return modules
+}
+
+// code128Image dibuja data como código de barras Code 128 con barras de height
+// puntos de alto y módulos de module puntos de ancho
+func code128Image(data string, module, height int) (*image.Gray, error) {
+	values, err := code128Values(data)
+	if err != nil {
+		return nil, err
+	}
+	img := image.NewGray(image.Rect(0, 0, code128Modules(values)*module, height))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	x := code128Quiet * module
+	for _, v := range values {
+		for i, w := range code128Patterns[v] {
+			width := int(w-'0') * module
+			// Los anchos alternan barra y espacio, empezando por barra
+			if i%2 == 0 {
+				for dx := 0; dx < width; dx++ {
+					for y := 0; y < height; y++ {
+						img.SetGray(x+dx, y, color.Gray{})
+					}
+				}
+			}
+			x += width
+		}
+	}
+	return img, nil
+}
@@ -76,3 +76,40 @@ func CountChars(s string) int {
 	}
 	return count
 }
+
+// tabWidth es la distancia entre tabuladores al acomodar texto plano
+const tabWidth = 8
+
+// WrapText acomoda texto plano en líneas de width columnas. Conserva los
+// saltos de línea del original, expande los tabuladores, parte las líneas
+// largas entre palabras y solo corta una palabra, sin guion, si no cabe en una
+// línea.
+// Los caracteres de control se descartan.
+func WrapText(text string, width int) []string {
+	if width <= 0 {
+		return nil
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.TrimRight(text, "\n")
+
+	var lines []string
+	for _, raw := range strings.Split(text, "\n") {
+		// Expandir tabuladores y descartar caracteres de control
+		var b strings.Builder
+		col := 0
+		for _, r := range raw {
+			switch {
+			case r == '\t':
+				n := tabWidth - col%tabWidth
+				b.WriteString(strings.Repeat(" ", n))
+				col += n
+			case r < ' ' || r == 0x7F:
+			default:
+				b.WriteRune(r)
+				col += RuneWidth(r)
+			}
+		}
+		lines = append(lines, wrapWords(b.String(), width, false)...)
+	}
+	return lines
+}
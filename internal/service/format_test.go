@@ -206,3 +206,36 @@ func TestSplitString(t *testing.T) {
 		})
 	}
 }
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		width    int
+		expected []string
+	}{
+		{"Línea corta", "HOLA", 10, []string{"HOLA"}},
+		{"Parte entre palabras", "uno dos tres cuatro", 9, []string{"uno dos", "tres", "cuatro"}},
+		{"Palabra más larga que el ancho", "abcdefghij kl", 4, []string{"abcd", "efgh", "ij", "kl"}},
+		{"Conserva saltos y líneas vacías", "a\r\n\nb\n", 5, []string{"a", "", "b"}},
+		{"Expande tabuladores", "a\tb", 20, []string{"a       b"}},
+		{"Descarta caracteres de control", "a\x1b\x07b", 5, []string{"ab"}},
+		{"Acentos cuentan como un carácter", "AÑO ÚNICO", 4, []string{"AÑO", "ÚNIC", "O"}},
+		{"Ancho inválido", "abc", 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := WrapText(tt.text, tt.width)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("WrapText(%q, %d) = %q; want %q", tt.text, tt.width, result, tt.expected)
+			}
+			for i := range result {
+				if result[i] != tt.expected[i] {
+					t.Errorf("WrapText(%q, %d) = %q; want %q", tt.text, tt.width, result, tt.expected)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,669 @@
+package service
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	posprinter "github.com/AdConDev/pos-printer"
+	"github.com/AdConDev/pos-printer/imaging"
+	"github.com/AdConDev/pos-printer/types"
+	"github.com/skip2/go-qrcode"
+	"pos-daemon.adcon.dev/internal/models"
+)
+
+// defaultLayoutJSON es el acomodo del recibo cuando la plantilla no trae uno
+//
+//go:embed layouts/default.json
+var defaultLayoutJSON []byte
+
+// DefaultLayout devuelve el acomodo por defecto del recibo. Reproduce el ticket
+// clásico y respeta los campos ver_* de la plantilla.
+func DefaultLayout() (*models.Layout, error) {
+	var l models.Layout
+	if err := json.Unmarshal(defaultLayoutJSON, &l); err != nil {
+		return nil, fmt.Errorf("layout: acomodo por defecto inválido: %w", err)
+	}
+	return &l, nil
+}
+
+var (
+	defaultLayoutOnce    sync.Once
+	defaultLayoutProgram *layoutProgram
+	defaultLayoutErr     error
+)
+
+// defaultProgram compila el acomodo por defecto una sola vez
+func defaultProgram() (*layoutProgram, error) {
+	defaultLayoutOnce.Do(func() {
+		l, err := DefaultLayout()
+		if err != nil {
+			defaultLayoutErr = err
+			return
+		}
+		defaultLayoutProgram, defaultLayoutErr = compileLayout(l)
+	})
+	return defaultLayoutProgram, defaultLayoutErr
+}
+
+// layoutData son los datos visibles para las plantillas del acomodo
+type layoutData struct {
+	Ticket   *models.NewTicketData
+	Template *models.NewTicketTemplateData
+	Totals   ticketTotals
+	Reprint  *Reprint
+	Warnings []string // Observaciones de la validación del ticket
+	Item     any      // Elemento de la fila en curso de una tabla
+}
+
+// ticketTotals son los montos calculados a partir de los conceptos y pagos.
+// Las sumas son exactas y se redondean a centavos como los totales del CFDI.
+type ticketTotals struct {
+	Subtotal  models.Decimal
+	Impuestos TaxSummary     // Impuestos agrupados por código, tipo, factor, tasa y entidad
+	Cantidad  models.Decimal // Suma de las cantidades de los conceptos
+
+	Total    models.Decimal // Total del ticket
+	Efectivo models.Decimal // Cantidad de la primera forma de pago
+	Cambio   models.Decimal // Lo pagado menos el total
+}
+
+// computeTotals suma los conceptos del ticket, agrupa sus impuestos y calcula
+// el cambio. Si el ticket no trae total, pagado o cambio se usan los del
+// primer documento de pago.
+func computeTotals(t *models.NewTicketData) ticketTotals {
+	totals := ticketTotals{Impuestos: SummarizeTaxes(t.Conceptos)}
+	for _, conc := range t.Conceptos {
+		totals.Subtotal = totals.Subtotal.Add(conc.Total)
+		totals.Cantidad = totals.Cantidad.Add(conc.Cantidad)
+	}
+	totals.Subtotal = totals.Subtotal.Round(LenDecimales)
+
+	totals.Total = t.Total
+	paid, change := t.Pagado, t.Cambio
+	if len(t.DocumentosPago) > 0 {
+		pago := t.DocumentosPago[0]
+		if totals.Total.Sign() == 0 {
+			totals.Total = pago.Total
+		}
+		if paid.Sign() == 0 {
+			paid = pago.Total
+		}
+		if change.Sign() == 0 {
+			change = pago.Cambio
+		}
+		if len(pago.FormasPago) > 0 {
+			totals.Efectivo = pago.FormasPago[0].Cantidad
+		}
+	}
+	totals.Total = totals.Total.Round(LenDecimales)
+	// El cambio se recalcula con los montos exactos; si lo pagado no alcanza
+	// para calcularlo se usa el que envió el ERP
+	if c := paid.Round(LenDecimales).Sub(totals.Total); c.Sign() > 0 {
+		totals.Cambio = c
+	} else if change.Sign() > 0 {
+		totals.Cambio = change.Round(LenDecimales)
+	}
+	return totals
+}
+
+// layoutFuncs son las funciones disponibles en las plantillas del acomodo
+var layoutFuncs = template.FuncMap{
+	// money formatea un monto con dos decimales
+	"money": func(d models.Decimal) string { return d.StringFixed(LenDecimales) },
+	// qty formatea una cantidad sin ceros de sobra
+	"qty":  models.Decimal.String,
+	"join": strings.Join,
+	"mul":  func(a, b int) int { return a * b },
+	// textsize convierte un tamaño de la plantilla (razon_social_size,
+	// datos_size) en la ampliación de los caracteres
+	"textsize": TextSize,
+}
+
+// TextSize convierte un tamaño en puntos de la plantilla en la ampliación de
+// los caracteres: 10 o menos es el tamaño normal y cada 10 puntos más amplían
+// una vez, hasta 8.
+func TextSize(points int) int {
+	return min(max((points+9)/10, 1), 8)
+}
+
+// parseTextSize lee una ampliación "N" o "AxH" con valores de 1 a 8
+func parseTextSize(s string) (width, height int, err error) {
+	w, h, found := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "x")
+	width, err = strconv.Atoi(w)
+	if err != nil {
+		return 0, 0, fmt.Errorf("tamaño de texto inválido %q", s)
+	}
+	height = width
+	if found {
+		if height, err = strconv.Atoi(h); err != nil {
+			return 0, 0, fmt.Errorf("tamaño de texto inválido %q", s)
+		}
+	}
+	if width < 1 || width > 8 || height < 1 || height > 8 {
+		return 0, 0, fmt.Errorf("tamaño de texto %q fuera de 1 a 8", s)
+	}
+	return width, height, nil
+}
+
+// layoutProgram es un acomodo con sus plantillas ya compiladas
+type layoutProgram struct {
+	layout    *models.Layout
+	templates map[string]*template.Template // Por texto fuente
+	conds     map[string]*template.Template // Por expresión
+}
+
+// compileLayout valida el acomodo y compila sus plantillas
+func compileLayout(l *models.Layout) (*layoutProgram, error) {
+	p := &layoutProgram{
+		layout:    l,
+		templates: make(map[string]*template.Template),
+		conds:     make(map[string]*template.Template),
+	}
+	for i := range l.Sections {
+		s := &l.Sections[i]
+		if err := p.compileCond(s.If); err != nil {
+			return nil, fmt.Errorf("layout: sección %q: %w", s.Name, err)
+		}
+		if err := p.compileStyle(s.Style); err != nil {
+			return nil, fmt.Errorf("layout: sección %q: %w", s.Name, err)
+		}
+		for j := range s.Elements {
+			if err := p.compileElement(&s.Elements[j]); err != nil {
+				return nil, fmt.Errorf("layout: sección %q: %w", s.Name, err)
+			}
+		}
+	}
+	return p, nil
+}
+
+func (p *layoutProgram) compileElement(e *models.LayoutElement) error {
+	if err := p.compileCond(e.If); err != nil {
+		return err
+	}
+	if err := p.compileStyle(e.Style); err != nil {
+		return err
+	}
+	switch e.Type {
+	case models.LayoutText:
+		if err := p.compileText(e.Text); err != nil {
+			return err
+		}
+		for _, span := range e.Spans {
+			if err := p.compileCond(span.If); err != nil {
+				return err
+			}
+			if err := p.compileStyle(span.Style); err != nil {
+				return err
+			}
+			if err := p.compileText(span.Text); err != nil {
+				return err
+			}
+		}
+	case models.LayoutBlock:
+		for i := range e.Elements {
+			if err := p.compileElement(&e.Elements[i]); err != nil {
+				return err
+			}
+		}
+	case models.LayoutFeed:
+	case models.LayoutTable:
+		if _, err := fieldType(reflect.TypeOf(layoutData{}), e.Rows, reflect.Slice); err != nil {
+			return fmt.Errorf("tabla: %w", err)
+		}
+		if err := p.compileStyle(e.HeaderStyle); err != nil {
+			return err
+		}
+		for _, c := range e.Columns {
+			if err := p.compileCond(c.If); err != nil {
+				return err
+			}
+			if err := p.compileText(c.Text); err != nil {
+				return err
+			}
+			if _, err := alignFunc(c.Align); err != nil {
+				return fmt.Errorf("columna %q: %w", c.Name, err)
+			}
+			if c.Width < 0 || c.MinWidth < 0 || c.MaxWidth < 0 || c.Grow < 0 || c.Padding < 0 {
+				return fmt.Errorf("columna %q: anchos negativos", c.Name)
+			}
+			switch c.Overflow {
+			case "", models.OverflowTruncate, models.OverflowWrap, models.OverflowScroll:
+			default:
+				return fmt.Errorf("columna %q: overflow desconocido %q", c.Name, c.Overflow)
+			}
+			if c.MaxWidth > 0 && c.MinWidth > c.MaxWidth {
+				return fmt.Errorf("columna %q: min_width %d mayor que max_width %d", c.Name, c.MinWidth, c.MaxWidth)
+			}
+		}
+	case models.LayoutImage:
+		if e.Path == "" {
+			return fmt.Errorf("imagen sin archivo")
+		}
+		if err := p.compileText(e.Width); err != nil {
+			return err
+		}
+	case models.LayoutQR:
+		if err := p.compileText(e.Data); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("tipo de elemento desconocido %q", e.Type)
+	}
+	return nil
+}
+
+// compileText compila un texto si contiene acciones de plantilla
+func (p *layoutProgram) compileText(src string) error {
+	if !strings.Contains(src, "{{") || p.templates[src] != nil {
+		return nil
+	}
+	t, err := template.New("text").Funcs(layoutFuncs).Parse(src)
+	if err != nil {
+		return err
+	}
+	p.templates[src] = t
+	return nil
+}
+
+// compileCond compila una condición: una expresión de plantilla que se evalúa
+// como lo haría {{if}}
+func (p *layoutProgram) compileCond(expr string) error {
+	if expr == "" || p.conds[expr] != nil {
+		return nil
+	}
+	t, err := template.New("if").Funcs(layoutFuncs).Parse("{{if " + expr + "}}1{{end}}")
+	if err != nil {
+		return err
+	}
+	p.conds[expr] = t
+	return nil
+}
+
+// text evalúa un texto del acomodo. Un error se registra y deja el texto vacío.
+func (p *layoutProgram) text(src string, data *layoutData) string {
+	t := p.templates[src]
+	if t == nil {
+		return src
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		log.Printf("layout: error al evaluar %q: %v", src, err)
+		return ""
+	}
+	return b.String()
+}
+
+// cond evalúa una condición; vacía es verdadera
+func (p *layoutProgram) cond(expr string, data *layoutData) bool {
+	if expr == "" {
+		return true
+	}
+	var b strings.Builder
+	if err := p.conds[expr].Execute(&b, data); err != nil {
+		log.Printf("layout: error al evaluar la condición %q: %v", expr, err)
+		return false
+	}
+	return b.Len() > 0
+}
+
+// fieldType sigue una ruta de campos (ej. ".Ticket.Conceptos") desde t y
+// verifica que termine en un valor de tipo kind
+func fieldType(t reflect.Type, path string, kind reflect.Kind) (reflect.Type, error) {
+	for _, name := range strings.Split(strings.TrimPrefix(path, "."), ".") {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("%q: %s no tiene campos", path, t)
+		}
+		f, ok := t.FieldByName(name)
+		if !ok || !f.IsExported() {
+			return nil, fmt.Errorf("%q: campo %q desconocido", path, name)
+		}
+		t = f.Type
+	}
+	if t.Kind() != kind {
+		return nil, fmt.Errorf("%q no es %s", path, kind)
+	}
+	return t, nil
+}
+
+// fieldValue sigue una ruta de campos ya validada con fieldType
+func fieldValue(v reflect.Value, path string) reflect.Value {
+	for _, name := range strings.Split(strings.TrimPrefix(path, "."), ".") {
+		for v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		}
+		v = v.FieldByName(name)
+	}
+	return v
+}
+
+// compileStyle valida los valores de un estilo y compila su tamaño si es una
+// plantilla
+func (p *layoutProgram) compileStyle(s models.LayoutStyle) error {
+	if _, err := justification(s.Align); err != nil {
+		return err
+	}
+	if _, err := font(s.Font); err != nil {
+		return err
+	}
+	if strings.Contains(s.Size, "{{") {
+		return p.compileText(s.Size)
+	}
+	if s.Size != "" {
+		if _, _, err := parseTextSize(s.Size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// style evalúa el tamaño de un estilo con los datos del ticket
+func (r *layoutRenderer) style(s models.LayoutStyle) models.LayoutStyle {
+	if s.Size != "" {
+		s.Size = r.program.text(s.Size, r.data)
+	}
+	return s
+}
+
+func justification(align string) (types.Alignment, error) {
+	switch strings.ToLower(align) {
+	case "", "left":
+		return types.AlignLeft, nil
+	case "center":
+		return types.AlignCenter, nil
+	case "right":
+		return types.AlignRight, nil
+	}
+	return 0, fmt.Errorf("alineación desconocida %q", align)
+}
+
+func font(name string) (types.Font, error) {
+	switch strings.ToUpper(name) {
+	case "", "A":
+		return types.FontA, nil
+	case "B":
+		return types.FontB, nil
+	}
+	return 0, fmt.Errorf("fuente desconocida %q", name)
+}
+
+// alignFunc devuelve la función que acomoda el texto de una columna en su ancho
+func alignFunc(align string) (func(string, int) string, error) {
+	switch strings.ToLower(align) {
+	case "", "left":
+		return alignLeft, nil
+	case "center":
+		return alignCenter, nil
+	case "right":
+		return alignRight, nil
+	}
+	return nil, fmt.Errorf("alineación desconocida %q", align)
+}
+
+// textStyle es el estado de texto de la impresora
+type textStyle struct {
+	bold   bool
+	font   types.Font
+	width  int // Ampliación de ancho; 0 equivale a 1
+	height int // Ampliación de alto; 0 equivale a 1
+}
+
+// layoutWriter envía el acomodo a la impresora. Retrasa la restauración del
+// estilo hasta el siguiente comando, para que una línea termine con TextLn
+// antes de restaurarlo.
+type layoutWriter struct {
+	printer Printer
+	style   textStyle
+	line    strings.Builder
+	pending []func() // Restauraciones de estilo aún no enviadas
+}
+
+// sync envía el texto acumulado y las restauraciones pendientes
+func (w *layoutWriter) sync() {
+	if w.line.Len() > 0 {
+		if err := w.printer.Text(w.line.String()); err != nil {
+			log.Printf("ticket_printer: error al imprimir texto: %v", err)
+		}
+		w.line.Reset()
+	}
+	for _, restore := range w.pending {
+		restore()
+	}
+	w.pending = w.pending[:0]
+}
+
+// push aplica un estilo y devuelve cómo restaurar el énfasis, la fuente y el
+// tamaño. El tamaño ya debe estar evaluado; uno inválido se registra y se omite.
+func (w *layoutWriter) push(s models.LayoutStyle) []func() {
+	if s.Align == "" && s.Bold == nil && s.Font == "" && s.Size == "" {
+		return nil
+	}
+	w.sync()
+	var restores []func()
+	if s.Align != "" {
+		align, _ := justification(s.Align)
+		if err := w.printer.SetJustification(align); err != nil {
+			log.Printf("Error al establecer justificación: %v", err)
+		}
+	}
+	if s.Bold != nil {
+		prev := w.style.bold
+		w.setBold(*s.Bold)
+		if prev != *s.Bold {
+			restores = append(restores, func() { w.setBold(prev) })
+		}
+	}
+	if s.Font != "" {
+		prev := w.style.font
+		f, _ := font(s.Font)
+		w.setFont(f)
+		if prev != f {
+			restores = append(restores, func() { w.setFont(prev) })
+		}
+	}
+	if s.Size != "" {
+		width, height, err := parseTextSize(s.Size)
+		if err != nil {
+			log.Printf("layout: %v", err)
+		} else if prevW, prevH := w.size(); prevW != width || prevH != height {
+			if w.setSize(width, height) {
+				restores = append(restores, func() { w.setSize(prevW, prevH) })
+			}
+		}
+	}
+	return restores
+}
+
+// pop deja pendientes las restauraciones de push, en orden inverso
+func (w *layoutWriter) pop(restores []func()) {
+	for i := len(restores) - 1; i >= 0; i-- {
+		w.pending = append(w.pending, restores[i])
+	}
+}
+
+func (w *layoutWriter) setBold(on bool) {
+	mode := types.EmphOff
+	if on {
+		mode = types.EmphOn
+	}
+	if err := w.printer.SetEmphasis(mode); err != nil {
+		log.Printf("Error al establecer énfasis: %v", err)
+	}
+	w.style.bold = on
+}
+
+func (w *layoutWriter) setFont(f types.Font) {
+	if err := w.printer.SetFont(f); err != nil {
+		log.Printf("Error al establecer fuente: %v", err)
+	}
+	w.style.font = f
+}
+
+// setSize amplía los caracteres; si la impresora no lo permite el tamaño no
+// cambia y devuelve false
+func (w *layoutWriter) setSize(width, height int) bool {
+	if err := w.printer.SetTextSize(width, height); err != nil {
+		log.Printf("Error al establecer tamaño de texto: %v", err)
+		return false
+	}
+	w.style.width, w.style.height = width, height
+	return true
+}
+
+// size devuelve la ampliación en curso
+func (w *layoutWriter) size() (width, height int) {
+	return max(w.style.width, 1), max(w.style.height, 1)
+}
+
+// write agrega texto a la línea en curso
+func (w *layoutWriter) write(s string) {
+	if len(w.pending) > 0 {
+		w.sync()
+	}
+	w.line.WriteString(s)
+}
+
+// columns devuelve cuántos caracteres caben en una línea con la fuente y la
+// ampliación en curso
+func (w *layoutWriter) columns() int {
+	width, _ := w.size()
+	return FontColumns(w.printer.GetProfile(), w.style.font) / width
+}
+
+// endLine termina la línea en curso
+func (w *layoutWriter) endLine() {
+	if err := w.printer.TextLn(w.line.String()); err != nil {
+		log.Printf("ticket_printer: error al imprimir texto: %v", err)
+	}
+	w.line.Reset()
+}
+
+func (w *layoutWriter) feed(lines int) {
+	w.sync()
+	if err := w.printer.Feed(lines); err != nil {
+		log.Printf("Error al alimentar papel: %v", err)
+	}
+}
+
+// layoutRenderer imprime un acomodo con los datos de un ticket
+type layoutRenderer struct {
+	program *layoutProgram
+	w       *layoutWriter
+	data    *layoutData
+}
+
+// render imprime todas las secciones del acomodo
+func (r *layoutRenderer) render() {
+	for _, s := range r.program.layout.Sections {
+		if !r.program.cond(s.If, r.data) {
+			continue
+		}
+		restores := r.w.push(r.style(s.Style))
+		for i := range s.Elements {
+			r.element(&s.Elements[i])
+		}
+		r.w.pop(restores)
+	}
+	r.w.sync()
+}
+
+func (r *layoutRenderer) element(e *models.LayoutElement) {
+	if !r.program.cond(e.If, r.data) {
+		return
+	}
+	restores := r.w.push(r.style(e.Style))
+	defer r.w.pop(restores)
+
+	switch e.Type {
+	case models.LayoutText:
+		if len(e.Spans) == 0 {
+			r.w.write(r.program.text(e.Text, r.data))
+		}
+		var spans []*models.LayoutSpan
+		for i := range e.Spans {
+			if r.program.cond(e.Spans[i].If, r.data) {
+				spans = append(spans, &e.Spans[i])
+			}
+		}
+		// Cada parte se envía por separado; la última termina la línea
+		for i, span := range spans {
+			spanRestores := r.w.push(r.style(span.Style))
+			r.w.write(r.program.text(span.Text, r.data))
+			if i < len(spans)-1 {
+				r.w.sync()
+			}
+			r.w.pop(spanRestores)
+		}
+		r.w.endLine()
+	case models.LayoutBlock:
+		for i := range e.Elements {
+			r.element(&e.Elements[i])
+		}
+	case models.LayoutFeed:
+		r.w.feed(e.Lines)
+	case models.LayoutTable:
+		r.table(e)
+	case models.LayoutImage:
+		r.image(e)
+	case models.LayoutQR:
+		r.qr(e)
+	}
+}
+
+// image imprime una imagen con tramado; si no se puede cargar se omite
+func (r *layoutRenderer) image(e *models.LayoutElement) {
+	r.w.sync()
+	img, err := imaging.LoadImage(e.Path)
+	if err != nil {
+		log.Printf("ticket_printer: error cargando imagen del logo: %v", err)
+		return
+	}
+	width, err := strconv.Atoi(strings.TrimSpace(r.program.text(e.Width, r.data)))
+	if err != nil && e.Width != "" {
+		log.Printf("ticket_printer: ancho de imagen inválido %q: %v", e.Width, err)
+	}
+	if e.Margin > 0 {
+		r.w.feed(e.Margin)
+	}
+	opts := posprinter.PrintImageOptions{
+		Density:    types.DensitySingle,
+		DitherMode: imaging.DitherFloydSteinberg,
+		Threshold:  128,
+		Width:      width,
+	}
+	if err := r.w.printer.PrintImageWithOptions(img, opts); err != nil {
+		log.Printf("ticket_printer: error al imprimir logo con dithering: %v", err)
+	}
+	if e.Margin > 0 {
+		r.w.feed(e.Margin)
+	}
+}
+
+// qr imprime un código QR
+func (r *layoutRenderer) qr(e *models.LayoutElement) {
+	r.w.sync()
+	qr, err := qrcode.New(r.program.text(e.Data, r.data), qrcode.Medium)
+	if err != nil {
+		log.Printf("Error generando QR: %v", err)
+		return
+	}
+	size := e.Size
+	if size <= 0 {
+		size = 256
+	}
+	if err := r.w.printer.PrintImage(qr.Image(size)); err != nil {
+		log.Printf("Error al imprimir QR: %v", err)
+	}
+}
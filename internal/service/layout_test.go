@@ -0,0 +1,220 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/AdConDev/pos-printer/profile"
+
+	"pos-daemon.adcon.dev/internal/models"
+)
+
+// printWithLayout imprime new_ticket.json con la plantilla de 80mm y el
+// acomodo indicado
+func printWithLayout(t *testing.T, layout string) []string {
+	t.Helper()
+	return printWithLayoutOn(t, layout, profile.CreateProfile80mm())
+}
+
+// printWithLayoutOn imprime el acomodo con una plantilla de 80mm en una
+// impresora con el perfil dado
+func printWithLayoutOn(t *testing.T, layout string, prof *profile.Profile) []string {
+	t.Helper()
+	ticketData, err := os.ReadFile("../api/rest/new_ticket.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	templateData := []byte(`{"data": {"ticket_width": "80", "ver_precio_u": "1", "cambiar_pie": "Gracias", "layout": ` + layout + `}}`)
+
+	rec := newRecordingPrinter(prof)
+	tc := NewTicketConstructor(io.Discard, rec)
+	if err := tc.LoadTemplateFromJSON(templateData); err != nil {
+		t.Fatalf("LoadTemplateFromJSON: %v", err)
+	}
+	if err := tc.LoadTicketFromJSON(ticketData); err != nil {
+		t.Fatalf("LoadTicketFromJSON: %v", err)
+	}
+	if err := tc.PrintTicket(); err != nil {
+		t.Fatalf("PrintTicket: %v", err)
+	}
+	return rec.lines
+}
+
+func TestCustomLayout(t *testing.T) {
+	lines := printWithLayout(t, `{"sections": [
+		{"name": "header", "style": {"align": "left"}, "elements": [
+			{"type": "text", "spans": [
+				{"text": "Folio: ", "style": {"bold": true}},
+				{"text": "{{.Ticket.Serie}}-{{.Ticket.Folio}}"}
+			]},
+			{"type": "text", "if": ".Template.VerFolio", "text": "no se imprime"},
+			{"type": "text", "style": {"bold": true, "font": "B"}, "text": "{{.Template.CambiarPie}}"}
+		]},
+		{"name": "items", "elements": [
+			{"type": "table", "rows": ".Ticket.Conceptos", "columns": [
+				{"name": "cant", "text": "{{qty .Item.Cantidad}}", "width": 3, "align": "right"},
+				{"name": "oculta", "text": "x", "if": "not .Template.VerPrecioU", "width": 5},
+				{"name": "desc", "text": "{{.Item.Descripcion}}", "width": 10, "overflow": "wrap", "grow": 1, "max_width": 16},
+				{"name": "total", "text": "{{money .Item.Total}}", "width": 10, "align": "right"}
+			]}
+		]},
+		{"name": "omitida", "if": ".Reprint", "elements": [{"type": "text", "text": "COPIA"}]}
+	]}`)
+
+	want := []string{
+		`justify center`,
+		`font A`,
+		`justify left`,
+		`emphasis on`,
+		`text "Folio: "`,
+		`emphasis off`,
+		`textln "ABC1-326"`,
+		`emphasis on`,
+		`font B`,
+		`textln "Gracias"`,
+		`font A`,
+		`emphasis off`,
+	}
+	got := strings.Join(lines, "\n")
+	if !strings.HasPrefix(got, strings.Join(want, "\n")) {
+		t.Fatalf("inicio =\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+	// La descripción crece hasta su máximo y se parte en filas
+	if !strings.Contains(got, `textln "  1ESTUCO ACRILICO      99.99"`+"\n"+`textln "   UV RD                     "`) {
+		t.Errorf("tabla =\n%s", got)
+	}
+	if strings.Contains(got, "no se imprime") || strings.Contains(got, "COPIA") {
+		t.Errorf("se imprimieron elementos con condición falsa:\n%s", got)
+	}
+}
+
+func TestLayoutTextSize(t *testing.T) {
+	lines := printWithLayout(t, `{"sections": [
+		{"name": "header", "style": {"size": "{{textsize 20}}"}, "elements": [
+			{"type": "text", "text": "GRANDE"},
+			{"type": "text", "style": {"size": "1x2"}, "text": "ALTO"},
+			{"type": "table", "rows": ".Ticket.Conceptos", "columns": [
+				{"name": "desc", "text": "{{.Item.Descripcion}}", "min_width": 10}
+			]}
+		]},
+		{"name": "footer", "elements": [{"type": "text", "text": "NORMAL"}]}
+	]}`)
+	got := strings.Join(lines, "\n")
+	want := strings.Join([]string{
+		`size 2x2`,
+		`textln "GRANDE"`,
+		`size 1x2`,
+		`textln "ALTO"`,
+		`size 2x2`,
+		// Con doble ancho caben 24 caracteres de los 48 de la línea
+		`textln "Producto con Series 2   "`,
+		`textln "MANTENIMIENTO OTROS C..."`,
+	}, "\n")
+	if !strings.Contains(got, want) {
+		t.Errorf("comandos =\n%s\nwant\n%s", got, want)
+	}
+	if !strings.Contains(got, "size 1x1\ntextln \"NORMAL\"") {
+		t.Errorf("no se restauró el tamaño:\n%s", got)
+	}
+}
+
+func TestLayoutErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		layout string
+	}{
+		{"tipo desconocido", `{"sections": [{"elements": [{"type": "marquee"}]}]}`},
+		{"plantilla inválida", `{"sections": [{"elements": [{"type": "text", "text": "{{.Ticket.Folio"}]}]}`},
+		{"condición inválida", `{"sections": [{"if": "and (", "elements": []}]}`},
+		{"filas desconocidas", `{"sections": [{"elements": [{"type": "table", "rows": ".Ticket.Renglones"}]}]}`},
+		{"filas que no son lista", `{"sections": [{"elements": [{"type": "table", "rows": ".Ticket.Folio"}]}]}`},
+		{"alineación desconocida", `{"sections": [{"style": {"align": "middle"}, "elements": []}]}`},
+		{"tamaño fuera de rango", `{"sections": [{"style": {"size": "9"}, "elements": []}]}`},
+		{"tamaño inválido", `{"sections": [{"style": {"size": "doble"}, "elements": []}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := NewTicketConstructor(io.Discard, nil)
+			err := tc.LoadTemplateFromJSON([]byte(`{"data": {"ticket_width": "80", "layout": ` + tt.layout + `}}`))
+			if err == nil {
+				t.Error("se aceptó un acomodo inválido")
+			}
+		})
+	}
+}
+
+func TestDefaultLayout(t *testing.T) {
+	l, err := DefaultLayout()
+	if err != nil {
+		t.Fatalf("DefaultLayout: %v", err)
+	}
+	if _, err := compileLayout(l); err != nil {
+		t.Fatalf("compileLayout: %v", err)
+	}
+
+	// Una plantilla con el acomodo por defecto explícito imprime lo mismo que
+	// una sin acomodo
+	data, err := json.Marshal(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	explicit := printWithLayout(t, string(data))
+	implicit := printWithLayout(t, "null")
+	if strings.Join(explicit, "\n") != strings.Join(implicit, "\n") {
+		t.Error("el acomodo por defecto explícito imprime distinto")
+	}
+}
+
+func TestComputeTotals(t *testing.T) {
+	d := models.MustDecimal
+	conceptos := []models.Concepto{
+		{Cantidad: d("1"), Total: d("0.1")},
+		{Cantidad: d("0.5"), Total: d("0.2")},
+	}
+	tests := []struct {
+		name   string
+		ticket models.NewTicketData
+		total  string
+		cambio string
+	}{
+		{
+			"Cambio calculado",
+			models.NewTicketData{TicketData: models.TicketData{Total: d("37951.8"), Pagado: d("38000"), Cambio: d("48.199999999997")}},
+			"37951.80", "48.20",
+		},
+		{
+			"Sin total ni pagado",
+			models.NewTicketData{TicketData: models.TicketData{
+				DocumentosPago: []models.DocumentoPago{{Total: d("10"), Cambio: d("0.5")}},
+			}},
+			"10.00", "0.50",
+		},
+		{
+			"Pago exacto",
+			models.NewTicketData{TicketData: models.TicketData{Total: d("10"), Pagado: d("10")}},
+			"10.00", "0.00",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.ticket.Conceptos = conceptos
+			totals := computeTotals(&tt.ticket)
+			// 0.1 + 0.2 suma exactamente 0.30
+			if got := totals.Subtotal.StringFixed(2); got != "0.30" {
+				t.Errorf("Subtotal = %s", got)
+			}
+			if got := totals.Cantidad.String(); got != "1.5" {
+				t.Errorf("Cantidad = %s", got)
+			}
+			if got := totals.Total.StringFixed(2); got != tt.total {
+				t.Errorf("Total = %s; want %s", got, tt.total)
+			}
+			if got := totals.Cambio.StringFixed(2); got != tt.cambio {
+				t.Errorf("Cambio = %s; want %s", got, tt.cambio)
+			}
+		})
+	}
+}
@@ -0,0 +1,607 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	fp "path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	posprinter "github.com/AdConDev/pos-printer"
+	"github.com/AdConDev/pos-printer/imaging"
+	"github.com/AdConDev/pos-printer/types"
+	"github.com/skip2/go-qrcode"
+	"pos-daemon.adcon.dev/internal/models"
+)
+
+// ReceiptExt es la extensión de los archivos de recibos libres
+const ReceiptExt = ".tmpl"
+
+// ErrReceiptNotFound indica que no hay un recibo libre con el nombre pedido
+var ErrReceiptNotFound = errors.New("recibo no encontrado")
+
+// markupTag describe una etiqueta de los recibos libres
+type markupTag struct {
+	container bool     // Encierra contenido y se cierra con </tag>
+	data      bool     // Su contenido son datos (qr, barcode), no texto con formato
+	block     bool     // Ocupa líneas completas; se ignora el salto de línea que la sigue
+	attrs     []string // Atributos admitidos
+}
+
+// markupTags son las etiquetas reconocidas. Cualquier otro texto entre < y >
+// se imprime tal cual.
+var markupTags = map[string]markupTag{
+	"b":       {container: true},
+	"double":  {container: true},
+	"center":  {container: true, block: true},
+	"qr":      {container: true, data: true, block: true, attrs: []string{"size"}},
+	"barcode": {container: true, data: true, block: true, attrs: []string{"height", "module"}},
+	"img":     {block: true, attrs: []string{"src", "width"}},
+	"feed":    {block: true, attrs: []string{"lines"}},
+	"cut":     {block: true},
+}
+
+// numericAttrs son los atributos que deben ser enteros positivos
+var numericAttrs = map[string]bool{"size": true, "height": true, "module": true, "width": true, "lines": true}
+
+var (
+	// tagPattern reconoce <tag attr="valor">, </tag> y <tag/>
+	tagPattern  = regexp.MustCompile(`<(/?)([a-zA-Z][a-zA-Z0-9]*)((?:\s+[a-zA-Z]+="[^"]*")*)\s*(/?)>`)
+	attrPattern = regexp.MustCompile(`([a-zA-Z]+)="([^"]*)"`)
+	// looseTagPattern reconoce cualquier cosa con forma de etiqueta, para
+	// reportar etiquetas mal escritas al cargar el recibo
+	looseTagPattern = regexp.MustCompile(`</?([a-zA-Z][a-zA-Z0-9]*)\b[^<>]*>`)
+)
+
+// Los valores que escriben las acciones de la plantilla ({{.Folio}}) llevan
+// < y > cambiados por estas runas de uso privado, para que los datos del
+// ticket no se lean como etiquetas; el texto las recupera al armar el árbol
+const (
+	escapedLT = '\uE000'
+	escapedGT = '\uE001'
+)
+
+var (
+	markupEscaper   = strings.NewReplacer("<", string(escapedLT), ">", string(escapedGT))
+	markupUnescaper = strings.NewReplacer(string(escapedLT), "<", string(escapedGT), ">")
+)
+
+// markupEscapeFunc es la función que se agrega al final de cada acción
+const markupEscapeFunc = "markupEscape"
+
+// markupEscape convierte el valor de una acción en texto sin etiquetas
+func markupEscape(v any) string {
+	return markupEscaper.Replace(fmt.Sprint(v))
+}
+
+// Receipt es un recibo libre: una plantilla de text/template sobre los datos
+// del ticket cuyo resultado lleva etiquetas de formato (<b>, <center>,
+// <double>, <qr>, <barcode>, <img>, <feed> y <cut>)
+type Receipt struct {
+	name string
+	dir  string // Base de las rutas relativas de <img>
+	tmpl *template.Template
+}
+
+// ParseReceipt compila un recibo libre. Valida la sintaxis de la plantilla y
+// las etiquetas escritas en ella; el anidamiento de las etiquetas se valida al
+// imprimir. Las etiquetas solo pueden venir del texto de la plantilla: lo que
+// escriben las acciones se imprime tal cual, aunque parezca una etiqueta.
+func ParseReceipt(name, src string) (*Receipt, error) {
+	tmpl, err := template.New(name).
+		Funcs(layoutFuncs).
+		Funcs(template.FuncMap{markupEscapeFunc: markupEscape}).
+		Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("recibo %s: %w", name, err)
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree == nil {
+			continue
+		}
+		if err := checkMarkupNode(t.Tree.Root); err != nil {
+			return nil, fmt.Errorf("recibo %s: %w", name, err)
+		}
+		escapeActions(t.Tree, t.Tree.Root)
+	}
+	return &Receipt{name: name, tmpl: tmpl}, nil
+}
+
+// ParseReceiptFile compila el recibo de path. Su nombre es el del archivo sin
+// extensión y las imágenes relativas parten de su directorio.
+func ParseReceiptFile(path string) (*Receipt, error) {
+	src, err := os.ReadFile(fp.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("error al leer %s: %w", path, err)
+	}
+	receipt, err := ParseReceipt(strings.TrimSuffix(fp.Base(path), fp.Ext(path)), string(src))
+	if err != nil {
+		return nil, err
+	}
+	receipt.dir = fp.Dir(path)
+	return receipt, nil
+}
+
+// Name devuelve el nombre del recibo
+func (r *Receipt) Name() string {
+	return r.name
+}
+
+// checkMarkupNode revisa las etiquetas del texto literal de la plantilla
+func checkMarkupNode(node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.TextNode:
+		for _, m := range looseTagPattern.FindAllSubmatch(n.Text, -1) {
+			if err := checkTag(string(m[0]), string(m[1])); err != nil {
+				return err
+			}
+		}
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkMarkupNode(child); err != nil {
+				return err
+			}
+		}
+	case *parse.IfNode:
+		return checkBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		return checkBranch(&n.BranchNode)
+	case *parse.WithNode:
+		return checkBranch(&n.BranchNode)
+	}
+	return nil
+}
+
+// escapeActions agrega markupEscape al final de cada acción que escribe un
+// valor, como hace html/template con su escape
+func escapeActions(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ActionNode:
+		// Las asignaciones ({{$x := .Folio}}) no escriben nada
+		if len(n.Pipe.Decl) > 0 {
+			return
+		}
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      n.Pos,
+			Args:     []parse.Node{parse.NewIdentifier(markupEscapeFunc).SetTree(tree).SetPos(n.Pos)},
+		})
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			escapeActions(tree, child)
+		}
+	case *parse.IfNode:
+		escapeBranch(tree, &n.BranchNode)
+	case *parse.RangeNode:
+		escapeBranch(tree, &n.BranchNode)
+	case *parse.WithNode:
+		escapeBranch(tree, &n.BranchNode)
+	}
+}
+
+func escapeBranch(tree *parse.Tree, b *parse.BranchNode) {
+	escapeActions(tree, b.List)
+	if b.ElseList != nil {
+		escapeActions(tree, b.ElseList)
+	}
+}
+
+func checkBranch(b *parse.BranchNode) error {
+	if err := checkMarkupNode(b.List); err != nil {
+		return err
+	}
+	if b.ElseList != nil {
+		return checkMarkupNode(b.ElseList)
+	}
+	return nil
+}
+
+// checkTag valida una etiqueta literal completa
+func checkTag(text, name string) error {
+	spec, ok := markupTags[name]
+	if !ok {
+		return fmt.Errorf("etiqueta desconocida %s", text)
+	}
+	m := tagPattern.FindStringSubmatch(text)
+	if m == nil || m[0] != text {
+		return fmt.Errorf("etiqueta mal formada %s", text)
+	}
+	if m[1] == "/" {
+		return nil
+	}
+	return checkAttrs(name, spec, parseAttrs(m[3]))
+}
+
+func parseAttrs(s string) map[string]string {
+	attrs := make(map[string]string)
+	for _, m := range attrPattern.FindAllStringSubmatch(s, -1) {
+		attrs[m[1]] = m[2]
+	}
+	return attrs
+}
+
+func checkAttrs(name string, spec markupTag, attrs map[string]string) error {
+	for attr, value := range attrs {
+		known := false
+		for _, a := range spec.attrs {
+			known = known || a == attr
+		}
+		if !known {
+			return fmt.Errorf("<%s> no admite el atributo %s", name, attr)
+		}
+		if numericAttrs[attr] {
+			if n, err := strconv.Atoi(value); err != nil || n <= 0 {
+				return fmt.Errorf("<%s %s=%q>: se esperaba un entero positivo", name, attr, value)
+			}
+		}
+	}
+	if name == "img" && attrs["src"] == "" {
+		return fmt.Errorf("<img> requiere el atributo src")
+	}
+	return nil
+}
+
+// markupNode es un nodo del recibo ya ejecutado
+type markupNode struct {
+	tag      string // Vacío en los nodos de texto
+	attrs    map[string]string
+	text     string
+	children []*markupNode
+}
+
+// data devuelve el contenido de un <qr> o <barcode>
+func (n *markupNode) data() string {
+	var b strings.Builder
+	for _, c := range n.children {
+		b.WriteString(c.text)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// attr devuelve un atributo numérico ya validado, o def si no está
+func (n *markupNode) attr(name string, def int) int {
+	if v, err := strconv.Atoi(n.attrs[name]); err == nil {
+		return v
+	}
+	return def
+}
+
+// parseMarkup arma el árbol de etiquetas del recibo ejecutado
+func parseMarkup(s string) ([]*markupNode, error) {
+	root := &markupNode{}
+	stack := []*markupNode{root}
+	line := func(pos int) int { return strings.Count(s[:pos], "\n") + 1 }
+	addText := func(text string) {
+		if text != "" {
+			top := stack[len(stack)-1]
+			top.children = append(top.children, &markupNode{text: markupUnescaper.Replace(text)})
+		}
+	}
+
+	pos := 0
+	for _, m := range tagPattern.FindAllStringSubmatchIndex(s, -1) {
+		name := s[m[4]:m[5]]
+		spec, known := markupTags[name]
+		if !known {
+			// Se queda como texto
+			continue
+		}
+		closing := m[3] > m[2]
+		top := stack[len(stack)-1]
+		if markupTags[top.tag].data && !(closing && name == top.tag) {
+			return nil, fmt.Errorf("línea %d: <%s> no admite etiquetas", line(m[0]), top.tag)
+		}
+		addText(s[pos:m[0]])
+		pos = m[1]
+		if spec.block && strings.HasPrefix(s[pos:], "\n") {
+			pos++
+		}
+
+		if closing {
+			if top.tag == "" {
+				return nil, fmt.Errorf("línea %d: </%s> sin abrir", line(m[0]), name)
+			}
+			if top.tag != name {
+				return nil, fmt.Errorf("línea %d: </%s> cierra a <%s>", line(m[0]), name, top.tag)
+			}
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		attrs := parseAttrs(markupUnescaper.Replace(s[m[6]:m[7]]))
+		if err := checkAttrs(name, spec, attrs); err != nil {
+			return nil, fmt.Errorf("línea %d: %w", line(m[0]), err)
+		}
+		node := &markupNode{tag: name, attrs: attrs}
+		top.children = append(top.children, node)
+		if spec.container {
+			if m[9] > m[8] {
+				return nil, fmt.Errorf("línea %d: <%s/> debe encerrar contenido", line(m[0]), name)
+			}
+			stack = append(stack, node)
+		}
+	}
+	addText(s[pos:])
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("falta cerrar <%s>", stack[len(stack)-1].tag)
+	}
+	return root.children, nil
+}
+
+// build ejecuta la plantilla con el ticket y arma su árbol de etiquetas
+func (r *Receipt) build(ticketData []byte) ([]*markupNode, error) {
+	var ticket models.NewTicket
+	if err := json.Unmarshal(ticketData, &ticket); err != nil {
+		return nil, fmt.Errorf("error al leer el ticket JSON: %w", err)
+	}
+	var out bytes.Buffer
+	if err := r.tmpl.Execute(&out, &ticket.Data); err != nil {
+		return nil, fmt.Errorf("recibo %s: %w", r.name, err)
+	}
+	nodes, err := parseMarkup(out.String())
+	if err != nil {
+		return nil, fmt.Errorf("recibo %s: %w", r.name, err)
+	}
+	return nodes, nil
+}
+
+// Check ejecuta el recibo con el ticket sin imprimirlo, para rechazar antes de
+// encolar los tickets con los que no se puede armar
+func (r *Receipt) Check(ticketData []byte) error {
+	_, err := r.build(ticketData)
+	return err
+}
+
+// Print imprime el recibo con los datos del ticket. Si no termina con <cut>
+// se agrega un avance y un corte, como en los tickets.
+func (r *Receipt) Print(printer Printer, ticketData []byte) error {
+	nodes, err := r.build(ticketData)
+	if err != nil {
+		return err
+	}
+	p := &markupPrinter{
+		w:     &layoutWriter{printer: printer, style: textStyle{font: types.FontA}},
+		dir:   r.dir,
+		align: types.AlignLeft,
+	}
+	p.w.setFont(types.FontA)
+	p.setAlign(types.AlignLeft)
+	p.nodes(nodes)
+	p.breakLine()
+	p.w.sync()
+	if !p.cut {
+		if err := printer.Feed(2); err != nil {
+			log.Printf("Error al alimentar papel: %v", err)
+		}
+		if err := printer.Cut(types.CutFeed, 3); err != nil {
+			log.Printf("Error al cortar papel: %v", err)
+		}
+	}
+	return nil
+}
+
+// markupPrinter envía el árbol de un recibo libre a la impresora
+type markupPrinter struct {
+	w       *layoutWriter
+	dir     string
+	align   types.Alignment
+	midLine bool // Hay texto enviado en la línea en curso
+	cut     bool // El último elemento impreso fue un corte
+}
+
+func (p *markupPrinter) nodes(nodes []*markupNode) {
+	for _, n := range nodes {
+		p.node(n)
+	}
+}
+
+func (p *markupPrinter) node(n *markupNode) {
+	if n.tag == "" {
+		p.text(n.text)
+		return
+	}
+	p.cut = false
+	switch n.tag {
+	case "b":
+		on := true
+		restores := p.w.push(models.LayoutStyle{Bold: &on})
+		p.nodes(n.children)
+		p.w.pop(restores)
+	case "double":
+		restores := p.w.push(models.LayoutStyle{Size: "2"})
+		p.nodes(n.children)
+		p.w.pop(restores)
+	case "center":
+		p.breakLine()
+		p.w.sync()
+		prev := p.align
+		p.setAlign(types.AlignCenter)
+		p.nodes(n.children)
+		p.breakLine()
+		p.w.pending = append(p.w.pending, func() { p.setAlign(prev) })
+	case "qr":
+		p.breakLine()
+		p.w.sync()
+		p.qr(n)
+	case "barcode":
+		p.breakLine()
+		p.w.sync()
+		p.barcode(n)
+	case "img":
+		p.breakLine()
+		p.w.sync()
+		p.image(n)
+	case "feed":
+		p.breakLine()
+		p.w.feed(n.attr("lines", 1))
+	case "cut":
+		p.breakLine()
+		p.w.sync()
+		if err := p.w.printer.Cut(types.CutFeed, 3); err != nil {
+			log.Printf("Error al cortar papel: %v", err)
+		}
+		p.cut = true
+	}
+}
+
+// text imprime texto; cada salto de línea termina la línea en curso
+func (p *markupPrinter) text(s string) {
+	for i, line := range strings.Split(s, "\n") {
+		if i > 0 {
+			p.w.endLine()
+			p.midLine = false
+			p.cut = false
+		}
+		if line != "" {
+			p.w.write(line)
+			p.midLine = true
+			p.cut = false
+		}
+	}
+}
+
+// breakLine termina la línea en curso si tiene texto
+func (p *markupPrinter) breakLine() {
+	if p.midLine || p.w.line.Len() > 0 {
+		p.w.endLine()
+		p.midLine = false
+	}
+}
+
+func (p *markupPrinter) setAlign(a types.Alignment) {
+	if err := p.w.printer.SetJustification(a); err != nil {
+		log.Printf("Error al establecer justificación: %v", err)
+	}
+	p.align = a
+}
+
+// qr imprime un código QR con el contenido de la etiqueta
+func (p *markupPrinter) qr(n *markupNode) {
+	qr, err := qrcode.New(n.data(), qrcode.Medium)
+	if err != nil {
+		log.Printf("Error generando QR: %v", err)
+		return
+	}
+	if err := p.w.printer.PrintImage(qr.Image(n.attr("size", 256))); err != nil {
+		log.Printf("Error al imprimir QR: %v", err)
+	}
+}
+
+// barcode imprime un código Code 128. Si no cabe en el papel se reduce el
+// ancho del módulo hasta un punto.
+func (p *markupPrinter) barcode(n *markupNode) {
+	data := n.data()
+	values, err := code128Values(data)
+	if err != nil {
+		log.Printf("Error generando código de barras: %v", err)
+		return
+	}
+	module := n.attr("module", 2)
+	if dots := p.w.printer.GetProfile().DotsPerLine; dots > 0 {
+		for module > 1 && code128Modules(values)*module > dots {
+			module--
+		}
+	}
+	img, err := code128Image(data, module, n.attr("height", 80))
+	if err != nil {
+		log.Printf("Error generando código de barras: %v", err)
+		return
+	}
+	if err := p.w.printer.PrintImage(img); err != nil {
+		log.Printf("Error al imprimir código de barras: %v", err)
+	}
+}
+
+// image imprime una imagen con tramado; las rutas relativas parten del
+// directorio del recibo
+func (p *markupPrinter) image(n *markupNode) {
+	path := n.attrs["src"]
+	if !fp.IsAbs(path) && p.dir != "" {
+		path = fp.Join(p.dir, path)
+	}
+	img, err := imaging.LoadImage(path)
+	if err != nil {
+		log.Printf("recibo: error cargando imagen %s: %v", path, err)
+		return
+	}
+	opts := posprinter.PrintImageOptions{
+		Density:    types.DensitySingle,
+		DitherMode: imaging.DitherFloydSteinberg,
+		Threshold:  128,
+		Width:      n.attr("width", 0),
+	}
+	if err := p.w.printer.PrintImageWithOptions(img, opts); err != nil {
+		log.Printf("recibo: error al imprimir imagen %s: %v", path, err)
+	}
+}
+
+// Receipts son los recibos libres compilados de un directorio
+type Receipts struct {
+	byName map[string]*Receipt
+}
+
+// LoadReceipts compila los recibos *.tmpl de dir; el nombre de cada recibo es
+// el del archivo sin extensión. Con dir vacío no hay recibos. Reporta todos los
+// recibos inválidos a la vez.
+func LoadReceipts(dir string) (*Receipts, error) {
+	set := &Receipts{byName: make(map[string]*Receipt)}
+	if dir == "" {
+		return set, nil
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("error al abrir el directorio de recibos: %w", err)
+	}
+	paths, err := fp.Glob(fp.Join(dir, "*"+ReceiptExt))
+	if err != nil {
+		return nil, fmt.Errorf("error al buscar recibos en %s: %w", dir, err)
+	}
+	var errs []error
+	for _, path := range paths {
+		receipt, err := ParseReceiptFile(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		set.byName[receipt.name] = receipt
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return set, nil
+}
+
+// Get devuelve el recibo con el nombre indicado
+func (s *Receipts) Get(name string) (*Receipt, error) {
+	if s != nil {
+		if r, ok := s.byName[name]; ok {
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrReceiptNotFound, name)
+}
+
+// Names devuelve los nombres de los recibos en orden alfabético
+func (s *Receipts) Names() []string {
+	if s == nil {
+		return nil
+	}
+	names := make([]string, 0, len(s.byName))
+	for name := range s.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	fp "path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/AdConDev/pos-printer/profile"
+)
+
+// fixedSizePrinter es una impresora que no permite ampliar los caracteres
+type fixedSizePrinter struct {
+	*recordingPrinter
+}
+
+func (p fixedSizePrinter) SetTextSize(width, height int) error {
+	return errors.New("sin ampliación")
+}
+
+func TestReceiptMarkup(t *testing.T) {
+	ticketData, err := os.ReadFile("../api/rest/new_ticket.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt, err := ParseReceipt("rifa", `<center>
+<double>RIFA</double>
+{{.SucursalNombre}}
+</center>
+Folio <b>{{.Serie}}-{{.Folio}}</b> 1 < 2
+<feed lines="2"/>
+<barcode height="40">{{.Folio}}</barcode>
+<qr size="64">{{.AutofacturaLinkQr}}</qr>
+<cut/>
+`)
+	if err != nil {
+		t.Fatalf("ParseReceipt: %v", err)
+	}
+
+	rec := newRecordingPrinter(profile.CreateProfile80mm())
+	if err := receipt.Print(rec, ticketData); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	want := []string{
+		`font A`,
+		`justify left`,
+		`justify center`,
+		`size 2x2`,
+		`textln "RIFA"`,
+		`size 1x1`,
+		`textln "ESCUELA KEMPER URGATE"`,
+		`justify left`,
+		`text "Folio "`,
+		`emphasis on`,
+		`text "ABC1-326"`,
+		`emphasis off`,
+		`textln " 1 < 2"`,
+		`feed 2`,
+		`image 176x40 sha256:78f4224dc22bbfba`,
+		`image 64x64 sha256:889691e9d108e808`,
+		`cut feed 3`,
+	}
+	if got := strings.Join(rec.lines, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("comandos =\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestReceiptWithoutSizer(t *testing.T) {
+	receipt, err := ParseReceipt("simple", "<double>Hola</double>")
+	if err != nil {
+		t.Fatalf("ParseReceipt: %v", err)
+	}
+	rec := newRecordingPrinter(profile.CreateProfile58mm())
+	if err := receipt.Print(fixedSizePrinter{rec}, []byte(`{"data": {}}`)); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	want := []string{`font A`, `justify left`, `textln "Hola"`, `feed 2`, `cut feed 3`}
+	if got := strings.Join(rec.lines, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("comandos =\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestReceiptDataIsText(t *testing.T) {
+	// Lo que viene en los datos se imprime tal cual aunque parezca etiqueta
+	tests := []struct {
+		name  string
+		folio string
+		want  string
+	}{
+		{"negrita", "<b>1", `textln "<b>1"`},
+		{"cierre", "</center>", `textln "</center>"`},
+		{"imagen", `<img src="/etc/passwd"/>`, `textln "<img src=\"/etc/passwd\"/>"`},
+	}
+	receipt, err := ParseReceipt("folio", `Folio <b>{{.Folio}}</b>{{$f := .Folio}}`)
+	if err != nil {
+		t.Fatalf("ParseReceipt: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(map[string]any{"data": map[string]string{"folio": tt.folio}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec := newRecordingPrinter(profile.CreateProfile80mm())
+			if err := receipt.Print(rec, data); err != nil {
+				t.Fatalf("Print: %v", err)
+			}
+			want := []string{`font A`, `justify left`, `text "Folio "`, `emphasis on`, tt.want, `emphasis off`, `feed 2`, `cut feed 3`}
+			if got := strings.Join(rec.lines, "\n"); got != strings.Join(want, "\n") {
+				t.Errorf("comandos =\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+			}
+		})
+	}
+}
+
+func TestReceiptErrors(t *testing.T) {
+	load := []struct {
+		name string
+		src  string
+	}{
+		{"sintaxis", "{{.Folio"},
+		{"etiqueta desconocida", "<centre>Hola</centre>"},
+		{"atributo desconocido", `<feed n="2"/>`},
+		{"atributo no numérico", `<qr size="grande">x</qr>`},
+		{"imagen sin src", `<img width="100"/>`},
+		{"etiqueta en rama", `{{if .Folio}}<bold>{{end}}`},
+	}
+	for _, tt := range load {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseReceipt(tt.name, tt.src); err == nil {
+				t.Error("se aceptó un recibo inválido")
+			}
+		})
+	}
+
+	render := []struct {
+		name string
+		src  string
+	}{
+		{"sin cerrar", "<b>Hola"},
+		{"cierre sin abrir", "Hola</b>"},
+		{"cruzadas", "<b><double>Hola</b></double>"},
+		{"etiqueta en qr", "<qr><b>x</b></qr>"},
+		{"campo desconocido", "{{.Foio}}"},
+	}
+	for _, tt := range render {
+		t.Run(tt.name, func(t *testing.T) {
+			receipt, err := ParseReceipt(tt.name, tt.src)
+			if err != nil {
+				t.Fatalf("ParseReceipt: %v", err)
+			}
+			if err := receipt.Check([]byte(`{"data": {"folio": "1"}}`)); err == nil {
+				t.Error("se aceptó un recibo inválido")
+			}
+		})
+	}
+}
+
+func TestLoadReceipts(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, src string) {
+		if err := os.WriteFile(fp.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("promo.tmpl", "<b>2x1</b> en {{.SucursalNombre}}")
+	write("notas.txt", "<ignorado>")
+
+	receipts, err := LoadReceipts(dir)
+	if err != nil {
+		t.Fatalf("LoadReceipts: %v", err)
+	}
+	if got := strings.Join(receipts.Names(), ","); got != "promo" {
+		t.Errorf("Names = %q; want promo", got)
+	}
+	if _, err := receipts.Get("rifa"); !errors.Is(err, ErrReceiptNotFound) {
+		t.Errorf("Get(rifa) = %v; want ErrReceiptNotFound", err)
+	}
+
+	write("rota.tmpl", "<b>{{.Folio</b>")
+	if _, err := LoadReceipts(dir); err == nil || !strings.Contains(err.Error(), "rota") {
+		t.Errorf("LoadReceipts con recibo inválido = %v", err)
+	}
+}
+
+func TestRenderReceiptDouble(t *testing.T) {
+	receipt, err := ParseReceipt("total", "<double>TOTAL</double>")
+	if err != nil {
+		t.Fatalf("ParseReceipt: %v", err)
+	}
+	data, err := RenderReceipt(receipt, []byte(`{"data": {}}`), profile.CreateProfile80mm())
+	if err != nil {
+		t.Fatalf("RenderReceipt: %v", err)
+	}
+	if !bytes.Contains(data, []byte("\x1d!\x11TOTAL")) || !bytes.Contains(data, []byte("\x1d!\x00")) {
+		t.Errorf("faltan los comandos GS ! en %q", data)
+	}
+}
+
+func TestCode128(t *testing.T) {
+	for v, pattern := range code128Patterns {
+		bars, spaces := 0, 0
+		for i, w := range pattern {
+			if i%2 == 0 {
+				bars += int(w - '0')
+			} else {
+				spaces += int(w - '0')
+			}
+		}
+		want := 11
+		if v == code128Stop {
+			want = 13
+		}
+		// Las barras de cada símbolo suman un número par de módulos
+		if bars+spaces != want || bars%2 != 0 {
+			t.Errorf("patrón %d = %s inválido", v, pattern)
+		}
+	}
+
+	tests := []struct {
+		data string
+		want []int
+	}{
+		{"Wikipedia", []int{104, 55, 73, 75, 73, 80, 69, 68, 73, 65, 88, 106}},
+		{"123456", []int{105, 12, 34, 56, 44, 106}},
+		{"12345", []int{104, 17, 18, 19, 20, 21, 90, 106}},
+	}
+	for _, tt := range tests {
+		got, err := code128Values(tt.data)
+		if err != nil {
+			t.Fatalf("code128Values(%q): %v", tt.data, err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("code128Values(%q) = %v; want %v", tt.data, got, tt.want)
+		}
+	}
+	if _, err := code128Values("año"); err == nil {
+		t.Error("se aceptó un carácter fuera del juego B")
+	}
+}
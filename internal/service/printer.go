@@ -0,0 +1,53 @@
+package service
+
+import (
+	"fmt"
+	"image"
+
+	posprinter "github.com/AdConDev/pos-printer"
+	"github.com/AdConDev/pos-printer/profile"
+	"github.com/AdConDev/pos-printer/types"
+)
+
+// Printer is the set of printer operations TicketConstructor needs. It lets the
+// ticket layout run against recorders, preview renderers or other backends.
+type Printer interface {
+	// Formato de texto
+	SetJustification(alignment types.Alignment) error
+	SetEmphasis(on types.EmphasizedMode) error
+	SetFont(font types.Font) error
+	SetTextSize(width, height int) error // Multiplicadores de ancho y alto, de 1 a 8
+
+	// Impresión de texto
+	Text(str string) error
+	TextLn(str string) error
+
+	// Control de papel
+	Feed(lines int) error
+	Cut(mode types.CutMode, lines int) error
+
+	// Impresión de imágenes
+	PrintImage(img image.Image) error
+	PrintImageWithOptions(img image.Image, opts posprinter.PrintImageOptions) error
+
+	// Perfil de la impresora
+	GetProfile() *profile.Profile
+	SetProfile(newProfile *profile.Profile)
+}
+
+// ESCPOSPrinter es la implementación ESC/POS de Printer: GenericPrinter con los
+// comandos que pos-printer aún no implementa, escritos directo en el conector
+type ESCPOSPrinter struct {
+	*posprinter.GenericPrinter
+}
+
+var _ Printer = ESCPOSPrinter{}
+
+// SetTextSize envía GS ! con multiplicadores de ancho y alto de 1 a 8
+func (p ESCPOSPrinter) SetTextSize(width, height int) error {
+	if width < 1 || width > 8 || height < 1 || height > 8 {
+		return fmt.Errorf("tamaño de texto inválido: %dx%d", width, height)
+	}
+	_, err := p.Connector.Write([]byte{0x1d, '!', byte((width-1)<<4 | (height - 1))})
+	return err
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	posprinter "github.com/AdConDev/pos-printer"
+	"github.com/AdConDev/pos-printer/profile"
+	"github.com/AdConDev/pos-printer/protocol/escpos"
+	"github.com/AdConDev/pos-printer/types"
+)
+
+// bufferConnector acumula en memoria los comandos enviados a la impresora
+type bufferConnector struct {
+	bytes.Buffer
+}
+
+// Close implementa connector.Connector
+func (b *bufferConnector) Close() error {
+	return nil
+}
+
+// BuildTicket carga la plantilla y el ticket y los imprime sobre printer con
+// las marcas de opts: copia y aviso de validación.
+func BuildTicket(printer Printer, templateData, ticketData []byte, opts TicketOptions) error {
+	constructor := NewTicketConstructor(io.Discard, printer)
+	constructor.SetReprint(opts.Reprint)
+	constructor.SetWarnings(opts.Warnings)
+	if err := constructor.LoadTemplateFromJSON(templateData); err != nil {
+		return err
+	}
+	if err := constructor.LoadTicketFromJSON(ticketData); err != nil {
+		return err
+	}
+	return constructor.PrintTicket()
+}
+
+// RenderTicket construye el ticket completo en memoria y devuelve los comandos
+// ESC/POS resultantes, listos para enviarse al conector en una sola escritura.
+// Las marcas de opts se imprimen como en BuildTicket.
+func RenderTicket(templateData, ticketData []byte, prof *profile.Profile, opts TicketOptions) ([]byte, error) {
+	// PrintTicket ajusta el perfil al ancho de la plantilla; se trabaja sobre una copia
+	p := *prof
+
+	buf := &bufferConnector{}
+	printer, err := posprinter.NewGenericPrinter(escpos.NewESCPOSProtocol(), buf, &p)
+	if err != nil {
+		return nil, fmt.Errorf("render: error al crear impresora: %w", err)
+	}
+	if err := BuildTicket(ESCPOSPrinter{printer}, templateData, ticketData, opts); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Ancho en puntos de un carácter de cada fuente si el perfil no lo indica
+const (
+	fontADots = 12
+	fontBDots = 9
+)
+
+// FontColumns devuelve cuántos caracteres de la fuente caben en una línea
+func FontColumns(prof *profile.Profile, font types.Font) int {
+	name, dots := "FontA", fontADots
+	if font == types.FontB {
+		name, dots = "FontB", fontBDots
+	}
+	if w := prof.Fonts[name]; w > 0 {
+		dots = w
+	}
+	return prof.DotsPerLine / dots
+}
+
+// TextColumns devuelve cuántos caracteres de la fuente A caben en una línea
+func TextColumns(prof *profile.Profile) int {
+	return FontColumns(prof, types.FontA)
+}
+
+// PrintText imprime texto plano acomodado al ancho del perfil de printer,
+// seguido de un avance y un corte
+func PrintText(printer Printer, text string) error {
+	for _, line := range WrapText(text, TextColumns(printer.GetProfile())) {
+		if err := printer.TextLn(line); err != nil {
+			return fmt.Errorf("render: error al imprimir texto: %w", err)
+		}
+	}
+	if err := printer.Feed(2); err != nil {
+		return fmt.Errorf("render: error al alimentar papel: %w", err)
+	}
+	if err := printer.Cut(types.CutFeed, 3); err != nil {
+		return fmt.Errorf("render: error al cortar papel: %w", err)
+	}
+	return nil
+}
+
+// RenderText acomoda texto plano al ancho del perfil y devuelve los comandos
+// ESC/POS resultantes
+func RenderText(text string, prof *profile.Profile) ([]byte, error) {
+	p := *prof
+	buf := &bufferConnector{}
+	printer, err := posprinter.NewGenericPrinter(escpos.NewESCPOSProtocol(), buf, &p)
+	if err != nil {
+		return nil, fmt.Errorf("render: error al crear impresora: %w", err)
+	}
+	if err := PrintText(ESCPOSPrinter{printer}, text); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// RenderReceipt imprime un recibo libre en memoria y devuelve los comandos
+// ESC/POS resultantes
+func RenderReceipt(receipt *Receipt, ticketData []byte, prof *profile.Profile) ([]byte, error) {
+	p := *prof
+	buf := &bufferConnector{}
+	printer, err := posprinter.NewGenericPrinter(escpos.NewESCPOSProtocol(), buf, &p)
+	if err != nil {
+		return nil, fmt.Errorf("render: error al crear impresora: %w", err)
+	}
+	if err := receipt.Print(ESCPOSPrinter{printer}, ticketData); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"log"
+	"reflect"
+	"strings"
+
+	"pos-daemon.adcon.dev/internal/models"
+)
+
+// columnSpec son las reglas de ancho de una columna, en caracteres
+type columnSpec struct {
+	base int  // Ancho inicial
+	min  int  // Ancho al que puede reducirse
+	max  int  // Ancho máximo; 0 no limita
+	grow int  // Proporción del espacio sobrante que toma
+	wrap bool // Su contenido no se pierde al reducirla; se reduce antes que las demás
+}
+
+// columnWidths reparte avail caracteres entre las columnas. Si sobra espacio
+// lo toman las columnas con grow en proporción a su valor; si falta se
+// reducen hasta su mínimo, primero las que no recortan su contenido y siempre la
+// más ancha. El resultado puede medir menos que avail si ninguna columna
+// crece, o más si ninguna puede reducirse.
+func columnWidths(specs []columnSpec, avail int) []int {
+	widths := make([]int, len(specs))
+	total := 0
+	for i, s := range specs {
+		widths[i] = s.base
+		if s.max > 0 && widths[i] > s.max {
+			widths[i] = s.max
+		}
+		total += widths[i]
+	}
+
+	growable := func(i int) bool {
+		return specs[i].grow > 0 && (specs[i].max == 0 || widths[i] < specs[i].max)
+	}
+	for extra := avail - total; extra > 0; {
+		weights := 0
+		for i := range specs {
+			if growable(i) {
+				weights += specs[i].grow
+			}
+		}
+		if weights == 0 {
+			break
+		}
+		given := 0
+		for i := range specs {
+			if !growable(i) {
+				continue
+			}
+			add := extra * specs[i].grow / weights
+			if specs[i].max > 0 {
+				add = min(add, specs[i].max-widths[i])
+			}
+			widths[i] += add
+			given += add
+		}
+		if given == 0 {
+			// El residuo de la división va a la primera columna que puede crecer
+			for i := range specs {
+				if growable(i) {
+					widths[i]++
+					given = 1
+					break
+				}
+			}
+		}
+		extra -= given
+	}
+
+	for excess := total - avail; excess > 0; excess-- {
+		widest := -1
+		for _, wrapOnly := range []bool{true, false} {
+			for i, s := range specs {
+				if widths[i] > s.min && (!wrapOnly || s.wrap) && (widest < 0 || widths[i] > widths[widest]) {
+					widest = i
+				}
+			}
+			if widest >= 0 {
+				break
+			}
+		}
+		if widest < 0 {
+			break
+		}
+		widths[widest]--
+	}
+	return widths
+}
+
+// tableColumn es una columna visible con su ancho final
+type tableColumn struct {
+	*models.LayoutColumn
+	width int
+	align func(string, int) string
+}
+
+// cell acomoda el texto de la columna en una o más líneas de su ancho según
+// su Overflow. Con scroll devuelve además el texto que continúa bajo la fila.
+func (c *tableColumn) cell(text string) (lines []string, rest string) {
+	switch c.Overflow {
+	case models.OverflowWrap:
+		lines = WrapWords(text, max(c.width-c.Padding, 1))
+	case models.OverflowScroll:
+		var head string
+		head, rest = splitWords(text, c.width-c.Padding)
+		lines = []string{head}
+	default:
+		lines = []string{TruncateText(text, c.width)}
+	}
+	if len(lines) == 0 {
+		lines = []string{""}
+	}
+	return lines, rest
+}
+
+// table imprime una fila por elemento de Rows. Los anchos de las columnas se
+// calculan con los caracteres que caben en la línea con la fuente en curso. Las
+// columnas con overflow wrap continúan en filas adicionales con las demás
+// celdas vacías; las de scroll continúan después de la fila en líneas de todo
+// el ancho de la tabla.
+func (r *layoutRenderer) table(e *models.LayoutElement) {
+	var cols []tableColumn
+	for i := range e.Columns {
+		c := &e.Columns[i]
+		if !r.program.cond(c.If, r.data) {
+			continue
+		}
+		align, _ := alignFunc(c.Align)
+		cols = append(cols, tableColumn{LayoutColumn: c, align: align})
+	}
+
+	// Los textos se evalúan antes de calcular los anchos, que pueden
+	// depender del contenido
+	var texts [][]string
+	if rows := fieldValue(reflect.ValueOf(r.data).Elem(), e.Rows); rows.IsValid() {
+		for i := 0; i < rows.Len(); i++ {
+			r.data.Item = rows.Index(i).Interface()
+			row := make([]string, len(cols))
+			for j, c := range cols {
+				row[j] = r.program.text(c.Text, r.data)
+			}
+			texts = append(texts, row)
+		}
+		r.data.Item = nil
+	}
+
+	specs := make([]columnSpec, len(cols))
+	for j, c := range cols {
+		base := c.Width
+		if base == 0 {
+			if e.Header {
+				base = TextWidth(c.Title)
+			}
+			for _, row := range texts {
+				base = max(base, TextWidth(row[j])+c.Padding)
+			}
+		}
+		minWidth := c.MinWidth
+		if minWidth == 0 || minWidth > base {
+			minWidth = base
+		}
+		wrap := c.Overflow == models.OverflowWrap || c.Overflow == models.OverflowScroll
+		specs[j] = columnSpec{base: base, min: minWidth, max: c.MaxWidth, grow: c.Grow, wrap: wrap}
+	}
+	// Las restauraciones pendientes cambian la fuente y el tamaño con que se mide
+	r.w.sync()
+	avail := r.w.columns()
+	total := 0
+	for j, w := range columnWidths(specs, avail) {
+		cols[j].width = w
+		total += w
+	}
+	if total > avail {
+		log.Printf("Advertencia: la tabla mide %d caracteres y en la línea caben %d", total, avail)
+	}
+
+	printRow := func(row string) {
+		r.w.write(row)
+		r.w.endLine()
+	}
+
+	if e.Header {
+		var b strings.Builder
+		for _, c := range cols {
+			b.WriteString(c.align(cutText(c.Title, c.width), c.width))
+		}
+		restores := r.w.push(r.style(e.HeaderStyle))
+		printRow(b.String())
+		r.w.pop(restores)
+	}
+
+	for _, row := range texts {
+		cells := make([][]string, len(cols))
+		rests := make([]string, len(cols))
+		lines := 1
+		for j := range cols {
+			cells[j], rests[j] = cols[j].cell(row[j])
+			lines = max(lines, len(cells[j]))
+		}
+		for line := 0; line < lines; line++ {
+			var b strings.Builder
+			for j, c := range cols {
+				cell := ""
+				if line < len(cells[j]) {
+					cell = cells[j][line]
+				}
+				b.WriteString(c.align(cell, c.width))
+			}
+			printRow(b.String())
+		}
+		for j, rest := range rests {
+			if rest == "" {
+				continue
+			}
+			for _, line := range WrapWords(rest, min(total, avail)) {
+				printRow(cols[j].align(line, min(total, avail)))
+			}
+		}
+	}
+}
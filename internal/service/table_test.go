@@ -0,0 +1,117 @@
+package service
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/AdConDev/pos-printer/profile"
+)
+
+func TestColumnWidths(t *testing.T) {
+	tests := []struct {
+		name  string
+		specs []columnSpec
+		avail int
+		want  []int
+	}{
+		{
+			"crece la descripción",
+			[]columnSpec{{base: 4, min: 4}, {base: 10, min: 6, grow: 1, wrap: true}, {base: 9, min: 9}, {base: 9, min: 9}},
+			48,
+			[]int{4, 26, 9, 9},
+		},
+		{
+			"se reduce la columna que parte su contenido",
+			[]columnSpec{{base: 4, min: 4}, {base: 18, min: 6, wrap: true}, {base: 9, min: 9}, {base: 9, min: 9}},
+			32,
+			[]int{4, 10, 9, 9},
+		},
+		{
+			"reparto proporcional con máximo",
+			[]columnSpec{{base: 5, grow: 1, max: 8}, {base: 5, grow: 2}, {base: 5, min: 5}},
+			30,
+			[]int{8, 17, 5},
+		},
+		{
+			"se reduce la más ancha al agotar las que parten",
+			[]columnSpec{{base: 12, min: 4}, {base: 8, min: 4}, {base: 6, min: 6, wrap: true}},
+			20,
+			[]int{7, 7, 6},
+		},
+		{
+			"no cabe",
+			[]columnSpec{{base: 20, min: 20}, {base: 20, min: 10}},
+			24,
+			[]int{20, 10},
+		},
+		{
+			"sin columnas que crezcan",
+			[]columnSpec{{base: 4}, {base: 4}},
+			32,
+			[]int{4, 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := columnWidths(tt.specs, tt.avail); !slices.Equal(got, tt.want) {
+				t.Errorf("columnWidths = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTableOverflow(t *testing.T) {
+	lines := printWithLayout(t, `{"sections": [{"name": "items", "elements": [
+		{"type": "table", "rows": ".Ticket.Conceptos", "columns": [
+			{"name": "desc", "text": "{{.Item.Descripcion}}", "width": 16, "overflow": "scroll"},
+			{"name": "nota", "text": "{{.Item.Descripcion}}", "width": 10},
+			{"name": "total", "text": "{{money .Item.Total}}", "width": 10, "align": "right"}
+		]}
+	]}]}`)
+	got := strings.Join(lines, "\n")
+	want := strings.Join([]string{
+		`textln "MANTENIMIENTO   MANTENI...  37041.80"`,
+		`textln "OTROS CORRECTIVOS                   "`,
+	}, "\n")
+	if !strings.Contains(got, want) {
+		t.Errorf("tabla =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTableFillsPaperWidth(t *testing.T) {
+	const layout = `{"sections": [{"name": "items", "elements": [
+		{"type": "table", "rows": ".Ticket.Conceptos", "header": true, "columns": [
+			{"name": "cant", "title": "CANT", "text": "{{qty .Item.Cantidad}}", "width": 4},
+			{"name": "desc", "title": "PRODUCTO", "text": "{{.Item.Descripcion}}", "width": 8, "overflow": "wrap", "grow": 1},
+			{"name": "total", "title": "TOTAL", "text": "{{money .Item.Total}}", "width": 10, "align": "right"}
+		]}
+	]}]}`
+	tests := []struct {
+		name    string
+		profile func() *profile.Profile
+		want    int
+	}{
+		{"58mm", profile.CreateProfile58mm, 32},
+		{"80mm", profile.CreateProfile80mm, 48},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := 0
+			for _, line := range printWithLayoutOn(t, layout, tt.profile()) {
+				text, ok := strings.CutPrefix(line, "textln ")
+				if !ok {
+					continue
+				}
+				rows++
+				if got := utf8.RuneCountInString(text) - 2; got != tt.want {
+					t.Errorf("renglón %s de %d columnas; want %d", text, got, tt.want)
+				}
+			}
+			if rows == 0 {
+				t.Fatal("la tabla no imprimió renglones")
+			}
+		})
+	}
+}
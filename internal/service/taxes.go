@@ -0,0 +1,222 @@
+package service
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"pos-daemon.adcon.dev/internal/models"
+)
+
+// Códigos de impuestos federales del SAT
+const (
+	TaxISR  = "001"
+	TaxIVA  = "002"
+	TaxIEPS = "003"
+)
+
+// taxNames son los nombres de los impuestos federales por código
+var taxNames = map[string]string{
+	TaxISR:  "ISR",
+	TaxIVA:  "IVA",
+	TaxIEPS: "IEPS",
+}
+
+// TaxLine es la suma de los impuestos de los conceptos con el mismo código,
+// tipo, factor, tasa y entidad
+type TaxLine struct {
+	Codigo  string         // Código del SAT o, en los impuestos locales, su nombre
+	Nombre  string         // ISR, IVA, IEPS o el nombre del impuesto local
+	Tipo    string         // T (trasladado) o R (retenido)
+	Factor  string         // Tasa, Cuota o Exento
+	Tasa    models.Decimal // Tasa o cuota aplicada
+	Local   bool           // Impuesto local; si no, federal
+	Base    models.Decimal // Suma de las bases
+	Importe models.Decimal // Suma de los importes, redondeada a centavos
+}
+
+// Retenido indica si el impuesto es una retención
+func (l TaxLine) Retenido() bool {
+	return l.Tipo == "R"
+}
+
+// Label es el nombre del impuesto para el ticket, con su tasa: "IVA 16%",
+// "Ret. ISR 10%", "IEPS $0.35" si es una cuota o "IVA Exento". La tasa no se
+// repite si el nombre ya la incluye.
+func (l TaxLine) Label() string {
+	label := l.Nombre
+	if l.Retenido() {
+		label = "Ret. " + label
+	}
+	switch strings.ToLower(l.Factor) {
+	case "exento":
+		return label + " Exento"
+	case "cuota":
+		return label + " $" + l.Tasa.String()
+	}
+	rate := formatRate(l.Tasa)
+	// Algunos impuestos locales ya traen la tasa en el nombre
+	if strings.Contains(l.Nombre, rate) {
+		return label
+	}
+	return label + " " + rate
+}
+
+// formatRate escribe una tasa como porcentaje, con hasta cuatro decimales y
+// sin ceros de sobra
+func formatRate(tasa models.Decimal) string {
+	return tasa.Mul(models.DecimalFromInt(100)).Round(4).String() + "%"
+}
+
+// TaxSummary son los impuestos de un ticket agrupados
+type TaxSummary struct {
+	// Lines tiene una línea por impuesto: primero los federales y luego los
+	// locales; en cada grupo, los trasladados antes que los retenidos y en el
+	// orden en que aparecen en los conceptos
+	Lines []TaxLine
+
+	IVATrasladado  models.Decimal
+	IEPSTrasladado models.Decimal
+	IVARetenido    models.Decimal
+	ISRRetenido    models.Decimal
+
+	LocalesTrasladados models.Decimal
+	LocalesRetenidos   models.Decimal
+
+	Trasladados models.Decimal // Todos los trasladados, federales y locales
+	Retenidos   models.Decimal // Todas las retenciones, federales y locales
+}
+
+// taxKey identifica una línea del resumen. El factor separa el IVA exento del
+// IVA a tasa 0%, que tienen la misma tasa.
+type taxKey struct {
+	codigo string
+	tipo   string
+	factor string
+	tasa   models.Decimal
+	local  bool
+}
+
+// SummarizeTaxes agrupa los impuestos de los conceptos por código, tipo,
+// factor, tasa y entidad. Los códigos federales se aceptan también por nombre
+// ("IVA").
+func SummarizeTaxes(conceptos []models.Concepto) TaxSummary {
+	var s TaxSummary
+	index := make(map[taxKey]int)
+	for _, conc := range conceptos {
+		for _, imp := range conc.Impuestos {
+			line := newTaxLine(imp)
+			key := taxKey{
+				codigo: strings.ToUpper(line.Codigo),
+				tipo:   line.Tipo,
+				factor: normalizeFactor(line.Factor),
+				tasa:   line.Tasa,
+				local:  line.Local,
+			}
+			i, ok := index[key]
+			if !ok {
+				i = len(s.Lines)
+				index[key] = i
+				s.Lines = append(s.Lines, line)
+			} else {
+				s.Lines[i].Base = s.Lines[i].Base.Add(line.Base)
+				s.Lines[i].Importe = s.Lines[i].Importe.Add(line.Importe)
+			}
+			s.add(line)
+		}
+	}
+	for i := range s.Lines {
+		s.Lines[i].Base = s.Lines[i].Base.Round(LenDecimales)
+		s.Lines[i].Importe = s.Lines[i].Importe.Round(LenDecimales)
+	}
+	s.round()
+	slices.SortStableFunc(s.Lines, func(a, b TaxLine) int {
+		if c := cmp.Compare(boolRank(a.Local), boolRank(b.Local)); c != 0 {
+			return c
+		}
+		return cmp.Compare(boolRank(a.Retenido()), boolRank(b.Retenido()))
+	})
+	return s
+}
+
+// newTaxLine normaliza un impuesto de un concepto
+func newTaxLine(imp models.Impuesto) TaxLine {
+	line := TaxLine{
+		Codigo:  strings.TrimSpace(imp.Codigo),
+		Tipo:    strings.ToUpper(strings.TrimSpace(imp.Tipo)),
+		Factor:  strings.TrimSpace(imp.Factor),
+		Tasa:    imp.Tasa,
+		Local:   strings.EqualFold(strings.TrimSpace(imp.Entidad), "local"),
+		Base:    imp.Base,
+		Importe: imp.Importe,
+	}
+	if strings.HasPrefix(line.Tipo, "R") {
+		line.Tipo = "R"
+	} else {
+		line.Tipo = "T"
+	}
+	line.Nombre = line.Codigo
+	if !line.Local {
+		for code, name := range taxNames {
+			if strings.EqualFold(line.Codigo, name) {
+				line.Codigo = code
+			}
+		}
+		if name, ok := taxNames[line.Codigo]; ok {
+			line.Nombre = name
+		}
+	}
+	return line
+}
+
+// normalizeFactor escribe el tipo de factor en minúsculas; vacío es "tasa"
+func normalizeFactor(factor string) string {
+	if factor == "" {
+		return "tasa"
+	}
+	return strings.ToLower(factor)
+}
+
+// add suma el importe de una línea a los totales del resumen
+func (s *TaxSummary) add(l TaxLine) {
+	if l.Retenido() {
+		s.Retenidos = s.Retenidos.Add(l.Importe)
+	} else {
+		s.Trasladados = s.Trasladados.Add(l.Importe)
+	}
+	var total *models.Decimal
+	switch {
+	case l.Local && l.Retenido():
+		total = &s.LocalesRetenidos
+	case l.Local:
+		total = &s.LocalesTrasladados
+	case l.Codigo == TaxIVA && !l.Retenido():
+		total = &s.IVATrasladado
+	case l.Codigo == TaxIEPS && !l.Retenido():
+		total = &s.IEPSTrasladado
+	case l.Codigo == TaxIVA:
+		total = &s.IVARetenido
+	case l.Codigo == TaxISR && l.Retenido():
+		total = &s.ISRRetenido
+	default:
+		return
+	}
+	*total = total.Add(l.Importe)
+}
+
+// round redondea los totales del resumen a centavos
+func (s *TaxSummary) round() {
+	for _, d := range []*models.Decimal{
+		&s.IVATrasladado, &s.IEPSTrasladado, &s.IVARetenido, &s.ISRRetenido,
+		&s.LocalesTrasladados, &s.LocalesRetenidos, &s.Trasladados, &s.Retenidos,
+	} {
+		*d = d.Round(LenDecimales)
+	}
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"pos-daemon.adcon.dev/internal/models"
+)
+
+func TestSummarizeTaxes(t *testing.T) {
+	data, err := os.ReadFile("../api/rest/ticket.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ticket models.NewTicket
+	if err := json.Unmarshal(data, &ticket); err != nil {
+		t.Fatal(err)
+	}
+	s := SummarizeTaxes(ticket.Data.Conceptos)
+
+	var labels []string
+	for _, l := range s.Lines {
+		labels = append(labels, l.Label())
+	}
+	want := []string{
+		"IVA 16%",
+		"IEPS 8%",
+		"Ret. IVA 10.6667%",
+		"Ret. ISR 10%",
+		"Ret. IVA 10.6666%",
+		"ISH 3%",
+		"Impuesto Cedular 2%",
+		"ISH 2%",
+		"Ret. Impuesto Cedular 3%",
+		"Ret. Inspe. Obras 3%",
+		"Ret. SICV-COP 2%",
+		"Ret. 2 al millar I.C.I.C 0.2%",
+		"Ret. 3% M.O.S.E.R.T.P",
+		"Ret. Impuesto Cedular 1%",
+		"Ret. 2 al millar 0.2%",
+		"Ret. Cuota Sindical 2%",
+		"Ret. 5 al millar INS. OBRAS 0.5%",
+		"Ret. 5 al millar C.M.I.C 0.5%",
+		"Ret. AMORTIZACION DEL ANTICIPO 30%",
+	}
+	if got := strings.Join(labels, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("líneas =\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+
+	// El IVA al 16% de los tres conceptos se suma en una sola línea y el
+	// impuesto cedular repetido con "tasa" y "Tasa" también
+	if got := s.Lines[0].Importe.String(); got != "207.35" {
+		t.Errorf("IVA 16%% = %s", got)
+	}
+	if got := s.Lines[8].Importe.String(); got != "67.82" {
+		t.Errorf("retención cedular 3%% = %s", got)
+	}
+
+	checks := []struct {
+		name string
+		got  models.Decimal
+		want string
+	}{
+		{"IVATrasladado", s.IVATrasladado, "207.35"},
+		{"IEPSTrasladado", s.IEPSTrasladado, "98.16"},
+		{"IVARetenido", s.IVARetenido, "130.88"},
+		{"ISRRetenido", s.ISRRetenido, "122.7"},
+		{"LocalesTrasladados", s.LocalesTrasladados, "79.11"},
+		{"LocalesRetenidos", s.LocalesRetenidos, "547.03"},
+		{"Trasladados", s.Trasladados, "384.62"},
+		{"Retenidos", s.Retenidos, "800.61"},
+	}
+	for _, c := range checks {
+		if got := c.got.String(); got != c.want {
+			t.Errorf("%s = %s; want %s", c.name, got, c.want)
+		}
+	}
+}
+
+func TestSummarizeTaxesPartial(t *testing.T) {
+	// Un concepto con solo dos impuestos, en otro orden y con códigos por nombre
+	conceptos := []models.Concepto{{Impuestos: []models.Impuesto{
+		{Codigo: "IEPS", Tipo: "T", Factor: "Cuota", Tasa: models.MustDecimal("0.35"), Importe: models.MustDecimal("3.5"), Entidad: "Federal"},
+		{Codigo: "iva", Tipo: "T", Factor: "Exento", Entidad: "Federal"},
+	}}}
+	s := SummarizeTaxes(conceptos)
+	if len(s.Lines) != 2 || s.Lines[0].Label() != "IEPS $0.35" || s.Lines[1].Label() != "IVA Exento" {
+		t.Errorf("líneas = %+v", s.Lines)
+	}
+	if s.IEPSTrasladado != models.MustDecimal("3.5") || s.IVATrasladado != 0 || s.IVARetenido != 0 {
+		t.Errorf("resumen = %+v", s)
+	}
+}
+
+func TestSummarizeTaxesExento(t *testing.T) {
+	// El IVA exento y el IVA a tasa 0% tienen tasa cero pero son líneas distintas
+	conceptos := []models.Concepto{
+		{Impuestos: []models.Impuesto{{Codigo: TaxIVA, Tipo: "T", Factor: "Exento", Base: models.MustDecimal("100")}}},
+		{Impuestos: []models.Impuesto{{Codigo: TaxIVA, Tipo: "T", Factor: "Tasa", Base: models.MustDecimal("50")}}},
+		{Impuestos: []models.Impuesto{{Codigo: TaxIVA, Tipo: "T", Factor: "tasa", Base: models.MustDecimal("25")}}},
+		{Impuestos: []models.Impuesto{{Codigo: TaxIVA, Tipo: "T", Factor: "exento", Base: models.MustDecimal("10")}}},
+	}
+	s := SummarizeTaxes(conceptos)
+	var got []string
+	for _, l := range s.Lines {
+		got = append(got, l.Label()+" base "+l.Base.String())
+	}
+	want := []string{"IVA Exento base 110", "IVA 0% base 75"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("líneas = %q; want %q", got, want)
+	}
+}
+
+func TestSummarizeTaxesRounding(t *testing.T) {
+	// Tres importes de 0.005 suman 0.015 y se redondean una sola vez a 0.02;
+	// redondear cada uno daría 0.03 y sumarlos en float64, 0.01
+	imp := models.Impuesto{Codigo: TaxIVA, Tipo: "T", Factor: "Tasa", Tasa: models.MustDecimal("0.16"), Importe: models.MustDecimal("0.005")}
+	conceptos := []models.Concepto{
+		{Impuestos: []models.Impuesto{imp}},
+		{Impuestos: []models.Impuesto{imp}},
+		{Impuestos: []models.Impuesto{imp}},
+	}
+	s := SummarizeTaxes(conceptos)
+	if got := s.Lines[0].Importe.String(); got != "0.02" {
+		t.Errorf("importe = %s; want 0.02", got)
+	}
+	if got := s.IVATrasladado.String(); got != "0.02" {
+		t.Errorf("IVATrasladado = %s; want 0.02", got)
+	}
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"crypto/sha256"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	posprinter "github.com/AdConDev/pos-printer"
+	"github.com/AdConDev/pos-printer/profile"
+	"github.com/AdConDev/pos-printer/types"
+)
+
+var update = flag.Bool("update", false, "regenerar los archivos golden de testdata")
+
+// recordingPrinter implementa Printer guardando cada comando como una línea legible
+type recordingPrinter struct {
+	profile *profile.Profile
+	lines   []string
+}
+
+func newRecordingPrinter(prof *profile.Profile) *recordingPrinter {
+	return &recordingPrinter{profile: prof}
+}
+
+func (r *recordingPrinter) record(format string, args ...interface{}) error {
+	r.lines = append(r.lines, fmt.Sprintf(format, args...))
+	return nil
+}
+
+func (r *recordingPrinter) SetJustification(a types.Alignment) error {
+	names := map[types.Alignment]string{
+		types.AlignLeft:      "left",
+		types.AlignCenter:    "center",
+		types.AlignRight:     "right",
+		types.AlignJustified: "justified",
+	}
+	return r.record("justify %s", names[a])
+}
+
+func (r *recordingPrinter) SetEmphasis(on types.EmphasizedMode) error {
+	if on == types.EmphOn {
+		return r.record("emphasis on")
+	}
+	return r.record("emphasis off")
+}
+
+func (r *recordingPrinter) SetFont(font types.Font) error {
+	if font == types.FontB {
+		return r.record("font B")
+	}
+	return r.record("font A")
+}
+
+func (r *recordingPrinter) SetTextSize(width, height int) error {
+	return r.record("size %dx%d", width, height)
+}
+
+func (r *recordingPrinter) Text(str string) error   { return r.record("text %q", str) }
+func (r *recordingPrinter) TextLn(str string) error { return r.record("textln %q", str) }
+func (r *recordingPrinter) Feed(lines int) error    { return r.record("feed %d", lines) }
+
+func (r *recordingPrinter) Cut(mode types.CutMode, lines int) error {
+	if mode == types.CutFeed {
+		return r.record("cut feed %d", lines)
+	}
+	return r.record("cut %d", lines)
+}
+
+func (r *recordingPrinter) PrintImage(img image.Image) error {
+	return r.record("image %s", describeImage(img))
+}
+
+func (r *recordingPrinter) PrintImageWithOptions(img image.Image, opts posprinter.PrintImageOptions) error {
+	return r.record("image %s width=%d dither=%d threshold=%d", describeImage(img), opts.Width, opts.DitherMode, opts.Threshold)
+}
+
+func (r *recordingPrinter) GetProfile() *profile.Profile { return r.profile }
+
+func (r *recordingPrinter) SetProfile(p *profile.Profile) {
+	r.profile = p
+	_ = r.record("profile paper=%.0fmm dots=%d", p.PaperWidth, p.DotsPerLine)
+}
+
+// describeImage resume una imagen por tamaño y un hash de sus píxeles en blanco y negro
+func describeImage(img image.Image) string {
+	b := img.Bounds()
+	h := sha256.New()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			if (r+g+bl)/3 < 0x8000 {
+				h.Write([]byte{1})
+			} else {
+				h.Write([]byte{0})
+			}
+		}
+	}
+	return fmt.Sprintf("%dx%d sha256:%x", b.Dx(), b.Dy(), h.Sum(nil)[:8])
+}
+
+func TestPrintTicketGolden(t *testing.T) {
+	const fixtures = "../api/rest"
+
+	tests := []struct {
+		name     string
+		ticket   string
+		template string
+		profile  func() *profile.Profile
+		reprint  *Reprint
+		warnings []string
+	}{
+		{"new_ticket_80mm", "new_ticket.json", "new_ticket_template.json", profile.CreateProfile80mm, nil, nil},
+		{"new_ticket_58mm", "new_ticket.json", "ticket_template_58mm.json", profile.CreateProfile58mm, nil, nil},
+		{"ticket_80mm", "ticket.json", "ticket_template.json", profile.CreateProfile80mm, nil, nil},
+		{"ticket_58mm", "ticket.json", "ticket_template_58mm.json", profile.CreateProfile58mm, nil, nil},
+		{"new_ticket_80mm_reprint", "new_ticket.json", "new_ticket_template.json", profile.CreateProfile80mm,
+			&Reprint{Number: 2, At: time.Date(2025, 7, 17, 9, 30, 0, 0, time.UTC)}, nil},
+		{"new_ticket_58mm_warnings", "new_ticket.json", "ticket_template_58mm.json", profile.CreateProfile58mm, nil,
+			[]string{"total: el total 234.00 no coincide con la suma de los conceptos menos el descuento (37485.79)"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ticketData, err := os.ReadFile(filepath.Join(fixtures, tt.ticket))
+			if err != nil {
+				t.Fatalf("error al leer ticket: %v", err)
+			}
+			templateData, err := os.ReadFile(filepath.Join(fixtures, tt.template))
+			if err != nil {
+				t.Fatalf("error al leer plantilla: %v", err)
+			}
+
+			rec := newRecordingPrinter(tt.profile())
+			tc := NewTicketConstructor(io.Discard, rec)
+			tc.SetReprint(tt.reprint)
+			tc.SetWarnings(tt.warnings)
+			if err := tc.LoadTemplateFromJSON(templateData); err != nil {
+				t.Fatalf("LoadTemplateFromJSON: %v", err)
+			}
+			if err := tc.LoadTicketFromJSON(ticketData); err != nil {
+				t.Fatalf("LoadTicketFromJSON: %v", err)
+			}
+			if err := tc.PrintTicket(); err != nil {
+				t.Fatalf("PrintTicket: %v", err)
+			}
+
+			assertGolden(t, filepath.Join("testdata", tt.name+".golden"), strings.Join(rec.lines, "\n")+"\n")
+		})
+	}
+}
+
+// assertGolden compara got con el archivo golden o lo regenera con -update
+func assertGolden(t *testing.T, path, got string) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o600); err != nil {
+			t.Fatalf("error al escribir %s: %v", path, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("error al leer %s (use -update para generarlo): %v", path, err)
+	}
+	if got != string(want) {
+		gotLines := strings.Split(got, "\n")
+		wantLines := strings.Split(string(want), "\n")
+		for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+			var g, w string
+			if i < len(gotLines) {
+				g = gotLines[i]
+			}
+			if i < len(wantLines) {
+				w = wantLines[i]
+			}
+			if g != w {
+				t.Fatalf("%s difiere en la línea %d:\n got: %s\nwant: %s\n(use -update si el cambio es intencional)", path, i+1, g, w)
+			}
+		}
+	}
+}
+
+func TestPrintText(t *testing.T) {
+	printer := newRecordingPrinter(profile.CreateProfile58mm())
+	text := "REPORTE DE CORTE\n\tVentas del día en la sucursal principal: 1,234.50\n"
+	if err := PrintText(printer, text); err != nil {
+		t.Fatalf("PrintText: %v", err)
+	}
+	want := []string{
+		`textln "REPORTE DE CORTE"`,
+		`textln "        Ventas del día en la"`,
+		`textln "sucursal principal: 1,234.50"`,
+		`feed 2`,
+		`cut feed 3`,
+	}
+	if strings.Join(printer.lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("PrintText =\n%s\nwant\n%s", strings.Join(printer.lines, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"pos-daemon.adcon.dev/internal/models"
+)
+
+// ValidationMode decide qué se hace con un ticket que no pasa la validación
+type ValidationMode string
+
+const (
+	// ValidationReject rechaza los tickets con errores; las advertencias no
+	// impiden imprimir
+	ValidationReject ValidationMode = "reject"
+	// ValidationWarn imprime el ticket con un aviso que lista las observaciones
+	ValidationWarn ValidationMode = "warn"
+	// ValidationSilent imprime el ticket sin aviso; las observaciones solo se
+	// informan en la respuesta
+	ValidationSilent ValidationMode = "silent"
+)
+
+// ParseValidationMode lee el modo de la configuración; vacío es ValidationWarn
+func ParseValidationMode(s string) (ValidationMode, error) {
+	switch m := ValidationMode(strings.ToLower(strings.TrimSpace(s))); m {
+	case "":
+		return ValidationWarn, nil
+	case ValidationReject, ValidationWarn, ValidationSilent:
+		return m, nil
+	}
+	return "", fmt.Errorf("modo de validación desconocido %q (use reject, warn o silent)", s)
+}
+
+// Rejects indica si el ticket con estas observaciones no debe imprimirse
+func (m ValidationMode) Rejects(f Findings) bool {
+	return m == ValidationReject && f.HasErrors()
+}
+
+// Banner devuelve las líneas del aviso que se imprime al inicio del ticket;
+// solo ValidationWarn imprime aviso
+func (m ValidationMode) Banner(f Findings) []string {
+	if m != ValidationWarn {
+		return nil
+	}
+	return f.Messages()
+}
+
+// Severity es la gravedad de una observación
+type Severity string
+
+const (
+	SeverityError   Severity = "error"   // El ticket es inconsistente
+	SeverityWarning Severity = "warning" // El ticket puede imprimirse pero conviene revisarlo
+)
+
+// Códigos de las observaciones
+const (
+	FindingMissingField      = "missing_field"       // Falta un campo obligatorio
+	FindingNoItems           = "no_items"            // El ticket no tiene conceptos
+	FindingInvalidQuantity   = "invalid_quantity"    // Cantidad cero o negativa
+	FindingItemTotalMismatch = "item_total_mismatch" // Total de un concepto distinto de cantidad × precio más impuestos
+	FindingTotalMismatch     = "total_mismatch"      // Total distinto de la suma de los conceptos menos el descuento
+	FindingChangeMismatch    = "change_mismatch"     // Cambio distinto de lo pagado menos el total
+	FindingUnderpaid         = "underpaid"           // Lo pagado no cubre el total
+	FindingTaxMismatch       = "tax_mismatch"        // Importe de un impuesto distinto de base × tasa
+	FindingInvalidRFC        = "invalid_rfc"         // RFC con formato inválido
+)
+
+// Finding es una observación de la validación de un ticket
+type Finding struct {
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`            // Código estable para el cliente
+	Field    string   `json:"field,omitempty"` // Campo JSON del ticket ("conceptos[1].total")
+	Message  string   `json:"message"`         // Descripción legible
+}
+
+// String escribe la observación para el aviso y los logs
+func (f Finding) String() string {
+	if f.Field == "" {
+		return f.Message
+	}
+	return f.Field + ": " + f.Message
+}
+
+// Findings son las observaciones de un ticket
+type Findings []Finding
+
+// HasErrors indica si alguna observación es un error
+func (fs Findings) HasErrors() bool {
+	for _, f := range fs {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Messages devuelve cada observación como texto
+func (fs Findings) Messages() []string {
+	var out []string
+	for _, f := range fs {
+		out = append(out, f.String())
+	}
+	return out
+}
+
+// Tolerancias de las comparaciones. El ERP redondea cada importe a centavos,
+// así que cada monto redondeado puede diferir medio centavo del exacto.
+var (
+	centavo      = models.MustDecimal("0.01")
+	medioCentavo = models.MustDecimal("0.005")
+)
+
+// tolerance es la diferencia aceptada al sumar n montos redondeados, al menos
+// un centavo
+func tolerance(n int) models.Decimal {
+	return max(centavo, medioCentavo.Mul(models.DecimalFromInt(int64(n))))
+}
+
+// rfcPattern es el formato del RFC: tres letras (personas morales) o cuatro
+// (físicas), la fecha AAMMDD y la homoclave
+var rfcPattern = regexp.MustCompile(`^[A-ZÑ&]{3,4}[0-9]{6}[A-Z0-9]{3}$`)
+
+// ValidateTicket revisa los campos obligatorios, los conceptos, los RFC y la
+// aritmética del ticket: el importe de cada impuesto, el total de cada
+// concepto, el total del ticket, que lo pagado lo cubra y el cambio. Los
+// montos se comparan con tolerancia de redondeo a centavos.
+func ValidateTicket(t *models.NewTicketData) Findings {
+	v := &validator{}
+	v.required("identificador", t.Identificador)
+	v.required("folio", t.Folio)
+	v.required("fecha_sistema", t.FechaSistema)
+	v.rfc("sucursal_rfc", t.SucursalRFC)
+	v.rfc("cliente_rfc", t.ClienteRFC)
+
+	if len(t.Conceptos) == 0 {
+		v.add(SeverityError, FindingNoItems, "conceptos", "el ticket no tiene conceptos")
+		return v.findings
+	}
+	var sum models.Decimal
+	for i := range t.Conceptos {
+		v.concepto(fmt.Sprintf("conceptos[%d]", i), &t.Conceptos[i])
+		sum = sum.Add(t.Conceptos[i].Total)
+	}
+
+	expected := sum.Sub(t.Descuento).Round(LenDecimales)
+	if !near(t.Total, expected, tolerance(len(t.Conceptos))) {
+		v.add(SeverityError, FindingTotalMismatch, "total",
+			fmt.Sprintf("el total %s no coincide con la suma de los conceptos menos el descuento (%s)",
+				t.Total.StringFixed(LenDecimales), expected.StringFixed(LenDecimales)))
+	}
+
+	if t.Pagado.Sign() != 0 {
+		change := t.Pagado.Sub(t.Total).Round(LenDecimales)
+		if change.Sign() < 0 {
+			v.add(SeverityError, FindingUnderpaid, "pagado",
+				fmt.Sprintf("lo pagado %s no cubre el total %s (faltan %s)",
+					t.Pagado.StringFixed(LenDecimales), t.Total.StringFixed(LenDecimales), (-change).StringFixed(LenDecimales)))
+		} else if !near(t.Cambio, change, centavo) {
+			v.add(SeverityError, FindingChangeMismatch, "cambio",
+				fmt.Sprintf("el cambio %s no coincide con lo pagado menos el total (%s)",
+					t.Cambio.StringFixed(LenDecimales), change.StringFixed(LenDecimales)))
+		}
+	}
+	return v.findings
+}
+
+// validator acumula las observaciones de un ticket
+type validator struct {
+	findings Findings
+}
+
+func (v *validator) add(severity Severity, code, field, message string) {
+	v.findings = append(v.findings, Finding{Severity: severity, Code: code, Field: field, Message: message})
+}
+
+func (v *validator) required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.add(SeverityError, FindingMissingField, field, "campo obligatorio vacío")
+	}
+}
+
+// rfc revisa el formato de un RFC; uno vacío no se revisa
+func (v *validator) rfc(field, value string) {
+	value = strings.TrimSpace(value)
+	if value != "" && !rfcPattern.MatchString(strings.ToUpper(value)) {
+		v.add(SeverityWarning, FindingInvalidRFC, field, fmt.Sprintf("RFC inválido %q", value))
+	}
+}
+
+// concepto revisa la cantidad, el importe de cada impuesto y que el total sea
+// cantidad × precio más los impuestos trasladados menos los retenidos
+func (v *validator) concepto(field string, c *models.Concepto) {
+	v.required(field+".descripcion", c.Descripcion)
+	if c.Cantidad.Sign() <= 0 {
+		v.add(SeverityError, FindingInvalidQuantity, field+".cantidad",
+			fmt.Sprintf("la cantidad %s debe ser mayor que cero", c.Cantidad))
+	}
+
+	expected := c.Cantidad.Mul(c.PrecioVenta)
+	for j, imp := range c.Impuestos {
+		line := newTaxLine(imp)
+		if line.Retenido() {
+			expected = expected.Sub(line.Importe)
+		} else {
+			expected = expected.Add(line.Importe)
+		}
+		if strings.EqualFold(line.Factor, "exento") {
+			continue
+		}
+		calc := line.Base.Mul(line.Tasa).Round(LenDecimales)
+		if !near(line.Importe, calc, centavo) {
+			v.add(SeverityWarning, FindingTaxMismatch, fmt.Sprintf("%s.impuestos[%d].importe", field, j),
+				fmt.Sprintf("%s: el importe %s no coincide con base × tasa (%s)",
+					line.Label(), line.Importe.StringFixed(LenDecimales), calc.StringFixed(LenDecimales)))
+		}
+	}
+	expected = expected.Round(LenDecimales)
+	if !near(c.Total, expected, tolerance(len(c.Impuestos)+1)) {
+		v.add(SeverityError, FindingItemTotalMismatch, field+".total",
+			fmt.Sprintf("el total %s no coincide con cantidad × precio más impuestos (%s)",
+				c.Total.StringFixed(LenDecimales), expected.StringFixed(LenDecimales)))
+	}
+}
+
+// near indica si a y b difieren a lo más en tol
+func near(a, b, tol models.Decimal) bool {
+	d := a.Sub(b)
+	return max(d, -d) <= tol
+}
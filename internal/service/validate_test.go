@@ -0,0 +1,128 @@
+package service
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+	"testing"
+
+	"pos-daemon.adcon.dev/internal/models"
+)
+
+// loadTicket lee un ticket de los fixtures de la API
+func loadTicket(t *testing.T, name string) *models.NewTicketData {
+	t.Helper()
+	data, err := os.ReadFile("../api/rest/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ticket models.NewTicket
+	if err := json.Unmarshal(data, &ticket); err != nil {
+		t.Fatal(err)
+	}
+	return &ticket.Data
+}
+
+func TestValidateTicket(t *testing.T) {
+	d := models.MustDecimal
+	tests := []struct {
+		name   string
+		edit   func(t *models.NewTicketData)
+		fields []string
+	}{
+		// ticket.json cuadra: el cambio 48.199999999997 se redondea y los
+		// impuestos difieren a lo más un centavo de base × tasa
+		{"Consistente", func(*models.NewTicketData) {}, nil},
+		{"Sin conceptos", func(t *models.NewTicketData) { t.Conceptos = nil }, []string{"conceptos"}},
+		{"Campos obligatorios", func(t *models.NewTicketData) {
+			t.Identificador, t.Folio = "", " "
+			t.Conceptos[4].Descripcion = ""
+		}, []string{"identificador", "folio", "conceptos[4].descripcion"}},
+		{"RFC inválido", func(t *models.NewTicketData) { t.ClienteRFC = "XAXX-010101" }, []string{"cliente_rfc"}},
+		{"Total", func(t *models.NewTicketData) { t.Total = d("37950.8"); t.Cambio = d("49.2") }, []string{"total"}},
+		{"Descuento", func(t *models.NewTicketData) { t.Descuento = d("51.8"); t.Total = d("37900") }, []string{"cambio"}},
+		{"Cambio", func(t *models.NewTicketData) { t.Cambio = d("48") }, []string{"cambio"}},
+		// Pagar de menos no se esconde como un cambio de cero
+		{"Pago insuficiente", func(t *models.NewTicketData) { t.Pagado = d("37000"); t.Cambio = d("0") }, []string{"pagado"}},
+		{"Concepto", func(t *models.NewTicketData) {
+			t.Conceptos[4].Cantidad = d("2")
+		}, []string{"conceptos[4].total"}},
+		{"Cantidad", func(t *models.NewTicketData) {
+			t.Conceptos[4].Cantidad = d("0")
+		}, []string{"conceptos[4].cantidad", "conceptos[4].total"}},
+		{"Impuesto", func(t *models.NewTicketData) {
+			t.Conceptos[2].Impuestos[0].Importe = d("15.5")
+		}, []string{"conceptos[2].impuestos[0].importe"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ticket := loadTicket(t, "ticket.json")
+			tt.edit(ticket)
+			var fields []string
+			for _, f := range ValidateTicket(ticket) {
+				fields = append(fields, f.Field)
+			}
+			if !slices.Equal(fields, tt.fields) {
+				t.Errorf("campos = %q; want %q", fields, tt.fields)
+			}
+		})
+	}
+}
+
+func TestValidateTicketFindings(t *testing.T) {
+	// El total de new_ticket.json es solo el del primer concepto
+	got := ValidateTicket(loadTicket(t, "new_ticket.json"))
+	want := Findings{{
+		Severity: SeverityError,
+		Code:     FindingTotalMismatch,
+		Field:    "total",
+		Message:  "el total 234.00 no coincide con la suma de los conceptos menos el descuento (37485.79)",
+	}}
+	if !slices.Equal(got, want) {
+		t.Errorf("ValidateTicket = %+v; want %+v", got, want)
+	}
+
+	ticket := loadTicket(t, "ticket.json")
+	ticket.SucursalRFC = "EKU9003173C"
+	got = ValidateTicket(ticket)
+	if len(got) != 1 || got[0].Severity != SeverityWarning || got[0].Code != FindingInvalidRFC {
+		t.Errorf("ValidateTicket = %+v", got)
+	}
+
+	ticket = loadTicket(t, "ticket.json")
+	ticket.Pagado, ticket.Cambio = models.MustDecimal("37000"), 0
+	got = ValidateTicket(ticket)
+	want = Findings{{
+		Severity: SeverityError,
+		Code:     FindingUnderpaid,
+		Field:    "pagado",
+		Message:  "lo pagado 37000.00 no cubre el total 37951.80 (faltan 951.80)",
+	}}
+	if !slices.Equal(got, want) {
+		t.Errorf("ValidateTicket = %+v; want %+v", got, want)
+	}
+}
+
+func TestValidationMode(t *testing.T) {
+	errs := Findings{{Severity: SeverityError, Code: FindingNoItems, Field: "conceptos", Message: "el ticket no tiene conceptos"}}
+	warnings := Findings{{Severity: SeverityWarning, Code: FindingInvalidRFC, Field: "cliente_rfc", Message: `RFC inválido "X"`}}
+
+	for _, s := range []string{"", "warn", " Reject ", "silent"} {
+		if _, err := ParseValidationMode(s); err != nil {
+			t.Errorf("ParseValidationMode(%q): %v", s, err)
+		}
+	}
+	if _, err := ParseValidationMode("strict"); err == nil {
+		t.Error(`ParseValidationMode("strict") no devolvió error`)
+	}
+
+	if !ValidationReject.Rejects(errs) || ValidationReject.Rejects(warnings) || ValidationWarn.Rejects(errs) {
+		t.Error("Rejects solo debe rechazar errores en modo reject")
+	}
+	if got := ValidationWarn.Banner(warnings); !slices.Equal(got, []string{`cliente_rfc: RFC inválido "X"`}) {
+		t.Errorf("Banner = %q", got)
+	}
+	if ValidationSilent.Banner(errs) != nil || ValidationReject.Banner(warnings) != nil {
+		t.Error("solo el modo warn imprime aviso")
+	}
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/width"
+)
+
+// Ellipsis marca el texto recortado. Se usan puntos porque no todas las
+// páginas de códigos de las impresoras tienen "…".
+const Ellipsis = "..."
+
+// RuneWidth devuelve las columnas que ocupa r en la impresora: 2 para los
+// caracteres anchos de Asia oriental, 0 para las marcas combinantes y los
+// caracteres de control y 1 para los demás.
+func RuneWidth(r rune) int {
+	switch {
+	case r < ' ' || r == 0x7F || r == '\u200b':
+		return 0
+	case unicode.In(r, unicode.Mn, unicode.Me):
+		return 0
+	}
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
+}
+
+// TextWidth devuelve las columnas que ocupa s en la impresora
+func TextWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		n += RuneWidth(r)
+	}
+	return n
+}
+
+// fitRunes devuelve cuántas runas de runes caben en width columnas y las
+// columnas que ocupan
+func fitRunes(runes []rune, width int) (n, used int) {
+	for n < len(runes) && used+RuneWidth(runes[n]) <= width {
+		used += RuneWidth(runes[n])
+		n++
+	}
+	return n, used
+}
+
+// cutText devuelve el inicio de s que cabe en width columnas
+func cutText(s string, width int) string {
+	runes := []rune(s)
+	n, _ := fitRunes(runes, width)
+	return string(runes[:n])
+}
+
+// TruncateText recorta s a width columnas. Si hay que recortar y el ancho lo
+// permite, el texto termina en Ellipsis.
+func TruncateText(s string, width int) string {
+	if TextWidth(s) <= width {
+		return s
+	}
+	if width <= len(Ellipsis) {
+		return cutText(s, width)
+	}
+	return strings.TrimRight(cutText(s, width-len(Ellipsis)), " ") + Ellipsis
+}
+
+// WrapWords parte s en líneas de width columnas. Las líneas se parten entre
+// palabras; una palabra más larga que la línea se parte con guion.
+func WrapWords(s string, width int) []string {
+	if width <= 0 {
+		return nil
+	}
+	return wrapWords(strings.Join(strings.Fields(s), " "), width, true)
+}
+
+// splitWords separa de s las palabras que caben en width columnas. Si la
+// primera palabra no cabe, head queda vacío y todo el texto pasa a tail.
+func splitWords(s string, width int) (head, tail string) {
+	words := strings.Fields(s)
+	n, used := 0, 0
+	for ; n < len(words); n++ {
+		w := TextWidth(words[n])
+		if n > 0 {
+			w++
+		}
+		if used+w > width {
+			break
+		}
+		used += w
+	}
+	return strings.Join(words[:n], " "), strings.Join(words[n:], " ")
+}
+
+// wrapWords parte una línea sin saltos en líneas de width columnas. Retrocede
+// hasta el último espacio que cabe; si la palabra no cabe en una línea la
+// corta, con guion si hyphen es verdadero y la corta entre letras angostas.
+func wrapWords(line string, width int, hyphen bool) []string {
+	line = strings.TrimRight(line, " ")
+	if TextWidth(line) <= width {
+		return []string{line}
+	}
+
+	var out []string
+	runes := []rune(line)
+	for TextWidth(string(runes)) > width {
+		fit, _ := fitRunes(runes, width)
+		cut := 0
+		for i := fit; i > 0; i-- {
+			if i < len(runes) && runes[i] == ' ' {
+				cut = i
+				break
+			}
+		}
+		part := ""
+		switch {
+		case cut > 0:
+			part = string(runes[:cut])
+		case hyphen && width > 1:
+			cut, _ = fitRunes(runes, width-1)
+			if cut > 0 && hyphenates(runes[cut-1], runes[cut]) {
+				part = string(runes[:cut]) + "-"
+				break
+			}
+			fallthrough
+		default:
+			// Una runa más ancha que la línea se imprime sola aunque no quepa
+			cut = max(fit, 1)
+			part = string(runes[:cut])
+		}
+		out = append(out, strings.TrimRight(part, " "))
+		runes = runes[cut:]
+		// El espacio donde se partió no inicia la línea siguiente
+		for len(runes) > 0 && runes[0] == ' ' {
+			runes = runes[1:]
+		}
+	}
+	if len(runes) > 0 {
+		out = append(out, string(runes))
+	}
+	return out
+}
+
+// hyphenates indica si una palabra cortada entre a y b lleva guion. Los
+// caracteres anchos se parten sin él.
+func hyphenates(a, b rune) bool {
+	isNarrowWord := func(r rune) bool {
+		return RuneWidth(r) == 1 && (unicode.IsLetter(r) || unicode.IsDigit(r))
+	}
+	return isNarrowWord(a) && isNarrowWord(b)
+}
+
+// alignLeft, alignRight y alignCenter rellenan s con espacios hasta width
+// columnas. El texto más ancho se devuelve sin cambios.
+func alignLeft(s string, width int) string {
+	return s + strings.Repeat(" ", max(width-TextWidth(s), 0))
+}
+
+func alignRight(s string, width int) string {
+	return strings.Repeat(" ", max(width-TextWidth(s), 0)) + s
+}
+
+func alignCenter(s string, width int) string {
+	pad := max(width-TextWidth(s), 0)
+	return strings.Repeat(" ", pad/2) + s + strings.Repeat(" ", pad-pad/2)
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTextWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"AÑO", 3},
+		{"你好", 4},
+		{"ｶﾀｶﾅ", 4}, // Katakana de medio ancho
+		{"é", 1},
+		{"a\x1bb", 2},
+	}
+	for _, tt := range tests {
+		if got := TextWidth(tt.s); got != tt.want {
+			t.Errorf("TextWidth(%q) = %d; want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"ESTUCO", 6, "ESTUCO"},
+		{"ESTUCO ACRILICO", 10, "ESTUCO..."},
+		{"ESTUCO", 3, "EST"},
+		{"你好世界", 7, "你好..."},
+		{"你好世界", 3, "你"},
+	}
+	for _, tt := range tests {
+		if got := TruncateText(tt.s, tt.width); got != tt.want {
+			t.Errorf("TruncateText(%q, %d) = %q; want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestWrapWords(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     string
+		width int
+		want  []string
+	}{
+		{"Cabe", "AGUA DESTILADA", 14, []string{"AGUA DESTILADA"}},
+		{"Entre palabras", "MANTENIMIENTO OTROS CORRECTIVOS", 15, []string{"MANTENIMIENTO", "OTROS", "CORRECTIVOS"}},
+		{"Espacios repetidos", "UNO   DOS\tTRES", 8, []string{"UNO DOS", "TRES"}},
+		{"Palabra larga con guion", "PRODUCTO", 5, []string{"PROD-", "UCTO"}},
+		{"Sin guion junto a signos", "A/B/C/D/E/F", 4, []string{"A/B/", "C/D/", "E/F"}},
+		{"Anchos de Asia oriental", "你好世界 ok", 5, []string{"你好", "世界", "ok"}},
+		{"Runa más ancha que la línea", "你", 1, []string{"你"}},
+		{"Ancho inválido", "abc", 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WrapWords(tt.s, tt.width); !slices.Equal(got, tt.want) {
+				t.Errorf("WrapWords(%q, %d) = %q; want %q", tt.s, tt.width, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+// Package status consulta el estado en tiempo real de las impresoras ESC/POS.
+// Las consultas usan DLE EOT n o Automatic Status Back (GS a) sobre conexiones
+// bidireccionales, y el Monitor las repite periódicamente para que el worker de
+// la cola no envíe trabajos a una impresora sin papel o con la tapa abierta.
+package status
@@ -0,0 +1,195 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"pos-daemon.adcon.dev/internal/connector"
+	"pos-daemon.adcon.dev/internal/events"
+	"pos-daemon.adcon.dev/internal/printers"
+)
+
+// ErrNotReady se devuelve cuando la impresora informa un problema que impide imprimir
+var ErrNotReady = errors.New("status: la impresora no está lista")
+
+// entry es el estado conocido de una impresora
+type entry struct {
+	printer *printers.Printer
+
+	// conn se toma mientras se imprime o se consulta: muchas impresoras solo
+	// aceptan una conexión a la vez
+	conn sync.Mutex
+
+	mu     sync.Mutex
+	status PrinterStatus
+}
+
+// Monitor consulta periódicamente el estado de las impresoras, lo publica como
+// eventos y lo combina con el resultado de los envíos del worker
+type Monitor struct {
+	hub     *events.Hub
+	entries map[string]*entry
+	now     func() time.Time
+}
+
+// NewMonitor crea un monitor para las impresoras del registro. Los cambios de
+// estado se publican en hub como eventos de impresora.
+func NewMonitor(registry *printers.Registry, hub *events.Hub) *Monitor {
+	m := &Monitor{hub: hub, entries: make(map[string]*entry), now: time.Now}
+	for _, p := range registry.List() {
+		m.entries[p.Name] = &entry{
+			printer: p,
+			status:  PrinterStatus{Printer: p.Name, State: StateUnknown},
+		}
+	}
+	return m
+}
+
+// Run consulta las impresoras que lo permiten hasta que ctx se cancele
+func (m *Monitor) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, e := range m.entries {
+		if e.printer.Poll <= 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(e *entry) {
+			defer wg.Done()
+			m.pollLoop(ctx, e)
+		}(e)
+	}
+	wg.Wait()
+}
+
+// pollLoop consulta una impresora cada Poll
+func (m *Monitor) pollLoop(ctx context.Context, e *entry) {
+	ticker := time.NewTicker(e.printer.Poll)
+	defer ticker.Stop()
+	for {
+		m.poll(e)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll consulta la impresora salvo que esté ocupada imprimiendo
+func (m *Monitor) poll(e *entry) {
+	if !e.conn.TryLock() {
+		return
+	}
+	defer e.conn.Unlock()
+
+	st, err := query(e.printer)
+	if err != nil {
+		st = PrinterStatus{State: StateUnreachable, Error: err.Error()}
+	}
+	m.update(e, st)
+}
+
+// query abre una conexión con la impresora y consulta su estado
+func query(p *printers.Printer) (PrinterStatus, error) {
+	conn, err := connector.Open(p.Connector)
+	if err != nil {
+		return PrinterStatus{}, err
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			log.Printf("status: error al cerrar conector de %s: %v", p.Name, err)
+		}
+	}()
+	rw, ok := conn.(io.ReadWriter)
+	if !ok {
+		return PrinterStatus{}, fmt.Errorf("status: la conexión de %s no permite leer", p.Name)
+	}
+	return Query(rw, p.ASB)
+}
+
+// update guarda el estado y publica un evento si cambió
+func (m *Monitor) update(e *entry, st PrinterStatus) {
+	st.Printer = e.printer.Name
+	st.CheckedAt = m.now()
+
+	e.mu.Lock()
+	prev := e.status
+	e.status = st
+	e.mu.Unlock()
+
+	if st.State != prev.State || st.Error != prev.Error {
+		if st.State != StateOnline {
+			log.Printf("Impresora %s: %s (%s)", st.Printer, st.State, st.Error)
+		}
+		m.hub.Publish(events.Event{Type: events.TypePrinter, Printer: st.Printer, Status: string(st.State), Error: st.Error})
+	}
+}
+
+// lookup devuelve la entrada de la impresora name
+func (m *Monitor) lookup(name string) (*entry, error) {
+	e, ok := m.entries[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", printers.ErrUnknownPrinter, name)
+	}
+	return e, nil
+}
+
+// Status devuelve el último estado conocido de la impresora name
+func (m *Monitor) Status(name string) (PrinterStatus, error) {
+	e, err := m.lookup(name)
+	if err != nil {
+		return PrinterStatus{}, err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.status, nil
+}
+
+// Ready devuelve ErrNotReady si la última consulta informó un problema. Una
+// impresora que no se consulta o no respondió se considera lista: el envío
+// decidirá si falla.
+func (m *Monitor) Ready(name string) error {
+	st, err := m.Status(name)
+	if err != nil {
+		return err
+	}
+	if st.State == StateError {
+		return fmt.Errorf("%w: %s (%s)", ErrNotReady, name, st.Error)
+	}
+	return nil
+}
+
+// Use ejecuta send con la conexión de la impresora reservada y actualiza su
+// estado con el resultado
+func (m *Monitor) Use(name string, send func() error) error {
+	e, err := m.lookup(name)
+	if err != nil {
+		return err
+	}
+	e.conn.Lock()
+	err = send()
+	e.conn.Unlock()
+
+	e.mu.Lock()
+	st := e.status
+	e.mu.Unlock()
+	switch {
+	case errors.Is(err, ErrPaperEnd) && e.printer.Poll > 0:
+		// La siguiente consulta indicará cuándo vuelve a tener papel
+		st.State, st.PaperEnd, st.Error = StateError, true, err.Error()
+	case err != nil:
+		st.State, st.Error = StateUnreachable, err.Error()
+	case st.State == StateUnknown || st.State == StateUnreachable:
+		// El envío funcionó; el detalle lo dará la siguiente consulta
+		st.State, st.Online, st.Error = StateOnline, true, ""
+	default:
+		return nil
+	}
+	m.update(e, st)
+	return err
+}
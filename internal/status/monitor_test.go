@@ -0,0 +1,102 @@
+package status
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"pos-daemon.adcon.dev/internal/connector"
+	"pos-daemon.adcon.dev/internal/events"
+	"pos-daemon.adcon.dev/internal/printers"
+)
+
+// serveStatus atiende conexiones TCP respondiendo DLE EOT n con eot[n]
+func serveStatus(t *testing.T, eot map[byte]byte) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(c net.Conn) {
+				defer c.Close()
+				buf := make([]byte, 3)
+				for {
+					if _, err := c.Read(buf); err != nil {
+						return
+					}
+					if buf[0] == 0x10 && buf[1] == 0x04 {
+						if _, err := c.Write([]byte{eot[buf[2]]}); err != nil {
+							return
+						}
+					}
+				}
+			}(c)
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestMonitor(t *testing.T) {
+	eot := readyEOT()
+	eot[1], eot[2] = 0x1E, 0x16 // Tapa abierta
+	port := serveStatus(t, eot)
+
+	registry, err := printers.New([]printers.Printer{
+		{Name: "mostrador", Poll: time.Hour, Connector: connector.Options{
+			Type: connector.TypeTCP, Address: "127.0.0.1", Port: port, Timeout: time.Second,
+		}},
+		{Name: "archivo", Connector: connector.Options{Type: connector.TypeFile, Device: "ticket.bin"}},
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("printers.New: %v", err)
+	}
+	hub := events.NewHub()
+	ch, unsubscribe := hub.Subscribe()
+	defer unsubscribe()
+	m := NewMonitor(registry, hub)
+
+	m.poll(m.entries["mostrador"])
+	st, err := m.Status("mostrador")
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if st.State != StateError || !st.CoverOpen || st.CheckedAt.IsZero() {
+		t.Errorf("Status = %+v", st)
+	}
+	if err := m.Ready("mostrador"); !errors.Is(err, ErrNotReady) {
+		t.Errorf("Ready = %v; want ErrNotReady", err)
+	}
+	if ev := <-ch; ev.Type != events.TypePrinter || ev.Printer != "mostrador" || ev.Status != string(StateError) {
+		t.Errorf("evento = %+v", ev)
+	}
+
+	// Una impresora que no se consulta pasa a online con el primer envío exitoso
+	if err := m.Ready("archivo"); err != nil {
+		t.Errorf("Ready = %v; want nil", err)
+	}
+	if err := m.Use("archivo", func() error { return nil }); err != nil {
+		t.Fatalf("Use: %v", err)
+	}
+	if st, _ := m.Status("archivo"); st.State != StateOnline {
+		t.Errorf("State = %s; want online", st.State)
+	}
+	sendErr := errors.New("connection refused")
+	if err := m.Use("archivo", func() error { return sendErr }); !errors.Is(err, sendErr) {
+		t.Fatalf("Use = %v; want %v", err, sendErr)
+	}
+	if st, _ := m.Status("archivo"); st.State != StateUnreachable {
+		t.Errorf("State = %s; want unreachable", st.State)
+	}
+
+	if _, err := m.Status("bodega"); !errors.Is(err, printers.ErrUnknownPrinter) {
+		t.Errorf("Status = %v; want ErrUnknownPrinter", err)
+	}
+}
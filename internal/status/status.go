@@ -0,0 +1,257 @@
+package status
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrPaperEnd indica que la impresora reportó que no tiene papel
+var ErrPaperEnd = errors.New("status: la impresora no tiene papel")
+
+// State resume el estado de una impresora
+type State string
+
+const (
+	StateUnknown     State = "unknown"     // Aún no se consulta o la conexión no permite consultar
+	StateOnline      State = "online"      // Lista para imprimir
+	StateError       State = "error"       // La impresora informa un problema (papel, tapa, error)
+	StateUnreachable State = "unreachable" // No se pudo comunicar con la impresora
+)
+
+// PrinterStatus es el estado decodificado de una impresora
+type PrinterStatus struct {
+	Printer string `json:"printer"`
+	State   State  `json:"state"`
+
+	Online               bool `json:"online"`
+	CoverOpen            bool `json:"cover_open"`
+	FeedButton           bool `json:"feed_button"` // Se está alimentando papel con el botón
+	PaperNearEnd         bool `json:"paper_near_end"`
+	PaperEnd             bool `json:"paper_end"`
+	CutterError          bool `json:"cutter_error"`
+	UnrecoverableError   bool `json:"unrecoverable_error"`
+	AutoRecoverableError bool `json:"auto_recoverable_error"` // Por ejemplo, cabezal sobrecalentado
+
+	Error     string    `json:"error,omitempty"`      // Problema informado o fallo de comunicación
+	CheckedAt time.Time `json:"checked_at,omitempty"` // Momento de la última consulta
+}
+
+// Problem describe lo que impide imprimir, o "" si la impresora está lista.
+// El papel por terminarse no impide imprimir.
+func (s *PrinterStatus) Problem() string {
+	var problems []string
+	add := func(cond bool, msg string) {
+		if cond {
+			problems = append(problems, msg)
+		}
+	}
+	add(s.PaperEnd, "sin papel")
+	add(s.CoverOpen, "tapa abierta")
+	add(s.CutterError, "error del cortador")
+	add(s.UnrecoverableError, "error irrecuperable")
+	add(s.AutoRecoverableError, "error recuperable")
+	if len(problems) == 0 && !s.Online {
+		problems = append(problems, "fuera de línea")
+	}
+	return strings.Join(problems, ", ")
+}
+
+// enableASB es GS a 0x0F: informar cajón, en línea, errores y sensor de papel
+var enableASB = []byte{0x1D, 0x61, 0x0F}
+
+// disableASB es GS a 0: la impresora deja de enviar su estado por su cuenta
+var disableASB = []byte{0x1D, 0x61, 0x00}
+
+// maxStray es cuántos bytes de ASB se descartan antes de la respuesta a
+// DLE EOT: cuatro bloques de estado atrasados
+const maxStray = 16
+
+// Estados que se piden con DLE EOT n
+const (
+	eotPrinter = 1 // Estado de la impresora
+	eotOffline = 2 // Causa de fuera de línea
+	eotError   = 3 // Causa del error
+	eotPaper   = 4 // Sensor de papel
+)
+
+// Bits fijos de las respuestas
+const (
+	eotFixedMask = 0x93 // Bits 0, 1, 4 y 7
+	eotFixed     = 0x12 // Bits 1 y 4 encendidos, 0 y 7 apagados
+	asbFixedMask = 0x93
+	asbFixed     = 0x10 // Primer byte de ASB: bit 4 encendido, 0, 1 y 7 apagados
+	asbNextMask  = 0x90 // Bytes 2 a 4 de ASB: bits 4 y 7 apagados
+)
+
+// Query consulta el estado completo de la impresora. Con asb se habilita
+// Automatic Status Back, que responde los cuatro bytes de estado de una vez, y
+// se deshabilita en cuanto se leen para que la impresora no siga enviándolos;
+// si no, se envía DLE EOT 1 a 4. Se espera una conexión nueva por consulta, y
+// debe tener un tiempo máximo de lectura para no bloquear si la impresora no
+// responde.
+func Query(conn io.ReadWriter, asb bool) (PrinterStatus, error) {
+	var st PrinterStatus
+	if asb {
+		if _, err := conn.Write(enableASB); err != nil {
+			return st, fmt.Errorf("status: no se pudo habilitar ASB: %w", err)
+		}
+		var b [4]byte
+		_, rerr := io.ReadFull(conn, b[:])
+		_, werr := conn.Write(disableASB)
+		if rerr != nil {
+			return st, fmt.Errorf("status: la impresora no respondió a ASB: %w", rerr)
+		}
+		if werr != nil {
+			return st, fmt.Errorf("status: no se pudo deshabilitar ASB: %w", werr)
+		}
+		if err := decodeASB(&st, b); err != nil {
+			return st, err
+		}
+	} else {
+		for n := byte(eotPrinter); n <= eotPaper; n++ {
+			b, err := transmit(conn, n)
+			if err != nil {
+				return st, err
+			}
+			decodeEOT(&st, n, b)
+		}
+	}
+	st.State = StateOnline
+	if problem := st.Problem(); problem != "" {
+		st.State, st.Error = StateError, problem
+	}
+	return st, nil
+}
+
+// CheckPaper consulta el sensor de papel con DLE EOT 4 y devuelve ErrPaperEnd
+// si la impresora no tiene papel. Si la impresora no responde se supone que
+// tiene papel: muchas interfaces ignoran el comando y eso no debe impedir la
+// impresión. Una respuesta que no es de estado sí es un error: no se sabe qué
+// hay del otro lado.
+func CheckPaper(conn io.ReadWriter) error {
+	b, err := transmit(conn, eotPaper)
+	if err != nil {
+		if errors.Is(err, errNoResponse) {
+			return nil
+		}
+		return err
+	}
+	var st PrinterStatus
+	decodeEOT(&st, eotPaper, b)
+	if st.PaperEnd {
+		return ErrPaperEnd
+	}
+	return nil
+}
+
+// errNoResponse indica que la impresora no respondió a DLE EOT
+var errNoResponse = errors.New("status: la impresora no respondió")
+
+// transmit envía DLE EOT n y lee el byte de respuesta. Los bytes de ASB que
+// la impresora haya dejado pendientes se descartan: sus bits fijos nunca
+// coinciden con los de una respuesta a DLE EOT.
+func transmit(conn io.ReadWriter, n byte) (byte, error) {
+	if _, err := conn.Write([]byte{0x10, 0x04, n}); err != nil {
+		return 0, fmt.Errorf("status: no se pudo enviar DLE EOT %d: %w", n, err)
+	}
+	var b [1]byte
+	for range maxStray + 1 {
+		if c, err := conn.Read(b[:]); c == 0 || err != nil {
+			return 0, fmt.Errorf("%w a DLE EOT %d", errNoResponse, n)
+		}
+		switch {
+		case b[0]&eotFixedMask == eotFixed:
+			return b[0], nil
+		case b[0]&asbFixedMask == asbFixed || b[0]&asbNextMask == 0:
+			continue
+		}
+		return 0, fmt.Errorf("status: respuesta inválida a DLE EOT %d (0x%02X)", n, b[0])
+	}
+	return 0, fmt.Errorf("status: la impresora no dejó de enviar ASB antes de responder a DLE EOT %d", n)
+}
+
+// decodeEOT interpreta la respuesta a DLE EOT n
+func decodeEOT(st *PrinterStatus, n, b byte) {
+	switch n {
+	case eotPrinter:
+		st.Online = b&0x08 == 0
+	case eotOffline:
+		st.CoverOpen = b&0x04 != 0
+		st.FeedButton = b&0x08 != 0
+		st.PaperEnd = st.PaperEnd || b&0x20 != 0
+	case eotError:
+		st.CutterError = b&0x08 != 0
+		st.UnrecoverableError = b&0x20 != 0
+		st.AutoRecoverableError = b&0x40 != 0
+	case eotPaper:
+		st.PaperNearEnd = b&0x0C != 0
+		st.PaperEnd = st.PaperEnd || b&0x60 != 0
+	}
+}
+
+// EncodeEOT arma la respuesta a DLE EOT n de una impresora en el estado st.
+// Permite contestar las consultas de estado en nombre de la impresora.
+func EncodeEOT(st PrinterStatus, n byte) byte {
+	b := byte(eotFixed)
+	set := func(cond bool, bit byte) {
+		if cond {
+			b |= bit
+		}
+	}
+	switch n {
+	case eotPrinter:
+		set(!st.Online, 0x08)
+	case eotOffline:
+		set(st.CoverOpen, 0x04)
+		set(st.FeedButton, 0x08)
+		set(st.PaperEnd, 0x20)
+		set(st.CutterError || st.UnrecoverableError || st.AutoRecoverableError, 0x40)
+	case eotError:
+		set(st.CutterError, 0x08)
+		set(st.UnrecoverableError, 0x20)
+		set(st.AutoRecoverableError, 0x40)
+	case eotPaper:
+		set(st.PaperNearEnd, 0x0C)
+		set(st.PaperEnd, 0x60)
+	}
+	return b
+}
+
+// EncodeASB arma los cuatro bytes de Automatic Status Back de una impresora en
+// el estado st
+func EncodeASB(st PrinterStatus) [4]byte {
+	b := [4]byte{asbFixed}
+	set := func(i int, cond bool, bit byte) {
+		if cond {
+			b[i] |= bit
+		}
+	}
+	set(0, !st.Online, 0x08)
+	set(0, st.CoverOpen, 0x20)
+	set(0, st.FeedButton, 0x40)
+	set(1, st.CutterError, 0x08)
+	set(1, st.UnrecoverableError, 0x20)
+	set(1, st.AutoRecoverableError, 0x40)
+	set(2, st.PaperNearEnd, 0x03)
+	set(2, st.PaperEnd, 0x0C)
+	return b
+}
+
+// decodeASB interpreta los cuatro bytes de Automatic Status Back
+func decodeASB(st *PrinterStatus, b [4]byte) error {
+	if b[0]&asbFixedMask != asbFixed || b[1]&asbNextMask != 0 || b[2]&asbNextMask != 0 || b[3]&asbNextMask != 0 {
+		return fmt.Errorf("status: respuesta ASB inválida (% X)", b[:])
+	}
+	st.Online = b[0]&0x08 == 0
+	st.CoverOpen = b[0]&0x20 != 0
+	st.FeedButton = b[0]&0x40 != 0
+	st.CutterError = b[1]&0x08 != 0
+	st.UnrecoverableError = b[1]&0x20 != 0
+	st.AutoRecoverableError = b[1]&0x40 != 0
+	st.PaperNearEnd = b[2]&0x03 != 0
+	st.PaperEnd = b[2]&0x0C != 0
+	return nil
+}
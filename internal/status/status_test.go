@@ -0,0 +1,183 @@
+package status
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// fakePrinter responde a DLE EOT n con eot[n] y a GS a con asb mientras ASB
+// esté habilitado
+type fakePrinter struct {
+	eot     map[byte]byte
+	asb     []byte
+	asbOn   bool
+	pending bytes.Buffer
+}
+
+func (f *fakePrinter) Write(p []byte) (int, error) {
+	switch {
+	case len(p) == 3 && p[0] == 0x10 && p[1] == 0x04:
+		if b, ok := f.eot[p[2]]; ok {
+			f.pending.WriteByte(b)
+		}
+	case len(p) == 3 && p[0] == 0x1D && p[1] == 0x61:
+		f.asbOn = p[2] != 0
+		if f.asbOn {
+			f.pending.Write(f.asb)
+		}
+	}
+	return len(p), nil
+}
+
+func (f *fakePrinter) Read(p []byte) (int, error) {
+	if f.pending.Len() == 0 {
+		return 0, errors.New("i/o timeout")
+	}
+	return f.pending.Read(p)
+}
+
+// readyEOT son las respuestas de una impresora lista
+func readyEOT() map[byte]byte {
+	return map[byte]byte{1: 0x16, 2: 0x12, 3: 0x12, 4: 0x12}
+}
+
+func TestQueryEOT(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  map[byte]byte
+		state   State
+		problem string
+	}{
+		{"Lista", nil, StateOnline, ""},
+		{"Papel por terminarse", map[byte]byte{4: 0x1E}, StateOnline, ""},
+		{"Tapa abierta", map[byte]byte{1: 0x1E, 2: 0x16}, StateError, "tapa abierta"},
+		{"Sin papel", map[byte]byte{1: 0x1E, 2: 0x32, 4: 0x72}, StateError, "sin papel"},
+		{"Error del cortador", map[byte]byte{1: 0x1E, 2: 0x52, 3: 0x1A}, StateError, "error del cortador"},
+		{"Fuera de línea", map[byte]byte{1: 0x1E}, StateError, "fuera de línea"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			printer := &fakePrinter{eot: readyEOT()}
+			for n, b := range tt.change {
+				printer.eot[n] = b
+			}
+			st, err := Query(printer, false)
+			if err != nil {
+				t.Fatalf("Query: %v", err)
+			}
+			if st.State != tt.state || st.Problem() != tt.problem {
+				t.Errorf("Query = %s %q; want %s %q", st.State, st.Problem(), tt.state, tt.problem)
+			}
+			if tt.name == "Papel por terminarse" && !st.PaperNearEnd {
+				t.Error("PaperNearEnd = false")
+			}
+		})
+	}
+}
+
+func TestQueryASB(t *testing.T) {
+	// Tapa abierta y sin papel
+	printer := &fakePrinter{asb: []byte{0x38, 0x00, 0x0C, 0x00}}
+	st, err := Query(printer, true)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if st.Online || !st.CoverOpen || !st.PaperEnd || st.State != StateError {
+		t.Errorf("Query = %+v", st)
+	}
+	if printer.asbOn {
+		t.Error("Query dejó ASB habilitado")
+	}
+
+	if _, err := Query(&fakePrinter{asb: []byte{0xFF, 0, 0, 0}}, true); err == nil {
+		t.Error("Query aceptó una respuesta ASB inválida")
+	}
+	if _, err := Query(&fakePrinter{}, true); err == nil {
+		t.Error("Query sin respuesta no devolvió error")
+	}
+}
+
+func TestQueryNoResponse(t *testing.T) {
+	if _, err := Query(&fakePrinter{eot: map[byte]byte{1: 0x16}}, false); err == nil {
+		t.Error("Query sin respuesta a DLE EOT 2 no devolvió error")
+	}
+}
+
+func TestCheckPaper(t *testing.T) {
+	tests := []struct {
+		name string
+		eot  map[byte]byte
+		want error
+	}{
+		{"Con papel", map[byte]byte{4: 0x12}, nil},
+		{"Papel por terminarse", map[byte]byte{4: 0x1E}, nil},
+		{"Sin papel", map[byte]byte{4: 0x72}, ErrPaperEnd},
+		{"Sin respuesta", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckPaper(&fakePrinter{eot: tt.eot}); !errors.Is(err, tt.want) {
+				t.Errorf("CheckPaper = %v; want %v", err, tt.want)
+			}
+		})
+	}
+
+	if err := CheckPaper(&fakePrinter{eot: map[byte]byte{4: 0xFF}}); err == nil {
+		t.Error("CheckPaper aceptó una respuesta que no es de estado")
+	}
+}
+
+func TestStrayASB(t *testing.T) {
+	// Un bloque ASB de tapa abierta llega antes de la respuesta a DLE EOT
+	stray := EncodeASB(PrinterStatus{CoverOpen: true, PaperEnd: true})
+
+	printer := &fakePrinter{eot: map[byte]byte{4: 0x72}}
+	printer.pending.Write(stray[:])
+	if err := CheckPaper(printer); !errors.Is(err, ErrPaperEnd) {
+		t.Errorf("CheckPaper = %v; want %v", err, ErrPaperEnd)
+	}
+
+	printer = &fakePrinter{eot: readyEOT()}
+	printer.pending.Write(stray[:])
+	st, err := Query(printer, false)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if st.State != StateOnline || st.CoverOpen {
+		t.Errorf("Query = %+v; want online", st)
+	}
+}
+
+func TestEncodeEOT(t *testing.T) {
+	want := PrinterStatus{CoverOpen: true, PaperNearEnd: true, CutterError: true}
+	printer := &fakePrinter{eot: map[byte]byte{}}
+	for n := byte(eotPrinter); n <= eotPaper; n++ {
+		printer.eot[n] = EncodeEOT(want, n)
+	}
+	st, err := Query(printer, false)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if st.Online || !st.CoverOpen || !st.PaperNearEnd || !st.CutterError || st.PaperEnd {
+		t.Errorf("Query = %+v; want %+v", st, want)
+	}
+	if b := EncodeEOT(PrinterStatus{Online: true}, eotPrinter); b != 0x12 {
+		t.Errorf("EncodeEOT(online, 1) = %#x; want 0x12", b)
+	}
+}
+
+func TestEncodeASB(t *testing.T) {
+	want := PrinterStatus{CoverOpen: true, PaperEnd: true, UnrecoverableError: true}
+	asb := EncodeASB(want)
+	st, err := Query(&fakePrinter{eot: readyEOT(), asb: asb[:]}, true)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if st.Online || !st.CoverOpen || !st.PaperEnd || !st.UnrecoverableError || st.PaperNearEnd {
+		t.Errorf("Query = %+v; want %+v", st, want)
+	}
+	if b := EncodeASB(PrinterStatus{Online: true}); b != [4]byte{0x10} {
+		t.Errorf("EncodeASB(online) = % X; want 10 00 00 00", b)
+	}
+}